@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/shahar-caura/forge/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+func newCancelCmd(logger *slog.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel <run-id>",
+		Short: "Cancel an in-flight run and kill its agent",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeRunIDs(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rs, err := pipeline.CancelRun(args[0], logger)
+			if err != nil {
+				return fmt.Errorf("cancelling run: %w", err)
+			}
+			fmt.Printf("Cancelled run %s; resume with `forge resume %s`\n", rs.ID, rs.ID)
+			return nil
+		},
+	}
+}
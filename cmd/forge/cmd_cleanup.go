@@ -18,7 +18,7 @@ import (
 func newCleanupCmd(logger *slog.Logger) *cobra.Command {
 	return &cobra.Command{
 		Use:   "cleanup",
-		Short: "Remove worktrees for merged PRs",
+		Short: "Restack PRs whose parent merged and remove worktrees for merged PRs",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg, err := config.Load("forge.yaml")
 			if err != nil {
@@ -42,6 +42,16 @@ func newCleanupCmd(logger *slog.Logger) *cobra.Command {
 			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 			defer stop()
 
+			// Restack before removing worktrees: children rebase in their own worktree
+			// while it exists.
+			restacked, err := pipeline.RestackMerged(ctx, v, cfg.VCS.BaseBranch, logger)
+			if err != nil {
+				return err
+			}
+			if restacked > 0 {
+				logger.Info("restacked PRs onto merged parents' base", "count", restacked)
+			}
+
 			cleaned, err := pipeline.CleanupMergedWorktrees(ctx, v, wt, logger)
 			if err != nil {
 				return err
@@ -46,6 +46,13 @@ func cmdEdit(logger *slog.Logger, runID string, push bool, message string) error
 		return fmt.Errorf("loading run state: %w", err)
 	}
 
+	// Editing a run's worktree while forge drives it would race the agent.
+	lock, err := state.AcquireLock(runID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	if rs.Branch == "" {
 		return fmt.Errorf("run %q has no branch yet (step 'generate branch' not completed)", runID)
 	}
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/shahar-caura/forge/internal/pipeline"
+	"github.com/shahar-caura/forge/internal/state"
 	"github.com/spf13/cobra"
 )
 
+// eventsPollInterval is how often forge logs --events -f checks the journal.
+const eventsPollInterval = 500 * time.Millisecond
+
 func newLogsCmd() *cobra.Command {
 	var (
 		follow bool
 		step   int
+		events bool
 	)
 
 	cmd := &cobra.Command{
@@ -24,12 +34,16 @@ func newLogsCmd() *cobra.Command {
 			return completeRunIDs(toComplete)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if events {
+				return cmdLogsEvents(args[0], follow)
+			}
 			return cmdLogs(args[0], follow, step)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "follow log output")
 	cmd.Flags().IntVar(&step, "step", 4, "step number to show logs for")
+	cmd.Flags().BoolVar(&events, "events", false, "show the run's event journal instead of agent output")
 
 	return cmd
 }
@@ -53,3 +67,60 @@ func cmdLogs(runID string, follow bool, step int) error {
 	_, err = io.Copy(os.Stdout, f)
 	return err
 }
+
+func cmdLogsEvents(runID string, follow bool) error {
+	events, err := state.ReadEvents(runID)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 && !follow {
+		fmt.Printf("No events recorded for run %s.\n", runID)
+		return nil
+	}
+	for _, ev := range events {
+		fmt.Println(formatEvent(ev))
+	}
+	if !follow {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	seen := len(events)
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		events, err := state.ReadEvents(runID)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events[min(seen, len(events)):] {
+			fmt.Println(formatEvent(ev))
+		}
+		seen = len(events)
+	}
+}
+
+// formatEvent renders a journal entry as "15:04:05  type  step  key=value ...",
+// with data keys sorted so output is stable.
+func formatEvent(ev state.Event) string {
+	parts := []string{ev.Time.Local().Format("15:04:05"), fmt.Sprintf("%-18s", ev.Type)}
+	if ev.Step != "" {
+		parts = append(parts, ev.Step)
+	}
+	keys := make([]string, 0, len(ev.Data))
+	for k := range ev.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, ev.Data[k]))
+	}
+	return strings.Join(parts, "  ")
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/shahar-caura/forge/internal/config"
 	"github.com/shahar-caura/forge/internal/pipeline"
@@ -15,24 +16,40 @@ import (
 )
 
 func newResumeCmd(logger *slog.Logger) *cobra.Command {
-	var fromStep string
+	var (
+		fromStep string
+		batchID  string
+	)
 
 	cmd := &cobra.Command{
-		Use:   "resume <run-id>",
-		Short: "Resume a previous run",
-		Args:  cobra.ExactArgs(1),
+		Use:   "resume <run-id> | --batch <batch-id>",
+		Short: "Resume a previous run or batch",
+		Args:  cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return completeRunIDs(toComplete)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchID != "" {
+				if len(args) > 0 || fromStep != "" {
+					return fmt.Errorf("--batch cannot be combined with a run ID or --from")
+				}
+				return cmdResumeBatch(cmd, logger, batchID)
+			}
+			if len(args) != 1 {
+				return fmt.Errorf("provide a run ID or --batch <batch-id>")
+			}
 			return cmdResume(cmd, logger, args[0], fromStep)
 		},
 	}
 
 	cmd.Flags().StringVar(&fromStep, "from", "", "step name to resume from")
+	cmd.Flags().StringVar(&batchID, "batch", "", "batch ID to resume (skips completed issues, resumes failed runs)")
 	_ = cmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return completeStepNames(toComplete)
 	})
+	_ = cmd.RegisterFlagCompletionFunc("batch", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeBatchIDs(toComplete)
+	})
 
 	return cmd
 }
@@ -43,6 +60,12 @@ func cmdResume(cobraCmd *cobra.Command, logger *slog.Logger, runID, fromStep str
 		return fmt.Errorf("loading run state: %w", err)
 	}
 
+	// Fail before touching anything if another process is executing the run
+	// (pipeline.Run re-checks by acquiring the lock).
+	if owner := rs.LockOwner(); owner != nil {
+		return &state.LockedError{ID: runID, Owner: *owner}
+	}
+
 	// Plan file check only applies to run mode (push mode may have no plan).
 	if rs.Mode != "push" {
 		if _, err := os.Stat(rs.PlanPath); err != nil {
@@ -51,25 +74,21 @@ func cmdResume(cobraCmd *cobra.Command, logger *slog.Logger, runID, fromStep str
 	}
 
 	if fromStep != "" {
-		idx, ok := state.StepIndex(fromStep)
+		idx, ok := rs.StepIndex(fromStep)
 		if !ok {
-			return fmt.Errorf("unknown step %q; valid steps: %s", fromStep, strings.Join(state.StepNames, ", "))
+			names := make([]string, len(rs.Steps))
+			for i, step := range rs.Steps {
+				names[i] = step.Name
+			}
+			return fmt.Errorf("unknown step %q; valid steps: %s", fromStep, strings.Join(names, ", "))
 		}
 		rs.ResetFrom(idx)
-		logger.Info("resuming from step", "step", state.StepNames[idx])
+		logger.Info("resuming from step", "step", rs.Steps[idx].Name)
 	} else {
 		if rs.Status == state.RunCompleted {
 			return fmt.Errorf("run %q already completed; use --from <step> to re-run from a specific step", runID)
 		}
-		// Reset failed status to active for re-run.
-		rs.Status = state.RunActive
-		// Reset any failed steps to pending so they get re-run.
-		for i := range rs.Steps {
-			if rs.Steps[i].Status == state.StepFailed {
-				rs.Steps[i].Status = state.StepPending
-				rs.Steps[i].Error = ""
-			}
-		}
+		rs.ResetFailed()
 	}
 
 	cfg, err := config.Load("forge.yaml")
@@ -80,7 +99,7 @@ func cmdResume(cobraCmd *cobra.Command, logger *slog.Logger, runID, fromStep str
 
 	logger.Info("resuming run", "id", runID, "mode", rs.Mode)
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	var pipelineErr error
@@ -116,3 +135,26 @@ func cmdResume(cobraCmd *cobra.Command, logger *slog.Logger, runID, fromStep str
 
 	return pipelineErr
 }
+
+func cmdResumeBatch(cobraCmd *cobra.Command, logger *slog.Logger, batchID string) error {
+	cfg, err := config.Load("forge.yaml")
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	applyOverrides(cobraCmd, cfg)
+
+	providers, err := wireProviders(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = pipeline.ResumeBatch(ctx, cfg, providers, batchID, os.Stdout, logger)
+	cleanupOldRuns(cfg, logger)
+	if cfg.Worktree.CleanupOnMerge {
+		cleanupMergedWorktrees(ctx, providers, logger)
+	}
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/shahar-caura/forge/internal/config"
@@ -23,26 +24,35 @@ func newRunCmd(logger *slog.Logger) *cobra.Command {
 		allIssues   bool
 		label       string
 		dryRun      bool
+		keepGoing   bool
 	)
 
 	cmd := &cobra.Command{
-		Use:   "run [plan.md]",
-		Short: "Execute a plan file, GitHub issue, or all open issues",
-		Args:  cobra.MaximumNArgs(1),
+		Use:   "run [plan.md...]",
+		Short: "Execute plan files, a GitHub issue, or all open issues",
+		Args:  cobra.ArbitraryArgs,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return []string{"md"}, cobra.ShellCompDirectiveFilterFileExt
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			hasPlan := len(args) == 1
+			hasPlan := len(args) > 0
 			hasIssue := issueNumber > 0
 
 			// Mutex: --all-issues is incompatible with plan file and --issue.
 			if allIssues && (hasPlan || hasIssue) {
 				return fmt.Errorf("--all-issues cannot be combined with a plan file or --issue")
 			}
+			if keepGoing && !allIssues {
+				return fmt.Errorf("--keep-going requires --all-issues")
+			}
 
 			if allIssues {
-				return cmdRunBatch(cmd, logger, label, dryRun)
+				return cmdRunBatch(cmd, logger, pipeline.BatchOptions{
+					Label:     label,
+					DryRun:    dryRun,
+					KeepGoing: keepGoing,
+					Out:       os.Stdout,
+				})
 			}
 
 			if hasPlan && hasIssue {
@@ -52,6 +62,10 @@ func newRunCmd(logger *slog.Logger) *cobra.Command {
 				return fmt.Errorf("provide a plan file argument, --issue, or --all-issues")
 			}
 
+			if len(args) > 1 {
+				return cmdRunPlans(cmd, logger, args, dryRun)
+			}
+
 			var planPath string
 			if hasPlan {
 				planPath = args[0]
@@ -63,14 +77,44 @@ func newRunCmd(logger *slog.Logger) *cobra.Command {
 	cmd.Flags().IntVar(&issueNumber, "issue", 0, "GitHub issue number to use as plan")
 	cmd.Flags().BoolVar(&allIssues, "all-issues", false, "Run all open issues in dependency order")
 	cmd.Flags().StringVar(&label, "label", "", "Filter issues by label (used with --all-issues)")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print execution plan without running (used with --all-issues)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print execution plan without running (used with --all-issues or multiple plans)")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Keep running issues that don't depend on a failed one, then print a summary (used with --all-issues)")
 	_ = cmd.RegisterFlagCompletionFunc("issue", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return completeIssueNumbers(toComplete)
 	})
 	return cmd
 }
 
-func cmdRunBatch(cmd *cobra.Command, logger *slog.Logger, label string, dryRun bool) error {
+func cmdRunBatch(cmd *cobra.Command, logger *slog.Logger, opts pipeline.BatchOptions) error {
+	cfg, err := config.Load("forge.yaml")
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	applyOverrides(cmd, cfg)
+
+	if cwd, err := os.Getwd(); err == nil {
+		registry.Touch(cwd)
+	}
+
+	providers, err := wireProviders(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = pipeline.RunBatch(ctx, cfg, providers, opts, logger)
+	if !opts.DryRun {
+		cleanupOldRuns(cfg, logger)
+		if cfg.Worktree.CleanupOnMerge {
+			cleanupMergedWorktrees(ctx, providers, logger)
+		}
+	}
+	return err
+}
+
+func cmdRunPlans(cmd *cobra.Command, logger *slog.Logger, planPaths []string, dryRun bool) error {
 	cfg, err := config.Load("forge.yaml")
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
@@ -86,10 +130,10 @@ func cmdRunBatch(cmd *cobra.Command, logger *slog.Logger, label string, dryRun b
 		return err
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	err = pipeline.RunBatch(ctx, cfg, providers, label, dryRun, logger)
+	err = pipeline.RunPlans(ctx, cfg, providers, planPaths, dryRun, logger)
 	if !dryRun {
 		cleanupOldRuns(cfg, logger)
 		if cfg.Worktree.CleanupOnMerge {
@@ -116,7 +160,7 @@ func cmdRun(cmd *cobra.Command, logger *slog.Logger, planPath string, issueNumbe
 		return err
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	// If --issue is set, fetch the issue and write a temp plan file.
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 
 	"github.com/shahar-caura/forge/internal/state"
@@ -9,14 +10,23 @@ import (
 )
 
 func newRunsCmd(logger *slog.Logger) *cobra.Command {
-	return &cobra.Command{
+	var batches bool
+
+	cmd := &cobra.Command{
 		Use:   "runs",
 		Short: "List all runs",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if batches {
+				return cmdBatches(cmd.OutOrStdout())
+			}
 			return cmdRuns(logger)
 		},
 	}
+
+	cmd.Flags().BoolVar(&batches, "batches", false, "list --all-issues batches instead, for forge resume --batch")
+
+	return cmd
 }
 
 func cmdRuns(logger *slog.Logger) error {
@@ -43,3 +53,34 @@ func cmdRuns(logger *slog.Logger) error {
 	_ = logger // unused but kept for consistency
 	return nil
 }
+
+// cmdBatches lists the recorded --all-issues batches, newest first.
+func cmdBatches(w io.Writer) error {
+	batches, err := state.ListBatches()
+	if err != nil {
+		return err
+	}
+
+	if len(batches) == 0 {
+		_, _ = fmt.Fprintln(w, "No batches found.")
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "%-30s  %-10s  %-20s  %-6s  %s\n", "ID", "STATUS", "CREATED", "DONE", "LABEL")
+	for _, b := range batches {
+		done := 0
+		for _, bi := range b.Issues {
+			if bi.Status == state.IssueCompleted {
+				done++
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%-30s  %-10s  %-20s  %-6s  %s\n",
+			b.ID,
+			b.Status,
+			b.CreatedAt.Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%d/%d", done, len(b.Issues)),
+			b.Label,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunsBatches_ListsNewestFirst(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	older := state.NewBatch("batch-older")
+	older.CreatedAt = time.Now().Add(-time.Hour)
+	older.Status = state.RunCompleted
+	older.Issues = []state.BatchIssue{{Number: 1, Status: state.IssueCompleted}}
+	require.NoError(t, older.Save())
+
+	newer := state.NewBatch("batch-newer")
+	newer.Label = "forge"
+	newer.Issues = []state.BatchIssue{
+		{Number: 2, Status: state.IssueCompleted},
+		{Number: 3, Status: state.IssueFailed},
+	}
+	require.NoError(t, newer.Save())
+
+	out, err := execForge(t, "runs", "--batches")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "DONE")
+	assert.Regexp(t, `^batch-newer\s+active\s+.*\s1/2\s+forge$`, lines[1])
+	assert.Regexp(t, `^batch-older\s+completed\s+.*\s1/1\s*$`, lines[2])
+
+	ids, _ := completeBatchIDs("batch-")
+	assert.Equal(t, []string{"batch-newer\tactive", "batch-older\tcompleted"}, ids)
+}
+
+func TestRunsBatches_None(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	out, err := execForge(t, "runs", "--batches")
+	require.NoError(t, err)
+	assert.Contains(t, out, "No batches found.")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/registry"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// stateless commands never touch run state, so they work even when the
+// configured state backend cannot be opened.
+var stateless = map[string]bool{
+	"version":    true,
+	"completion": true,
+	"help":       true,
+	"init":       true,
+}
+
+// openStateStore switches run state to the backend configured in forge.yaml.
+// The YAML backend needs no setup; the SQLite store is scoped to the current repo.
+func openStateStore(cmd *cobra.Command) error {
+	if stateless[cmd.Name()] {
+		return nil
+	}
+
+	sc, err := config.LoadState("forge.yaml")
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if sc.Backend != "sqlite" {
+		return nil
+	}
+
+	repo, err := filepath.Abs(".")
+	if err != nil {
+		return fmt.Errorf("resolving repo root: %w", err)
+	}
+	s, err := state.OpenSQLite(sc.Path, repo)
+	if err != nil {
+		return err
+	}
+	state.SetStore(s)
+	return nil
+}
+
+func newStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Manage the run state store",
+	}
+	cmd.AddCommand(newStateMigrateCmd())
+	return cmd
+}
+
+func newStateMigrateCmd() *cobra.Command {
+	var allRepos bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Import YAML run states into the SQLite state store",
+		Long: `Import the run state files under .forge/runs into the SQLite store
+configured by state.backend: sqlite. Re-running is safe: runs already in the
+database are overwritten with the file contents. The YAML files are left in place.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, ok := state.Current().(*state.SQLiteStore)
+			if !ok {
+				return errors.New("state.backend is not sqlite; set state.backend: sqlite in forge.yaml first")
+			}
+
+			if !allRepos {
+				return migrateRuns(cmd, db, filepath.Join(".forge", "runs"))
+			}
+
+			repos, err := registry.List()
+			if err != nil {
+				return fmt.Errorf("loading registry: %w", err)
+			}
+			for _, repo := range repos {
+				scoped := db.ForRepo(repo.Path).(*state.SQLiteStore)
+				if err := migrateRuns(cmd, scoped, filepath.Join(repo.Path, ".forge", "runs")); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&allRepos, "all-repos", false, "migrate every repo in the forge registry")
+
+	return cmd
+}
+
+func migrateRuns(cmd *cobra.Command, db *state.SQLiteStore, runsDir string) error {
+	runs, err := state.NewYAMLStore(runsDir).List(state.Query{})
+	if err != nil {
+		return err
+	}
+	if err := db.Import(runs); err != nil {
+		return fmt.Errorf("importing %s: %w", runsDir, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Imported %d run(s) from %s\n", len(runs), runsDir)
+	return nil
+}
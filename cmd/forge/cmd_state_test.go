@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execForge runs the root command with args in the current directory,
+// closing and resetting the state store it opened afterwards.
+func execForge(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	root := newRootCmd(slog.New(slog.NewTextHandler(&out, nil)))
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	err := root.Execute()
+	_ = state.Current().Close()
+	state.SetStore(nil)
+	return out.String(), err
+}
+
+func TestStateMigrate_SQLiteEndToEnd(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	dbPath := filepath.Join(dir, "db", "state.db")
+	require.NoError(t, os.WriteFile("forge.yaml", []byte("state:\n  backend: sqlite\n  path: "+dbPath+"\n"), 0o644))
+
+	files := state.NewYAMLStore(filepath.Join(".forge", "runs"))
+	done := state.New("run-done", "plans/a.md")
+	done.Status = state.RunCompleted
+	done.SourceIssue = 7
+	require.NoError(t, files.Save(done))
+	require.NoError(t, files.Save(state.New("run-active", "plans/b.md")))
+
+	out, err := execForge(t, "state", "migrate")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Imported 2 run(s)")
+
+	// Re-running is safe.
+	_, err = execForge(t, "state", "migrate")
+	require.NoError(t, err)
+
+	// Commands now read the database: drop the YAML files to prove it.
+	require.NoError(t, os.RemoveAll(".forge"))
+	_, err = execForge(t, "status", "run-done")
+	require.NoError(t, err)
+
+	db, err := state.OpenSQLite(dbPath, dir)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	runs, err := db.List(state.Query{})
+	require.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	completed, err := db.List(state.Query{Status: state.RunCompleted, SourceIssue: 7})
+	require.NoError(t, err)
+	require.Len(t, completed, 1)
+	assert.Equal(t, "run-done", completed[0].ID)
+
+	other, err := db.ForRepo("/elsewhere").List(state.Query{})
+	require.NoError(t, err)
+	assert.Empty(t, other, "runs are scoped to the migrated repo")
+}
+
+func TestStateMigrate_RequiresSQLiteBackend(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	_, err := execForge(t, "state", "migrate")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "state.backend is not sqlite")
+}
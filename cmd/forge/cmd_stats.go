@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/registry"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newStatsCmd() *cobra.Command {
+	var (
+		cost     bool
+		days     int
+		allRepos bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize runs by day and repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmdStats(cmd.OutOrStdout(), cost, days, allRepos)
+		},
+	}
+
+	cmd.Flags().BoolVar(&cost, "cost", false, "include agent token usage and cost")
+	cmd.Flags().IntVar(&days, "days", 30, "only count runs created in the last N days (0 = all)")
+	cmd.Flags().BoolVar(&allRepos, "all-repos", false, "include every repo in the forge registry")
+
+	return cmd
+}
+
+// statsRow aggregates the runs of one repo created on one day.
+type statsRow struct {
+	Day       string // YYYY-MM-DD, local time
+	Repo      string
+	Runs      int
+	Completed int
+	Failed    int
+	Usage     state.Usage
+}
+
+func cmdStats(w io.Writer, cost bool, days int, allRepos bool) error {
+	var repos []registry.RepoRuns
+	if allRepos {
+		var err error
+		if repos, err = registry.ListRuns(); err != nil {
+			return fmt.Errorf("loading registry: %w", err)
+		}
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting working directory: %w", err)
+		}
+		runs, err := state.List()
+		if err != nil {
+			return fmt.Errorf("listing runs: %w", err)
+		}
+		repos = []registry.RepoRuns{{Repo: registry.RepoEntry{Path: cwd, Name: filepath.Base(cwd)}, Runs: runs}}
+	}
+
+	var since time.Time
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	rows := rollupStats(repos, since)
+	if len(rows) == 0 {
+		_, _ = fmt.Fprintln(w, "No runs found.")
+		return nil
+	}
+
+	header := fmt.Sprintf("%-10s  %-20s  %5s  %9s  %6s", "DAY", "REPO", "RUNS", "COMPLETED", "FAILED")
+	if cost {
+		header += fmt.Sprintf("  %8s  %8s  %8s  %8s  %9s", "INPUT", "OUTPUT", "CACHED", "TOTAL", "COST")
+	}
+	_, _ = fmt.Fprintln(w, header)
+
+	printRow := func(r statsRow) {
+		line := fmt.Sprintf("%-10s  %-20s  %5d  %9d  %6d", r.Day, r.Repo, r.Runs, r.Completed, r.Failed)
+		if cost {
+			u := r.Usage
+			line += fmt.Sprintf("  %8s  %8s  %8s  %8s  %9s",
+				state.FormatTokens(u.InputTokens), state.FormatTokens(u.OutputTokens),
+				state.FormatTokens(u.CacheCreationTokens+u.CacheReadTokens), state.FormatTokens(u.Tokens()),
+				fmt.Sprintf("$%.2f", u.CostUSD))
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+
+	total := statsRow{Day: "TOTAL"}
+	for _, r := range rows {
+		printRow(r)
+		total.Runs += r.Runs
+		total.Completed += r.Completed
+		total.Failed += r.Failed
+		total.Usage.Add(r.Usage)
+	}
+	printRow(total)
+	return nil
+}
+
+// rollupStats groups runs created at or after since (zero = all) by local day
+// and repo, newest day first and repos alphabetically within a day.
+func rollupStats(repos []registry.RepoRuns, since time.Time) []statsRow {
+	type key struct{ day, repo string }
+	byKey := make(map[key]*statsRow)
+	for _, rr := range repos {
+		for _, rs := range rr.Runs {
+			if rs.CreatedAt.Before(since) {
+				continue
+			}
+			k := key{rs.CreatedAt.Local().Format("2006-01-02"), rr.Repo.Name}
+			row, ok := byKey[k]
+			if !ok {
+				row = &statsRow{Day: k.day, Repo: k.repo}
+				byKey[k] = row
+			}
+			row.Runs++
+			switch rs.Status {
+			case state.RunCompleted:
+				row.Completed++
+			case state.RunFailed:
+				row.Failed++
+			}
+			row.Usage.Add(rs.TotalUsage())
+		}
+	}
+
+	rows := make([]statsRow, 0, len(byKey))
+	for _, row := range byKey {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Day != rows[j].Day {
+			return rows[i].Day > rows[j].Day
+		}
+		return rows[i].Repo < rows[j].Repo
+	})
+	return rows
+}
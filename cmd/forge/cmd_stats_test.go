@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/registry"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+func statsRun(status state.RunStatus, created time.Time, cost float64) *state.RunState {
+	rs := state.New("r", "plans/x.md")
+	rs.Status = status
+	rs.CreatedAt = created
+	rs.Steps[4].Usage = state.Usage{InputTokens: 100, CostUSD: cost}
+	return rs
+}
+
+func TestRollupStats(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+
+	repos := []registry.RepoRuns{
+		{Repo: registry.RepoEntry{Name: "api"}, Runs: []*state.RunState{
+			statsRun(state.RunCompleted, day1, 0.5),
+			statsRun(state.RunFailed, day1.Add(time.Hour), 0.25),
+			statsRun(state.RunCompleted, day2, 1),
+			statsRun(state.RunCompleted, day1.AddDate(0, 0, -10), 9), // before since
+		}},
+		{Repo: registry.RepoEntry{Name: "web"}, Runs: []*state.RunState{
+			statsRun(state.RunActive, day1, 0),
+		}},
+	}
+
+	rows := rollupStats(repos, day1.AddDate(0, 0, -1))
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d: %+v", len(rows), rows)
+	}
+
+	// Newest day first, then repos alphabetically.
+	if rows[0].Day != "2026-03-02" || rows[0].Repo != "api" {
+		t.Errorf("row 0 = %s/%s, want 2026-03-02/api", rows[0].Day, rows[0].Repo)
+	}
+	api := rows[1]
+	if api.Day != "2026-03-01" || api.Repo != "api" || api.Runs != 2 || api.Completed != 1 || api.Failed != 1 {
+		t.Errorf("unexpected api row: %+v", api)
+	}
+	if api.Usage.CostUSD != 0.75 || api.Usage.InputTokens != 200 {
+		t.Errorf("api usage = %+v, want $0.75 and 200 input tokens", api.Usage)
+	}
+	if rows[2].Repo != "web" || rows[2].Runs != 1 || rows[2].Completed != 0 {
+		t.Errorf("unexpected web row: %+v", rows[2])
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/shahar-caura/forge/internal/state"
@@ -37,15 +38,25 @@ func cmdStatus(runID string) error {
 	fmt.Printf("Status:   %s\n", rs.Status)
 	fmt.Printf("Plan:     %s\n", rs.PlanPath)
 	fmt.Printf("Elapsed:  %s\n", elapsed)
+	if rs.Status == state.RunActive && rs.PID != 0 {
+		fmt.Printf("PID:      %d\n", rs.PID)
+	}
+	if owner := rs.LockOwner(); owner != nil {
+		fmt.Printf("Locked:   pid %d on %s (heartbeat %s ago)\n", owner.PID, owner.Host, time.Since(owner.Heartbeat).Truncate(time.Second))
+	}
 	if rs.Branch != "" {
 		fmt.Printf("Branch:   %s\n", rs.Branch)
 	}
 	if rs.PRUrl != "" {
 		fmt.Printf("PR:       %s\n", rs.PRUrl)
 	}
+	if usage := rs.TotalUsage(); !usage.IsZero() {
+		fmt.Printf("Usage:    %s\n", usage)
+	}
 	fmt.Println()
 
-	fmt.Printf("%-4s  %-20s  %-10s  %s\n", "STEP", "NAME", "STATUS", "ERROR")
+	now := time.Now()
+	fmt.Printf("%-4s  %-20s  %-10s  %-9s  %-8s  %s\n", "STEP", "NAME", "STATUS", "DURATION", "ATTEMPTS", "ERROR")
 	for i, step := range rs.Steps {
 		marker := " "
 		if step.Status == state.StepRunning {
@@ -58,7 +69,15 @@ func cmdStatus(runID string) error {
 				errMsg = errMsg[:60] + "..."
 			}
 		}
-		fmt.Printf("%s%3d  %-20s  %-10s  %s\n", marker, i, step.Name, step.Status, errMsg)
+		duration := "-"
+		if d := step.Elapsed(now); d > 0 {
+			duration = d.Truncate(time.Second).String()
+		}
+		attempts := "-"
+		if n := len(step.Attempts); n > 0 {
+			attempts = strconv.Itoa(n)
+		}
+		fmt.Printf("%s%3d  %-20s  %-10s  %-9s  %-8s  %s\n", marker, i, step.Name, step.Status, duration, attempts, errMsg)
 	}
 
 	return nil
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 
-	"github.com/shahar-caura/forge/internal/state"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +18,7 @@ func newStepsCmd() *cobra.Command {
 }
 
 func cmdSteps() error {
-	for i, name := range state.StepNames {
+	for i, name := range configuredStepNames() {
 		fmt.Printf("%2d  %s\n", i, name)
 	}
 	return nil
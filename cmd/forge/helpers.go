@@ -4,14 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/shahar-caura/forge/internal/config"
 	"github.com/shahar-caura/forge/internal/pipeline"
+	"github.com/shahar-caura/forge/internal/plan"
 	"github.com/shahar-caura/forge/internal/provider"
 	"github.com/shahar-caura/forge/internal/provider/agent"
 	"github.com/shahar-caura/forge/internal/provider/notifier"
@@ -39,9 +43,39 @@ func completeRunIDs(toComplete string) ([]string, cobra.ShellCompDirective) {
 	return ids, cobra.ShellCompDirectiveNoFileComp
 }
 
+// completeBatchIDs provides shell completion for batch IDs from
+// .forge/runs/batches/, newest first, described by status.
+func completeBatchIDs(toComplete string) ([]string, cobra.ShellCompDirective) {
+	batches, err := state.ListBatches()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, b := range batches {
+		if strings.HasPrefix(b.ID, toComplete) {
+			ids = append(ids, fmt.Sprintf("%s\t%s", b.ID, b.Status))
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// configuredStepNames returns the pipeline.steps sequence from forge.yaml,
+// falling back to the default steps when the config is missing or invalid.
+func configuredStepNames() []string {
+	cfg, err := config.Load("forge.yaml")
+	if err != nil {
+		return state.StepNames
+	}
+	steps, err := pipeline.BuildSteps(cfg)
+	if err != nil {
+		return state.StepNames
+	}
+	return pipeline.StepNames(steps)
+}
+
 func completeStepNames(toComplete string) ([]string, cobra.ShellCompDirective) {
 	var names []string
-	for _, name := range state.StepNames {
+	for _, name := range configuredStepNames() {
 		hyphenated := strings.ReplaceAll(name, " ", "-")
 		if strings.HasPrefix(hyphenated, toComplete) {
 			names = append(names, hyphenated)
@@ -107,6 +141,7 @@ func wireProviders(cfg *config.Config, logger *slog.Logger) (pipeline.Providers,
 		),
 		Agent:     pool.Primary(),
 		AgentPool: pool,
+		PlanAgent: planAgent(cfg, logger),
 		VCS:       vcs.New(cfg.VCS.Repo, logger),
 	}
 
@@ -143,20 +178,150 @@ func newAgentPool(cfg *config.Config, logger *slog.Logger) *pipeline.AgentPool {
 		agents[i] = newAgent(&agentCfg, logger)
 	}
 
-	return pipeline.NewAgentPool(agents, names)
+	pool := pipeline.NewAgentPool(agents, names)
+	pool.SetMaxConcurrent(cfg.Batch.MaxPerAgent)
+	if err := pool.LoadHealth(); err != nil {
+		logger.Warn("agent health unavailable, routing without history", "error", err)
+	}
+	return pool
 }
 
 func newAgent(cfg *config.Config, logger *slog.Logger) provider.Agent {
-	switch cfg.Agent.Provider {
+	ac := cfg.Agent
+	timeout := ac.Timeout.Duration
+	env := envList(ac.Env)
+
+	switch ac.Provider {
 	case "ralph":
-		return agent.NewRalph(cfg.Agent.Timeout.Duration, cfg.Agent.AllowedTools, logger)
+		a := agent.NewRalph(timeout, ac.AllowedTools, logger)
+		a.Model, a.Env = ac.Model, env
+		return a
 	case "codex":
-		return agent.NewCodex(cfg.Agent.Timeout.Duration, logger)
+		a := agent.NewCodex(timeout, logger)
+		a.Model, a.Env = ac.Model, env
+		return a
 	case "gemini":
-		return agent.NewGemini(cfg.Agent.Timeout.Duration, logger)
+		a := agent.NewGemini(timeout, logger)
+		a.Model, a.Env = ac.Model, env
+		return a
+	case "aider":
+		a := agent.NewAider(timeout, logger)
+		a.Model, a.Env = ac.Model, env
+		return a
+	case "replay":
+		rc := ac.Replay
+		innerCfg := *cfg
+		innerCfg.Agent.Provider = rc.Agent
+		return agent.NewReplay(rc.Mode, rc.Cassette, rc.Strict, newAgent(&innerCfg, logger), rc.Agent, logger)
+	case "exec":
+		a := agent.NewExec(timeout, ac.Exec.Command, ac.Exec.PromptInput, logger)
+		a.Env = env
+		return a
+	case "anthropic":
+		opts := agent.AnthropicOptions{
+			BaseURL:         ac.Anthropic.BaseURL,
+			Model:           ac.Anthropic.Model,
+			APIKey:          ac.Anthropic.APIKey,
+			MaxTokens:       ac.Anthropic.MaxTokens,
+			MaxTurns:        ac.Anthropic.MaxTurns,
+			AllowedCommands: ac.Anthropic.AllowedCommands,
+		}
+		if ac.Model != "" {
+			opts.Model = ac.Model
+		}
+		return agent.NewAnthropic(timeout, opts, logger)
 	default:
-		return agent.New(cfg.Agent.Timeout.Duration, logger)
+		a := agent.New(timeout, logger)
+		a.Model, a.AllowedTools, a.Env = ac.Model, ac.AllowedTools, env
+		return a
+	}
+}
+
+// planAgent returns a pipeline.Providers.PlanAgent that builds agent name for
+// a plan whose frontmatter overrides agent settings, on top of cfg's.
+// Plan env values may reference the environment (${OPENROUTER_API_KEY}).
+// Overrides the agent would ignore are an error rather than silently dropped.
+func planAgent(cfg *config.Config, logger *slog.Logger) func(*plan.Plan, string) (provider.Agent, error) {
+	return func(p *plan.Plan, name string) (provider.Agent, error) {
+		if !slices.Contains(config.AgentProviders, name) {
+			return nil, fmt.Errorf("unrecognized agent %q", name)
+		}
+		if unsupported := unsupportedOverrides(cfg, name, p); len(unsupported) > 0 {
+			return nil, fmt.Errorf("agent %s does not support %s", name, strings.Join(unsupported, ", "))
+		}
+
+		agentCfg := *cfg
+		ac := &agentCfg.Agent
+		ac.Provider = name
+		if p.Model != "" {
+			ac.Model = p.Model
+		}
+		if p.Timeout > 0 {
+			ac.Timeout.Duration = p.Timeout
+		}
+		if p.AllowedTools != "" {
+			ac.AllowedTools = p.AllowedTools
+		}
+		if len(p.Env) > 0 {
+			env := maps.Clone(cfg.Agent.Env)
+			if env == nil {
+				env = make(map[string]string, len(p.Env))
+			}
+			for k, v := range p.Env {
+				env[k] = os.ExpandEnv(v)
+			}
+			ac.Env = env
+		}
+		return newAgent(&agentCfg, logger), nil
+	}
+}
+
+// planOverrides lists the plan frontmatter settings each agent applies
+// besides timeout, which they all do (see newAgent).
+var planOverrides = map[string][]string{
+	"claude":    {"model", "allowed_tools", "env"},
+	"ralph":     {"model", "allowed_tools", "env"},
+	"codex":     {"model", "env"},
+	"gemini":    {"model", "env"},
+	"aider":     {"model", "env"},
+	"anthropic": {"model"},
+	"exec":      {"env"},
+}
+
+// unsupportedOverrides returns the settings p overrides that agent name
+// can't apply. The replay agent applies whatever the agent it wraps does.
+func unsupportedOverrides(cfg *config.Config, name string, p *plan.Plan) []string {
+	if name == "replay" {
+		name = cfg.Agent.Replay.Agent
+	}
+	supported := planOverrides[name]
+	var unsupported []string
+	for _, o := range []struct {
+		key string
+		set bool
+	}{
+		{"model", p.Model != ""},
+		{"allowed_tools", p.AllowedTools != ""},
+		{"env", len(p.Env) > 0},
+	} {
+		if o.set && !slices.Contains(supported, o.key) {
+			unsupported = append(unsupported, o.key)
+		}
+	}
+	return unsupported
+}
+
+// envList returns env as sorted KEY=VALUE entries, the form exec.Cmd takes.
+func envList(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
 	}
+	sort.Strings(list)
+	return list
 }
 
 // --- Git helpers ---
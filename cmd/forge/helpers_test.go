@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/plan"
+	"github.com/shahar-caura/forge/internal/provider/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanAgent_AppliesOverrides(t *testing.T) {
+	t.Setenv("FORGE_TEST_OPENROUTER_KEY", "sk-or-test")
+	cfg := &config.Config{Agent: config.AgentConfig{
+		Provider: "claude",
+		Timeout:  config.Duration{Duration: 45 * time.Minute},
+		Env:      map[string]string{"ANTHROPIC_BASE_URL": "https://proxy.internal", "KEEP": "1"},
+	}}
+	build := planAgent(cfg, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	ag, err := build(&plan.Plan{
+		Model:        "anthropic/claude-sonnet-4",
+		Timeout:      10 * time.Minute,
+		AllowedTools: "Read,Edit",
+		Env: map[string]string{
+			"ANTHROPIC_BASE_URL": "https://openrouter.ai/api",
+			"ANTHROPIC_API_KEY":  "${FORGE_TEST_OPENROUTER_KEY}",
+		},
+	}, "claude")
+	require.NoError(t, err)
+
+	c, ok := ag.(*agent.Claude)
+	require.True(t, ok, "got %T", ag)
+	assert.Equal(t, "anthropic/claude-sonnet-4", c.Model)
+	assert.Equal(t, 10*time.Minute, c.Timeout)
+	assert.Equal(t, "Read,Edit", c.AllowedTools)
+	assert.Equal(t, []string{
+		"ANTHROPIC_API_KEY=sk-or-test",
+		"ANTHROPIC_BASE_URL=https://openrouter.ai/api",
+		"KEEP=1",
+	}, c.Env)
+	assert.Equal(t, "https://proxy.internal", cfg.Agent.Env["ANTHROPIC_BASE_URL"], "config env must not change")
+}
+
+func TestPlanAgent_SwitchesProvider(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Provider: "claude", Timeout: config.Duration{Duration: time.Minute}}}
+	build := planAgent(cfg, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	ag, err := build(&plan.Plan{Agent: "codex", Model: "o3"}, "codex")
+	require.NoError(t, err)
+	c, ok := ag.(*agent.Codex)
+	require.True(t, ok, "got %T", ag)
+	assert.Equal(t, "o3", c.Model)
+	assert.Equal(t, time.Minute, c.Timeout)
+
+	_, err = build(&plan.Plan{Agent: "bogus"}, "bogus")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unrecognized agent "bogus"`)
+}
+
+func TestPlanAgent_RejectsUnsupportedOverrides(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{
+		Provider: "claude",
+		Timeout:  config.Duration{Duration: time.Minute},
+		Replay:   config.ReplayConfig{Agent: "codex"},
+	}}
+	build := planAgent(cfg, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	tests := []struct {
+		name string
+		plan plan.Plan
+		want string
+	}{
+		{"exec", plan.Plan{Model: "o3", Env: map[string]string{"K": "v"}}, "agent exec does not support model"},
+		{"anthropic", plan.Plan{Env: map[string]string{"K": "v"}}, "agent anthropic does not support env"},
+		{"codex", plan.Plan{Model: "o3", AllowedTools: "Read"}, "agent codex does not support allowed_tools"},
+		{"replay", plan.Plan{AllowedTools: "Read"}, "agent replay does not support allowed_tools"},
+	}
+	for _, tt := range tests {
+		_, err := build(&tt.plan, tt.name)
+		require.Error(t, err, tt.name)
+		assert.Equal(t, tt.want, err.Error())
+	}
+
+	_, err := build(&plan.Plan{Timeout: time.Minute}, "exec")
+	assert.NoError(t, err, "every agent applies timeout")
+}
@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/state"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +19,9 @@ var (
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
-	if err := newRootCmd(logger).Execute(); err != nil {
+	err := newRootCmd(logger).Execute()
+	_ = state.Current().Close()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
@@ -30,6 +34,9 @@ func newRootCmd(logger *slog.Logger) *cobra.Command {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Args:          cobra.ArbitraryArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return openStateStore(cmd)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return cmd.Help()
@@ -40,7 +47,7 @@ func newRootCmd(logger *slog.Logger) *cobra.Command {
 
 	root.PersistentFlags().String("agent", "", "override agent provider (e.g. claude, codex, gemini)")
 	_ = root.RegisterFlagCompletionFunc("agent", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"claude", "codex", "gemini", "ralph"}, cobra.ShellCompDirectiveNoFileComp
+		return config.AgentProviders, cobra.ShellCompDirectiveNoFileComp
 	})
 
 	root.AddCommand(
@@ -49,6 +56,7 @@ func newRootCmd(logger *slog.Logger) *cobra.Command {
 		newRunCmd(logger),
 		newPushCmd(logger),
 		newResumeCmd(logger),
+		newCancelCmd(logger),
 		newRunsCmd(logger),
 		newStatusCmd(),
 		newLogsCmd(),
@@ -57,6 +65,8 @@ func newRootCmd(logger *slog.Logger) *cobra.Command {
 		newCompletionCmd(),
 		newCleanupCmd(logger),
 		newServeCmd(logger),
+		newStateCmd(),
+		newStatsCmd(),
 	)
 
 	return root
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -39,6 +41,45 @@ type Config struct {
 	Editor   EditorConfig   `yaml:"editor"`
 	Hooks    HooksConfig    `yaml:"hooks"`
 	Server   ServerConfig   `yaml:"server"`
+	Pipeline PipelineConfig `yaml:"pipeline"`
+	Steps    []StepConfig   `yaml:"steps"`
+	Batch    BatchConfig    `yaml:"batch"`
+	Retry    RetryConfig    `yaml:"retry"`
+}
+
+// RetryConfig is the backoff policy the runner applies when an idempotent
+// step fails with a transient error (network blip, HTTP 5xx/529, rate limit).
+// Retries are opt-in: set max_attempts above 1 to enable them.
+type RetryConfig struct {
+	MaxAttempts     int      `yaml:"max_attempts"`     // tries per step, the first included (default 1: no retries)
+	InitialBackoff  Duration `yaml:"initial_backoff"`  // wait before the first retry (default 5s)
+	MaxBackoff      Duration `yaml:"max_backoff"`      // cap on any single wait (default 2m)
+	Multiplier      float64  `yaml:"multiplier"`       // backoff growth per retry (default 2)
+	Jitter          float64  `yaml:"jitter"`           // randomizes each wait by ± this fraction (default 0.2)
+	RetryableErrors []string `yaml:"retryable_errors"` // extra regexes (case-insensitive) marking an error transient
+}
+
+// BatchConfig controls scheduling for forge run --all-issues.
+type BatchConfig struct {
+	MergePollInterval Duration `yaml:"merge_poll_interval"` // how often to check an upstream PR (default 30s)
+	MergeTimeout      Duration `yaml:"merge_timeout"`       // max wait for an upstream PR to merge (default 24h)
+	Stacked           bool     `yaml:"stacked"`             // branch dependents off their dependency's branch ("Depends on #N" acts as "Stacked on #N")
+	MaxParallel       int      `yaml:"max_parallel"`        // max issues running at once (default 4)
+	MaxPerAgent       int      `yaml:"max_per_agent"`       // max concurrent issues per agent in agent.providers (default 2)
+}
+
+// PipelineConfig controls which steps a run executes and in what order.
+type PipelineConfig struct {
+	Steps []string `yaml:"steps"` // registered step names; empty means the default 11-step sequence
+}
+
+// StepConfig declares a user-defined shell step inserted into the pipeline.
+type StepConfig struct {
+	Name       string `yaml:"name"`
+	After      string `yaml:"after"`      // step to run after; empty appends to the end
+	Run        string `yaml:"run"`        // shell command, run in the worktree
+	OnFail     string `yaml:"on_fail"`    // "fail" (default), "warn", or "agent-fix"
+	Idempotent bool   `yaml:"idempotent"` // safe to rerun, so retried on transient errors
 }
 
 // ServerConfig holds settings for the dashboard HTTP server.
@@ -67,6 +108,8 @@ type CRConfig struct {
 
 type StateConfig struct {
 	Retention Duration `yaml:"retention"` // default 7 days (168h)
+	Backend   string   `yaml:"backend"`   // "yaml" (default) or "sqlite"
+	Path      string   `yaml:"path"`      // sqlite database; default <config dir>/forge/state.db
 }
 
 type VCSConfig struct {
@@ -90,10 +133,55 @@ type NotifierConfig struct {
 }
 
 type AgentConfig struct {
-	Provider     string   `yaml:"provider"`
-	Providers    []string `yaml:"providers"`
-	Timeout      Duration `yaml:"timeout"`
-	AllowedTools string   `yaml:"allowed_tools"`
+	Provider     string            `yaml:"provider"`
+	Providers    []string          `yaml:"providers"`
+	Timeout      Duration          `yaml:"timeout"`
+	AllowedTools string            `yaml:"allowed_tools"`
+	Model        string            `yaml:"model"` // passed to the agent CLI; empty uses its default
+	Env          map[string]string `yaml:"env"`   // extra agent process environment, e.g. ANTHROPIC_BASE_URL
+	Budget       BudgetConfig      `yaml:"budget"`
+	Anthropic    AnthropicConfig   `yaml:"anthropic"`
+	Exec         ExecAgentConfig   `yaml:"exec"`
+	Replay       ReplayConfig      `yaml:"replay"`
+}
+
+// ReplayConfig configures the "replay" agent, which records another agent's
+// calls (prompt hash, worktree patch, output) to a cassette directory and
+// plays them back offline.
+type ReplayConfig struct {
+	Mode     string `yaml:"mode"`     // "replay" (default) or "record"
+	Cassette string `yaml:"cassette"` // cassette directory (default .forge/cassettes/default)
+	Agent    string `yaml:"agent"`    // agent recorded, whose output format is replayed (default claude)
+	Strict   bool   `yaml:"strict"`   // fail on prompts missing from the cassette instead of replaying in order
+}
+
+// ExecAgentConfig configures the "exec" agent, which runs a custom command
+// (e.g. an in-house model wrapper) and takes its stdout as the output.
+type ExecAgentConfig struct {
+	Command     string `yaml:"command"`      // template with {{.Dir}}, {{.PromptFile}}, {{.RunID}}, {{.Step}}; split on whitespace before rendering, no shell
+	PromptInput string `yaml:"prompt_input"` // "stdin" (default) or "file"
+}
+
+// AgentProviders lists the agent provider names forge can wire.
+var AgentProviders = []string{"claude", "codex", "gemini", "ralph", "aider", "anthropic", "exec", "replay"}
+
+// AnthropicConfig configures the "anthropic" agent, which calls an Anthropic
+// Messages-compatible endpoint directly instead of shelling out to a CLI.
+type AnthropicConfig struct {
+	BaseURL         string   `yaml:"base_url"`         // default https://api.anthropic.com
+	Model           string   `yaml:"model"`            // required when the anthropic agent is used
+	APIKey          string   `yaml:"api_key"`          // optional for self-hosted endpoints
+	MaxTokens       int      `yaml:"max_tokens"`       // max output tokens per response (default 8192)
+	MaxTurns        int      `yaml:"max_turns"`        // max model round-trips per agent call (default 50)
+	AllowedCommands []string `yaml:"allowed_commands"` // command prefixes run_command accepts (default: git read-only + go build/test/vet)
+}
+
+// BudgetConfig caps agent spend; checked before every agent invocation.
+// Zero means no limit. Cost limits only see agents that report cost (claude).
+type BudgetConfig struct {
+	PerRunUSD           float64 `yaml:"per_run_usd"`           // total cost of one run, across resumes
+	PerDayUSD           float64 `yaml:"per_day_usd"`           // total cost of the repo's runs created today
+	MaxAgentInvocations int     `yaml:"max_agent_invocations"` // agent calls per run, across resumes
 }
 
 type WorktreeConfig struct {
@@ -113,8 +201,27 @@ const (
 	defaultRetention    = 7 * 24 * time.Hour // 168h
 	defaultPollTimeout  = 5 * time.Minute
 	defaultPollInterval = 15 * time.Second
+
+	defaultMergePollInterval = 30 * time.Second
+	defaultMergeTimeout      = 24 * time.Hour
+	defaultMaxParallel       = 4
+	defaultMaxPerAgent       = 2
+
+	defaultRetryMaxAttempts    = 1
+	defaultRetryInitialBackoff = 5 * time.Second
+	defaultRetryMaxBackoff     = 2 * time.Minute
+	defaultRetryMultiplier     = 2
+	defaultRetryJitter         = 0.2
+
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	defaultAnthropicMaxTokens = 8192
+	defaultAnthropicMaxTurns  = 50
 )
 
+// defaultAnthropicCommands is what the anthropic agent may run when
+// agent.anthropic.allowed_commands is unset.
+var defaultAnthropicCommands = []string{"ls", "git status", "git diff", "git log", "go build", "go test", "go vet"}
+
 // Load reads, expands env vars, parses, and validates a forge config file.
 // Environment variables are loaded from .forge.env files before expansion.
 func Load(path string) (*Config, error) {
@@ -138,9 +245,12 @@ func Load(path string) (*Config, error) {
 	if cfg.Agent.AllowedTools == "" && cfg.Agent.Provider == "ralph" {
 		cfg.Agent.AllowedTools = "Write,Read,Edit,Bash(git add *),Bash(git commit *),Bash(git diff *),Bash(git log *),Bash(git status),Bash(git status *),Bash(git push *),Bash(git pull *),Bash(git fetch *),Bash(git checkout *),Bash(git branch *),Bash(git stash *),Bash(git merge *),Bash(git tag *),Bash(go build *),Bash(go test *),Bash(go vet *),Bash(go fmt *),Bash(go mod *),Bash(go run *),Bash(make),Bash(make *)"
 	}
-	if cfg.State.Retention.Duration == 0 {
-		cfg.State.Retention.Duration = defaultRetention
+	applyAnthropicDefaults(&cfg.Agent.Anthropic)
+	if cfg.Agent.Exec.PromptInput == "" {
+		cfg.Agent.Exec.PromptInput = "stdin"
 	}
+	applyReplayDefaults(&cfg.Agent.Replay)
+	applyStateDefaults(&cfg.State)
 	if cfg.CR.Enabled {
 		if cfg.CR.Mode == "" {
 			cfg.CR.Mode = "poll"
@@ -163,6 +273,21 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
+	if cfg.Batch.MergePollInterval.Duration == 0 {
+		cfg.Batch.MergePollInterval.Duration = defaultMergePollInterval
+	}
+	if cfg.Batch.MergeTimeout.Duration == 0 {
+		cfg.Batch.MergeTimeout.Duration = defaultMergeTimeout
+	}
+	if cfg.Batch.MaxParallel == 0 {
+		cfg.Batch.MaxParallel = defaultMaxParallel
+	}
+	if cfg.Batch.MaxPerAgent == 0 {
+		cfg.Batch.MaxPerAgent = defaultMaxPerAgent
+	}
+
+	applyRetryDefaults(&cfg.Retry)
+
 	if cfg.Hooks.MaxHookRetries == 0 {
 		cfg.Hooks.MaxHookRetries = 2
 	}
@@ -171,6 +296,12 @@ func Load(path string) (*Config, error) {
 		cfg.Editor.Command = "code"
 	}
 
+	for i := range cfg.Steps {
+		if cfg.Steps[i].OnFail == "" {
+			cfg.Steps[i].OnFail = "fail"
+		}
+	}
+
 	if err := validate(&cfg); err != nil {
 		return nil, err
 	}
@@ -178,6 +309,141 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// LoadState reads only the state section of a forge config file, without
+// validating the rest, so every command can pick the state backend before it
+// loads (or fails to load) the full config. A missing file yields defaults.
+func LoadState(path string) (StateConfig, error) {
+	LoadEnvFiles()
+
+	var cfg struct {
+		State StateConfig `yaml:"state"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return StateConfig{}, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+		return StateConfig{}, fmt.Errorf("parsing config: %w", err)
+	}
+
+	applyStateDefaults(&cfg.State)
+	if err := validateState(cfg.State); err != nil {
+		return StateConfig{}, err
+	}
+	return cfg.State, nil
+}
+
+func applyAnthropicDefaults(ac *AnthropicConfig) {
+	if ac.BaseURL == "" {
+		ac.BaseURL = defaultAnthropicBaseURL
+	}
+	if ac.MaxTokens == 0 {
+		ac.MaxTokens = defaultAnthropicMaxTokens
+	}
+	if ac.MaxTurns == 0 {
+		ac.MaxTurns = defaultAnthropicMaxTurns
+	}
+	if ac.AllowedCommands == nil {
+		ac.AllowedCommands = append([]string(nil), defaultAnthropicCommands...)
+	}
+}
+
+func applyReplayDefaults(rc *ReplayConfig) {
+	if rc.Mode == "" {
+		rc.Mode = "replay"
+	}
+	if rc.Cassette == "" {
+		rc.Cassette = ".forge/cassettes/default"
+	}
+	if rc.Agent == "" {
+		rc.Agent = "claude"
+	}
+}
+
+func applyRetryDefaults(rc *RetryConfig) {
+	if rc.MaxAttempts == 0 {
+		rc.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if rc.InitialBackoff.Duration == 0 {
+		rc.InitialBackoff.Duration = defaultRetryInitialBackoff
+	}
+	if rc.MaxBackoff.Duration == 0 {
+		rc.MaxBackoff.Duration = defaultRetryMaxBackoff
+	}
+	if rc.Multiplier == 0 {
+		rc.Multiplier = defaultRetryMultiplier
+	}
+	if rc.Jitter == 0 {
+		rc.Jitter = defaultRetryJitter
+	}
+}
+
+func validateRetry(rc RetryConfig) []error {
+	var errs []error
+	if rc.MaxAttempts < 0 {
+		errs = append(errs, errors.New("retry.max_attempts must be positive"))
+	}
+	if rc.InitialBackoff.Duration < 0 || rc.MaxBackoff.Duration < 0 {
+		errs = append(errs, errors.New("retry backoffs must be >= 0"))
+	}
+	if rc.MaxBackoff.Duration < rc.InitialBackoff.Duration {
+		errs = append(errs, errors.New("retry.max_backoff must be >= retry.initial_backoff"))
+	}
+	if rc.Multiplier < 1 {
+		errs = append(errs, fmt.Errorf("retry.multiplier must be >= 1, got %g", rc.Multiplier))
+	}
+	if rc.Jitter < 0 || rc.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("retry.jitter must be between 0 and 1, got %g", rc.Jitter))
+	}
+	for i, pattern := range rc.RetryableErrors {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("retry.retryable_errors[%d]: %w", i, err))
+		}
+	}
+	return errs
+}
+
+// usesAgent reports whether name is the primary agent, in the agent pool,
+// the CR review agent, or the agent recorded by a replay agent among those.
+func usesAgent(cfg *Config, name string) bool {
+	names := append([]string{cfg.Agent.Provider, cfg.CR.Agent}, cfg.Agent.Providers...)
+	for _, n := range names {
+		if n == name || (n == "replay" && cfg.Agent.Replay.Agent == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyStateDefaults(sc *StateConfig) {
+	if sc.Retention.Duration == 0 {
+		sc.Retention.Duration = defaultRetention
+	}
+	if sc.Backend == "" {
+		sc.Backend = "yaml"
+	}
+	if sc.Path == "" {
+		sc.Path = DefaultStatePath()
+	}
+}
+
+func validateState(sc StateConfig) error {
+	switch sc.Backend {
+	case "yaml", "sqlite":
+		return nil
+	default:
+		return fmt.Errorf("state.backend must be \"yaml\" or \"sqlite\", got %q", sc.Backend)
+	}
+}
+
+// DefaultStatePath returns the path of the shared SQLite state database.
+func DefaultStatePath() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "forge", "state.db")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "forge", "state.db")
+}
+
 func validate(cfg *Config) error {
 	var errs []error
 
@@ -190,21 +456,70 @@ func validate(cfg *Config) error {
 	if cfg.VCS.BaseBranch == "" {
 		errs = append(errs, errors.New("vcs.base_branch is required"))
 	}
+	if err := validateState(cfg.State); err != nil {
+		errs = append(errs, err)
+	}
 	if cfg.Agent.Provider == "" {
 		errs = append(errs, errors.New("agent.provider is required"))
 	}
 	if cfg.Agent.Timeout.Duration <= 0 {
 		errs = append(errs, errors.New("agent.timeout must be positive"))
 	}
-	recognized := map[string]bool{"claude": true, "codex": true, "gemini": true, "ralph": true}
+	recognized := make(map[string]bool, len(AgentProviders))
+	for _, name := range AgentProviders {
+		recognized[name] = true
+	}
 	for _, name := range cfg.Agent.Providers {
 		if !recognized[name] {
 			errs = append(errs, fmt.Errorf("agent.providers: unrecognized agent %q", name))
 		}
 	}
+	if usesAgent(cfg, "anthropic") {
+		if cfg.Agent.Anthropic.Model == "" {
+			errs = append(errs, errors.New("agent.anthropic.model is required when the anthropic agent is used"))
+		}
+		if cfg.Agent.Anthropic.MaxTokens < 0 {
+			errs = append(errs, errors.New("agent.anthropic.max_tokens must be positive"))
+		}
+		if cfg.Agent.Anthropic.MaxTurns < 0 {
+			errs = append(errs, errors.New("agent.anthropic.max_turns must be positive"))
+		}
+	}
+	if usesAgent(cfg, "exec") {
+		if cfg.Agent.Exec.Command == "" {
+			errs = append(errs, errors.New("agent.exec.command is required when the exec agent is used"))
+		}
+		if p := cfg.Agent.Exec.PromptInput; p != "stdin" && p != "file" {
+			errs = append(errs, fmt.Errorf("agent.exec.prompt_input must be \"stdin\" or \"file\", got %q", p))
+		}
+	}
+	if usesAgent(cfg, "replay") {
+		if m := cfg.Agent.Replay.Mode; m != "replay" && m != "record" {
+			errs = append(errs, fmt.Errorf("agent.replay.mode must be \"replay\" or \"record\", got %q", m))
+		}
+		if a := cfg.Agent.Replay.Agent; !recognized[a] || a == "replay" {
+			errs = append(errs, fmt.Errorf("agent.replay.agent: unrecognized agent %q", a))
+		}
+	}
 	if cfg.Worktree.CreateCmd == "" {
 		errs = append(errs, errors.New("worktree.create_cmd is required"))
 	}
+	if cfg.Agent.Budget.PerRunUSD < 0 {
+		errs = append(errs, errors.New("agent.budget.per_run_usd must be >= 0"))
+	}
+	if cfg.Agent.Budget.PerDayUSD < 0 {
+		errs = append(errs, errors.New("agent.budget.per_day_usd must be >= 0"))
+	}
+	if cfg.Agent.Budget.MaxAgentInvocations < 0 {
+		errs = append(errs, errors.New("agent.budget.max_agent_invocations must be >= 0"))
+	}
+	if cfg.Batch.MaxParallel < 0 {
+		errs = append(errs, errors.New("batch.max_parallel must be positive"))
+	}
+	if cfg.Batch.MaxPerAgent < 0 {
+		errs = append(errs, errors.New("batch.max_per_agent must be positive"))
+	}
+	errs = append(errs, validateRetry(cfg.Retry)...)
 
 	// Only validate tracker fields when provider is set.
 	if cfg.Tracker.Provider != "" {
@@ -254,5 +569,24 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	seenSteps := make(map[string]bool, len(cfg.Steps))
+	for i, step := range cfg.Steps {
+		if step.Name == "" {
+			errs = append(errs, fmt.Errorf("steps[%d].name is required", i))
+		} else if seenSteps[step.Name] {
+			errs = append(errs, fmt.Errorf("steps: duplicate step name %q", step.Name))
+		}
+		seenSteps[step.Name] = true
+		if step.Run == "" {
+			errs = append(errs, fmt.Errorf("steps[%d].run is required", i))
+		}
+		switch step.OnFail {
+		case "fail", "warn", "agent-fix":
+			// valid
+		default:
+			errs = append(errs, fmt.Errorf("steps[%d].on_fail must be \"fail\", \"warn\", or \"agent-fix\", got %q", i, step.OnFail))
+		}
+	}
+
 	return errors.Join(errs...)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -566,14 +567,14 @@ vcs:
   base_branch: main
 agent:
   provider: claude
-  providers: [claude, gemini, codex]
+  providers: [claude, gemini, codex, aider]
 worktree:
   create_cmd: "echo hello"
 `
 	path := writeConfig(t, yaml)
 	cfg, err := Load(path)
 	require.NoError(t, err)
-	assert.Equal(t, []string{"claude", "gemini", "codex"}, cfg.Agent.Providers)
+	assert.Equal(t, []string{"claude", "gemini", "codex", "aider"}, cfg.Agent.Providers)
 }
 
 func TestLoad_AgentProviders_Empty(t *testing.T) {
@@ -631,3 +632,371 @@ cr:
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "cr.comment_pattern")
 }
+
+func TestLoad_PipelineStepsParsed(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+pipeline:
+  steps:
+    - read plan
+    - generate branch
+    - create worktree
+    - run agent
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"read plan", "generate branch", "create worktree", "run agent"}, cfg.Pipeline.Steps)
+}
+
+func TestLoad_PipelineStepsDefaultEmpty(t *testing.T) {
+	path := writeConfig(t, validYAML)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Empty(t, cfg.Pipeline.Steps)
+}
+
+func TestLoad_CustomStepsParsed(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+steps:
+  - name: lint-sql
+    after: run agent
+    run: make sqlcheck
+    on_fail: agent-fix
+  - name: smoke
+    run: make smoke
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Steps, 2)
+	assert.Equal(t, StepConfig{Name: "lint-sql", After: "run agent", Run: "make sqlcheck", OnFail: "agent-fix"}, cfg.Steps[0])
+	assert.Equal(t, "fail", cfg.Steps[1].OnFail, "on_fail defaults to fail")
+}
+
+func TestLoad_CustomStepsInvalid(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+steps:
+  - name: lint-sql
+    on_fail: retry
+  - name: lint-sql
+    run: make sqlcheck
+  - run: make smoke
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "steps[0].run is required")
+	assert.Contains(t, err.Error(), `steps[0].on_fail must be "fail", "warn", or "agent-fix", got "retry"`)
+	assert.Contains(t, err.Error(), `duplicate step name "lint-sql"`)
+	assert.Contains(t, err.Error(), "steps[2].name is required")
+}
+
+func TestLoad_AgentBudgetParsed(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  timeout: 30m\n", `  timeout: 30m
+  budget:
+    per_run_usd: 5
+    per_day_usd: 25.5
+    max_agent_invocations: 12
+`, 1))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, BudgetConfig{PerRunUSD: 5, PerDayUSD: 25.5, MaxAgentInvocations: 12}, cfg.Agent.Budget)
+}
+
+func TestLoad_AgentBudgetDefaultsUnlimited(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+	assert.Equal(t, BudgetConfig{}, cfg.Agent.Budget)
+}
+
+func TestLoad_AgentBudgetNegative(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  timeout: 30m\n", `  timeout: 30m
+  budget:
+    per_run_usd: -1
+    per_day_usd: -1
+    max_agent_invocations: -1
+`, 1))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent.budget.per_run_usd must be >= 0")
+	assert.Contains(t, err.Error(), "agent.budget.per_day_usd must be >= 0")
+	assert.Contains(t, err.Error(), "agent.budget.max_agent_invocations must be >= 0")
+}
+
+func TestLoad_AnthropicAgent(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  timeout: 30m\n", `  timeout: 30m
+  providers: [claude, anthropic]
+  anthropic:
+    base_url: https://openrouter.ai/api
+    model: anthropic/claude-sonnet-4
+    api_key: sk-or-test
+    allowed_commands: ["make test"]
+`, 1))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, AnthropicConfig{
+		BaseURL:         "https://openrouter.ai/api",
+		Model:           "anthropic/claude-sonnet-4",
+		APIKey:          "sk-or-test",
+		MaxTokens:       8192,
+		MaxTurns:        50,
+		AllowedCommands: []string{"make test"},
+	}, cfg.Agent.Anthropic)
+}
+
+func TestLoad_AnthropicAgentDefaults(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://api.anthropic.com", cfg.Agent.Anthropic.BaseURL)
+	assert.Contains(t, cfg.Agent.Anthropic.AllowedCommands, "go test")
+}
+
+func TestLoad_AnthropicAgentRequiresModel(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  timeout: 30m\n", "  timeout: 30m\n  providers: [anthropic]\n", 1))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent.anthropic.model is required")
+}
+
+func TestLoad_ExecAgent(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", `  provider: exec
+  exec:
+    command: "./bin/agent --prompt-file {{.PromptFile}}"
+    prompt_input: file
+`, 1))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, ExecAgentConfig{Command: "./bin/agent --prompt-file {{.PromptFile}}", PromptInput: "file"}, cfg.Agent.Exec)
+}
+
+func TestLoad_ExecAgentDefaultsToStdin(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", "  provider: exec\n  exec:\n    command: ./bin/agent\n", 1))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "stdin", cfg.Agent.Exec.PromptInput)
+}
+
+func TestLoad_ExecAgentInvalid(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", "  provider: exec\n  exec:\n    prompt_input: pipe\n", 1))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent.exec.command is required")
+	assert.Contains(t, err.Error(), `agent.exec.prompt_input must be "stdin" or "file", got "pipe"`)
+}
+
+func TestLoad_AgentModelAndEnv(t *testing.T) {
+	t.Setenv("FORGE_TEST_OPENROUTER_KEY", "sk-or-test")
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", `  provider: claude
+  model: claude-sonnet-4-5
+  env:
+    ANTHROPIC_BASE_URL: https://openrouter.ai/api
+    ANTHROPIC_API_KEY: ${FORGE_TEST_OPENROUTER_KEY}
+`, 1))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-5", cfg.Agent.Model)
+	assert.Equal(t, map[string]string{
+		"ANTHROPIC_BASE_URL": "https://openrouter.ai/api",
+		"ANTHROPIC_API_KEY":  "sk-or-test",
+	}, cfg.Agent.Env)
+}
+
+func TestLoad_ReplayAgentDefaults(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", "  provider: replay\n", 1))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayConfig{Mode: "replay", Cassette: ".forge/cassettes/default", Agent: "claude"}, cfg.Agent.Replay)
+}
+
+func TestLoad_ReplayAgentInvalid(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", `  provider: replay
+  replay:
+    mode: rewind
+    agent: replay
+`, 1))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `agent.replay.mode must be "replay" or "record", got "rewind"`)
+	assert.Contains(t, err.Error(), `agent.replay.agent: unrecognized agent "replay"`)
+}
+
+func TestLoad_ReplayAgentValidatesRecordedAgent(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "  provider: claude\n", `  provider: replay
+  replay:
+    mode: record
+    agent: anthropic
+`, 1))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent.anthropic.model is required")
+}
+
+func TestLoad_BatchDefaults(t *testing.T) {
+	path := writeConfig(t, validYAML)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 30*time.Second, cfg.Batch.MergePollInterval.Duration)
+	assert.Equal(t, 24*time.Hour, cfg.Batch.MergeTimeout.Duration)
+	assert.Equal(t, 4, cfg.Batch.MaxParallel)
+	assert.Equal(t, 2, cfg.Batch.MaxPerAgent)
+}
+
+func TestLoad_BatchConcurrencyParsed(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+batch:
+  max_parallel: 8
+  max_per_agent: 3
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, cfg.Batch.MaxParallel)
+	assert.Equal(t, 3, cfg.Batch.MaxPerAgent)
+}
+
+func TestLoad_BatchConcurrencyNegative(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+batch:
+  max_parallel: -1
+  max_per_agent: -2
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch.max_parallel must be positive")
+	assert.Contains(t, err.Error(), "batch.max_per_agent must be positive")
+}
+
+func TestLoad_RetryDefaults(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, RetryConfig{
+		MaxAttempts:    1,
+		InitialBackoff: Duration{Duration: 5 * time.Second},
+		MaxBackoff:     Duration{Duration: 2 * time.Minute},
+		Multiplier:     2,
+		Jitter:         0.2,
+	}, cfg.Retry)
+}
+
+func TestLoad_RetryParsed(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+retry:
+  max_attempts: 5
+  initial_backoff: 1s
+  max_backoff: 30s
+  multiplier: 3
+  jitter: 0.5
+  retryable_errors: ["secondary rate limit"]
+steps:
+  - name: seed
+    run: make seed
+    idempotent: true
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.Retry.MaxAttempts)
+	assert.Equal(t, time.Second, cfg.Retry.InitialBackoff.Duration)
+	assert.Equal(t, 30*time.Second, cfg.Retry.MaxBackoff.Duration)
+	assert.Equal(t, 3.0, cfg.Retry.Multiplier)
+	assert.Equal(t, 0.5, cfg.Retry.Jitter)
+	assert.Equal(t, []string{"secondary rate limit"}, cfg.Retry.RetryableErrors)
+	assert.True(t, cfg.Steps[0].Idempotent)
+}
+
+func TestLoad_RetryInvalid(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+retry:
+  max_attempts: -1
+  initial_backoff: 1m
+  max_backoff: 10s
+  multiplier: 0.5
+  jitter: 2
+  retryable_errors: ["(unclosed"]
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retry.max_attempts must be positive")
+	assert.Contains(t, err.Error(), "retry.max_backoff must be >= retry.initial_backoff")
+	assert.Contains(t, err.Error(), "retry.multiplier must be >= 1")
+	assert.Contains(t, err.Error(), "retry.jitter must be between 0 and 1")
+	assert.Contains(t, err.Error(), "retry.retryable_errors[0]")
+}
+
+func TestLoad_BatchMergeSettingsParsed(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+batch:
+  merge_poll_interval: 1m
+  merge_timeout: 6h
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Minute, cfg.Batch.MergePollInterval.Duration)
+	assert.Equal(t, 6*time.Hour, cfg.Batch.MergeTimeout.Duration)
+}
+
+func TestLoad_StateBackendDefaults(t *testing.T) {
+	path := writeConfig(t, validYAML)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "yaml", cfg.State.Backend)
+	assert.Equal(t, DefaultStatePath(), cfg.State.Path)
+}
+
+func TestLoad_StateBackendInvalid(t *testing.T) {
+	path := writeConfig(t, validYAML+`
+state:
+  backend: postgres
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `state.backend must be "yaml" or "sqlite", got "postgres"`)
+}
+
+func TestLoadState_IgnoresRestOfConfig(t *testing.T) {
+	path := writeConfig(t, `
+state:
+  backend: sqlite
+  path: /tmp/forge-state.db
+`)
+
+	sc, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", sc.Backend)
+	assert.Equal(t, "/tmp/forge-state.db", sc.Path)
+	assert.Equal(t, 7*24*time.Hour, sc.Retention.Duration)
+}
+
+func TestLoadState_MissingFileDefaults(t *testing.T) {
+	sc, err := LoadState(filepath.Join(t.TempDir(), "forge.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", sc.Backend)
+}
@@ -9,17 +9,39 @@ import (
 )
 
 var (
-	depPattern      = regexp.MustCompile(`(?i)(?:depends on|blocked by)\s+(#\d+(?:,\s*#\d+)*)`)
+	depPattern      = regexp.MustCompile(`(?i)(?:(?:depends on|blocked by)\s+(?:merge of\s+)?|stacked on\s+)(#\d+(?:,\s*#\d+)*)`)
+	mergeDepPattern = regexp.MustCompile(`(?i)(?:depends on|blocked by)\s+merge of\s+(#\d+(?:,\s*#\d+)*)`)
+	stackPattern    = regexp.MustCompile(`(?i)stacked on\s+(#\d+(?:,\s*#\d+)*)`)
 	issueNumPattern = regexp.MustCompile(`#(\d+)`)
 )
 
 // ParseDeps extracts issue dependencies from a GitHub issue body.
 // It looks for "Depends on #N" and "Blocked by #N" patterns (case-insensitive),
 // supporting comma-separated lists like "Depends on #1, #2, #3".
+// Merge dependencies ("Depends on merge of #N") and stack parents ("Stacked on #N")
+// are included.
 // Returns a deduplicated, sorted slice of issue numbers.
 func ParseDeps(body string) []int {
+	return parseIssueRefs(depPattern, body)
+}
+
+// ParseMergeDeps extracts dependencies whose PR must be merged, not just opened,
+// before the dependent starts: "Depends on merge of #N" or "Blocked by merge of #N".
+// Returns a deduplicated, sorted slice of issue numbers.
+func ParseMergeDeps(body string) []int {
+	return parseIssueRefs(mergeDepPattern, body)
+}
+
+// ParseStackDeps extracts stack parents: "Stacked on #N" means the dependent
+// branches off #N's branch and its PR targets #N's PR branch.
+// Returns a deduplicated, sorted slice of issue numbers.
+func ParseStackDeps(body string) []int {
+	return parseIssueRefs(stackPattern, body)
+}
+
+func parseIssueRefs(pattern *regexp.Regexp, body string) []int {
 	seen := map[int]bool{}
-	for _, match := range depPattern.FindAllStringSubmatch(body, -1) {
+	for _, match := range pattern.FindAllStringSubmatch(body, -1) {
 		for _, numMatch := range issueNumPattern.FindAllStringSubmatch(match[1], -1) {
 			n, err := strconv.Atoi(numMatch[1])
 			if err != nil {
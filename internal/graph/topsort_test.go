@@ -34,6 +34,30 @@ func TestParseDeps_CaseInsensitive(t *testing.T) {
 	assert.Equal(t, []int{7, 8}, ParseDeps(body))
 }
 
+func TestParseDeps_IncludesMergeDeps(t *testing.T) {
+	body := "Depends on #1\nDepends on merge of #2, #3"
+	assert.Equal(t, []int{1, 2, 3}, ParseDeps(body))
+}
+
+func TestParseMergeDeps(t *testing.T) {
+	body := "Depends on #1\nDepends on merge of #2, #3\nblocked by merge of #4"
+	assert.Equal(t, []int{2, 3, 4}, ParseMergeDeps(body))
+}
+
+func TestParseMergeDeps_None(t *testing.T) {
+	assert.Empty(t, ParseMergeDeps("Depends on #1"))
+}
+
+func TestParseDeps_IncludesStackDeps(t *testing.T) {
+	body := "Depends on #1\nStacked on #2"
+	assert.Equal(t, []int{1, 2}, ParseDeps(body))
+}
+
+func TestParseStackDeps(t *testing.T) {
+	body := "Depends on #1\nstacked on #2\nDepends on merge of #3"
+	assert.Equal(t, []int{2}, ParseStackDeps(body))
+}
+
 func TestParseDeps_Deduplicated(t *testing.T) {
 	body := "Depends on #5\nBlocked by #5"
 	assert.Equal(t, []int{5}, ParseDeps(body))
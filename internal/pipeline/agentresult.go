@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// agentResult parses the output of ag with its own parser. Output of agents
+// that don't implement provider.ResultParser is taken as plain text.
+func agentResult(ag provider.Agent, output string) provider.AgentResult {
+	if p, ok := ag.(provider.ResultParser); ok {
+		return p.ParseResult(output)
+	}
+	return provider.AgentResult{Text: output}
+}
+
+// finishAgent parses the output of an agent call started with startAgent,
+// adds its usage to the step executing under ctx and journals it as
+// agent_finished.
+func finishAgent(ctx context.Context, ag provider.Agent, output string) provider.AgentResult {
+	res := agentResult(ag, output)
+	usage := state.Usage{
+		InputTokens:         res.Usage.InputTokens,
+		OutputTokens:        res.Usage.OutputTokens,
+		CacheCreationTokens: res.Usage.CacheCreationTokens,
+		CacheReadTokens:     res.Usage.CacheReadTokens,
+		CostUSD:             res.Usage.CostUSD,
+	}
+	if m, ok := ctx.Value(meterKey{}).(*agentMeter); ok {
+		m.add(usage)
+	}
+
+	data := map[string]any{"tool_calls": len(res.ToolCalls)}
+	if res.ExitReason != "" {
+		data["exit_reason"] = res.ExitReason
+	}
+	if len(res.FilesTouched) > 0 {
+		data["files_touched"] = res.FilesTouched
+	}
+	if !usage.IsZero() {
+		data["tokens"] = usage.Tokens()
+		data["cost_usd"] = usage.CostUSD
+	}
+	record(ctx, state.EventAgentFinished, data)
+	return res
+}
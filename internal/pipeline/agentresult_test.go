@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resultAgent is a mockAgent whose output parses to a fixed result.
+type resultAgent struct {
+	*mockAgent
+	result provider.AgentResult
+}
+
+func (a resultAgent) ParseResult(string) provider.AgentResult { return a.result }
+
+// usageResult is a $0.42 agent call.
+var usageResult = provider.AgentResult{
+	Text:       "done",
+	ExitReason: "success",
+	Usage:      provider.Usage{InputTokens: 1200, OutputTokens: 800, CacheCreationTokens: 300, CacheReadTokens: 9000, CostUSD: 0.42},
+}
+
+func TestAgentResult_PlainTextWithoutParser(t *testing.T) {
+	assert.Equal(t, provider.AgentResult{Text: `{"result": "hi"}`}, agentResult(&mockAgent{}, `{"result": "hi"}`))
+}
+
+func TestAgentResult_UsesAgentParser(t *testing.T) {
+	ag := resultAgent{&mockAgent{}, usageResult}
+	assert.Equal(t, usageResult, agentResult(ag, "anything"))
+}
+
+func TestFallbackAgent_ParsesWithAgentThatRan(t *testing.T) {
+	primary := resultAgent{&mockAgent{err: errors.New("rate limit exceeded")}, provider.AgentResult{Text: "primary"}}
+	backup := resultAgent{&mockAgent{output: "ok"}, provider.AgentResult{Text: "backup"}}
+	pool := NewAgentPool([]provider.Agent{primary, backup}, []string{"claude", "codex"})
+	ag := NewFallbackAgent(pool, 0, testLogger())
+
+	output, err := ag.Run(context.Background(), "/dir", "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", agentResult(ag, output).Text)
+}
+
+func TestRun_RecordsAgentUsage(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/3", Number: 3}}
+	n := &mockNotifier{}
+	providers := Providers{VCS: vc, Agent: resultAgent{&mockAgent{}, usageResult}, Worktree: wt}
+	providers.Notifier = n
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+
+	idx, ok := rs.StepIndex("run agent")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), rs.Steps[idx].Usage.Calls)
+	assert.Equal(t, 0.42, rs.Steps[idx].Usage.CostUSD)
+	assert.Equal(t, int64(1200), rs.Steps[idx].Usage.InputTokens)
+
+	readIdx, _ := rs.StepIndex("read plan")
+	assert.True(t, rs.Steps[readIdx].Usage.IsZero())
+
+	require.Len(t, n.messages, 1)
+	assert.Contains(t, n.messages[0], "Agent usage: ")
+	assert.Contains(t, n.messages[0], "$0.42")
+
+	loaded, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rs.TotalUsage(), loaded.TotalUsage())
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	var finished []state.Event
+	for _, ev := range events {
+		if ev.Type == state.EventAgentFinished {
+			finished = append(finished, ev)
+		}
+	}
+	require.Len(t, finished, 1)
+	assert.Equal(t, "run agent", finished[0].Step)
+	assert.Equal(t, "success", finished[0].Data["exit_reason"])
+	assert.Equal(t, 0.42, finished[0].Data["cost_usd"])
+}
+
+func TestRun_LocalCR_ReadsReviewFromParsedResult(t *testing.T) {
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	// The raw review output is an envelope; only the parsed text has markers.
+	reviewAg := resultAgent{
+		&mockAgent{output: `{"type":"result"}`},
+		provider.AgentResult{Text: "---CRREVIEW---\n### Bug\n**Fix**: fix it\n---CRREVIEW---"},
+	}
+	providers := Providers{VCS: vc, Agent: &mockAgent{}, ReviewAgent: reviewAg, Worktree: wt}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	// Every review finds the bug, so the loop gives up after max_retries.
+	err := Run(context.Background(), testConfigWithLocalCR(), providers, planPath, rs, testLogger())
+
+	require.Error(t, err)
+	assert.Equal(t, "### Bug\n**Fix**: fix it", rs.CRFeedback)
+}
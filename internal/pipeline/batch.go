@@ -3,9 +3,11 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,12 +17,45 @@ import (
 	"github.com/shahar-caura/forge/internal/state"
 )
 
+// BatchOptions controls a forge run --all-issues batch.
+type BatchOptions struct {
+	Label     string    // only run open issues with this label (plus their dependencies)
+	DryRun    bool      // print the execution plan without running
+	KeepGoing bool      // on failure, skip only the failed issue's dependents and keep running the rest
+	Out       io.Writer // where the --keep-going summary table is written (nil = not written)
+}
+
+// IssueOutcome is the final status of an issue in a batch.
+type IssueOutcome string
+
+const (
+	IssueSucceeded IssueOutcome = "succeeded"
+	IssueFailed    IssueOutcome = "failed"
+	IssueBlocked   IssueOutcome = "blocked"
+)
+
+// IssueResult is the outcome of one issue in a batch run.
+type IssueResult struct {
+	Issue   int
+	Title   string
+	Outcome IssueOutcome
+	Detail  string // PR URL, error, or the failed issue that blocked it
+}
+
 // RunBatch fetches all open issues (optionally filtered by label), topologically
-// sorts them by dependency, and executes each in order. Sequential within each
-// level for V1.
+// sorts them by dependency, and executes them in parallel as their dependencies
+// finish (see executeBatch). By default no new issues start after the first
+// failure; with KeepGoing
+// only the failed issue's transitive dependents are skipped and a summary of
+// succeeded, failed and blocked issues is printed and sent to the notifier.
+// Issues with "Depends on merge of #N" wait for #N's PR to merge and start
+// their worktree from the freshly fetched base. Issues "Stacked on #N" branch
+// off #N's branch and open their PR against it; RestackMerged retargets them
+// once #N merges.
 func RunBatch(ctx context.Context, cfg *config.Config, providers Providers,
-	label string, dryRun bool, logger *slog.Logger,
+	opts BatchOptions, logger *slog.Logger,
 ) error {
+	label := opts.Label
 	issues, err := providers.VCS.ListIssues(ctx, "open", label)
 	if err != nil {
 		return fmt.Errorf("listing issues: %w", err)
@@ -70,113 +105,556 @@ func RunBatch(ctx context.Context, cfg *config.Config, providers Providers,
 	}
 
 	// Dry-run: print execution plan and return.
-	if dryRun {
+	if opts.DryRun {
 		printPlan(levels, titleMap, logger)
 		return nil
 	}
 
-	// Execute level by level, parallel within each level.
-	// Each parallel issue gets a different agent via round-robin to spread rate-limit pressure.
+	bs := state.NewBatch(time.Now().Format("20060102-150405") + "-batch")
+	bs.Label = label
+	bs.KeepGoing = opts.KeepGoing
+	bs.Levels = levels
+	for _, level := range levels {
+		for _, num := range level {
+			bs.Issues = append(bs.Issues, state.BatchIssue{
+				Number: num,
+				Title:  titleMap[num],
+				Body:   bodyMap[num],
+				Status: state.IssuePending,
+			})
+		}
+	}
+	if err := bs.Save(); err != nil {
+		return fmt.Errorf("saving batch state: %w", err)
+	}
+	logger.Info("batch started", "id", bs.ID, "issues", len(bs.Issues), "resume", "forge resume --batch "+bs.ID)
+
+	return executeBatch(ctx, cfg, providers, bs, opts.Out, logger)
+}
+
+// ResumeBatch continues a persisted batch without re-fetching issues. Completed
+// issues are skipped, issues whose run failed or was interrupted resume that
+// run, and issues that never started run fresh.
+func ResumeBatch(ctx context.Context, cfg *config.Config, providers Providers,
+	id string, out io.Writer, logger *slog.Logger,
+) error {
+	bs, err := state.LoadBatch(id)
+	if err != nil {
+		return err
+	}
+	if bs.Status == state.RunCompleted {
+		return fmt.Errorf("batch %q already completed", id)
+	}
+
+	bs.Status = state.RunActive
+	logger.Info("resuming batch", "id", bs.ID, "issues", len(bs.Issues))
+	return executeBatch(ctx, cfg, providers, bs, out, logger)
+}
+
+// executeBatch runs the batch's issues, starting each as soon as its own
+// dependencies succeed rather than waiting for the whole level. At most
+// batch.max_parallel issues run at once, and with an AgentPool each issue gets
+// the least-busy agent under batch.max_per_agent; issues waiting for a
+// dependency PR to merge hold neither. Each issue's run ID and status are
+// recorded in bs as it goes.
+func executeBatch(ctx context.Context, cfg *config.Config, providers Providers,
+	bs *state.BatchState, out io.Writer, logger *slog.Logger,
+) error {
+	issueSet := make(map[int]bool, len(bs.Issues))
+	depsMap := make(map[int][]int, len(bs.Issues))
+	titleMap := make(map[int]string, len(bs.Issues))
+	bodyMap := make(map[int]string, len(bs.Issues))
+	for _, bi := range bs.Issues {
+		issueSet[bi.Number] = true
+		titleMap[bi.Number] = bi.Title
+		bodyMap[bi.Number] = bi.Body
+		if deps := graph.ParseDeps(bi.Body); len(deps) > 0 {
+			depsMap[bi.Number] = deps
+		}
+	}
+
+	var mu sync.Mutex
+	update := func(num int, fn func(bi *state.BatchIssue)) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(bs.Issue(num))
+		_ = bs.Save()
+	}
+
+	// runIssue resumes prevRunID if set, otherwise starts a new run from
+	// mergeRef (see waitForMergeDeps; "" for the base branch). depBranches holds
+	// the branches of num's finished dependencies.
+	runIssue := func(p Providers, num int, prevRunID, mergeRef string, depBranches map[int]string) (*state.RunState, error) {
+		if prevRunID != "" {
+			rs, err := state.Load(prevRunID)
+			if err == nil {
+				logger.Info("resuming issue run", "issue", num, "id", rs.ID)
+				update(num, func(bi *state.BatchIssue) { bi.Status = state.IssueRunning })
+				rs.ResetFailed()
+				return rs, Run(ctx, cfg, p, rs.PlanPath, rs, logger)
+			}
+			logger.Warn("previous run not found, starting fresh", "issue", num, "id", prevRunID, "error", err)
+		}
+
+		base := runBase{ref: mergeRef}
+		mergeDeps := graph.ParseMergeDeps(bodyMap[num])
+		stackDeps := graph.ParseStackDeps(bodyMap[num])
+		if cfg.Batch.Stacked && len(mergeDeps) == 0 {
+			stackDeps = graph.ParseDeps(bodyMap[num])
+		}
+		parent, err := stackParent(num, stackDeps, depBranches, logger)
+		if err != nil {
+			return nil, err
+		}
+		if parent != "" {
+			logger.Info("stacking issue on dependency branch", "issue", num, "branch", parent)
+			base = runBase{ref: parent, prBase: parent}
+		}
+
+		rs, err := newIssueRun(num, titleMap[num], bodyMap[num], base)
+		if err != nil {
+			return nil, err
+		}
+		update(num, func(bi *state.BatchIssue) {
+			bi.RunID = rs.ID
+			bi.Status = state.IssueRunning
+		})
+		logger.Info("starting run from issue", "id", rs.ID, "issue", num, "title", titleMap[num])
+		return rs, Run(ctx, cfg, p, rs.PlanPath, rs, logger)
+	}
+
+	// Schedule issues as soon as their in-batch dependencies succeed, up to
+	// batch.max_parallel at once and the pool's per-agent limit.
+	inBatchDeps := make(map[int][]int, len(bs.Issues))
+	for num, deps := range depsMap {
+		for _, dep := range deps {
+			if issueSet[dep] {
+				inBatchDeps[num] = append(inBatchDeps[num], dep)
+			}
+		}
+	}
+
+	// PR numbers and branches of completed issues, for dependents that wait
+	// for a merge or stack on top of them.
+	prByIssue := make(map[int]int, len(bs.Issues))
+	branchByIssue := make(map[int]string, len(bs.Issues))
+	outcomes := make(map[int]IssueResult, len(bs.Issues))
+	var failed []int
+	blockedBy := make(map[int]int) // blocked issue → failed issue that blocked it
 	completed := 0
-	total := len(issueNumbers)
+	total := len(bs.Issues)
+	for _, bi := range bs.Issues {
+		if bi.Status != state.IssueCompleted {
+			continue
+		}
+		detail := ""
+		if rs, err := state.Load(bi.RunID); err == nil {
+			prByIssue[bi.Number] = rs.PRNumber
+			branchByIssue[bi.Number] = rs.Branch
+			detail = rs.PRUrl
+		}
+		completed++
+		outcomes[bi.Number] = IssueResult{Issue: bi.Number, Title: bi.Title, Outcome: IssueSucceeded, Detail: detail}
+		logger.Info("skipping completed issue", "issue", bi.Number, "id", bi.RunID)
+	}
+
+	type result struct {
+		num int
+		rs  *state.RunState
+		err error
+	}
+	done := make(chan result, len(bs.Issues))
+	started := make(map[int]bool, len(bs.Issues))
+	order := make([]int, len(bs.Issues))
+	prevRuns := make(map[int]string, len(bs.Issues))
+	for i, bi := range bs.Issues {
+		order[i] = bi.Number
+		prevRuns[bi.Number] = bi.RunID
+	}
 	pool := providers.AgentPool
-	for li, level := range levels {
-		if len(level) == 1 {
-			// Single issue — run directly, no goroutine overhead.
-			num := level[0]
+	maxParallel := cfg.Batch.MaxParallel
+	running := 0
+	var firstErr error
+
+	ready := func(num int) bool {
+		for _, dep := range inBatchDeps[num] {
+			if outcomes[dep].Outcome != IssueSucceeded {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Issues whose "Depends on merge of #N" PRs are still open wait outside
+	// the scheduler's slots: a waiter goroutine polls the PRs and reports the
+	// base to start from, and only then does the issue compete for
+	// batch.max_parallel and an agent. Resumed runs already passed the wait.
+	type mergeResult struct {
+		num int
+		ref string
+		err error
+	}
+	merged := make(chan mergeResult, len(bs.Issues))
+	mergeRefs := make(map[int]string, len(bs.Issues))
+	awaitingMerge := make(map[int]bool, len(bs.Issues))
+	waitCtx, stopWaiting := context.WithCancel(ctx)
+	defer stopWaiting()
+	needsMergeWait := func(num int) bool {
+		if _, ok := mergeRefs[num]; ok || len(graph.ParseMergeDeps(bodyMap[num])) == 0 {
+			return false
+		}
+		if prev := prevRuns[num]; prev != "" {
+			if _, err := state.Load(prev); err == nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Stacked batches restack children whose parent PR merged meanwhile, as
+	// issues finish and once the batch is done; forge cleanup catches later merges.
+	stacking := cfg.Batch.Stacked
+	for _, bi := range bs.Issues {
+		if len(graph.ParseStackDeps(bi.Body)) > 0 {
+			stacking = true
+		}
+	}
+	restack := func() {
+		if !stacking {
+			return
+		}
+		n, err := RestackMerged(ctx, providers.VCS, cfg.VCS.BaseBranch, logger)
+		if err != nil {
+			logger.Warn("restacking PRs of merged parents", "error", err)
+		} else if n > 0 {
+			logger.Info("restacked PRs onto merged parents' base", "count", n)
+		}
+	}
+
+	fail := func(num int, err error) {
+		update(num, func(bi *state.BatchIssue) {
+			bi.Status = state.IssueFailed
+			bi.Error = err.Error()
+		})
+		blocked := reportFailure(ctx, providers, num, err, depsMap, issueSet, !bs.KeepGoing, logger)
+		if firstErr == nil {
+			firstErr = fmt.Errorf("issue #%d (%s): %w", num, titleMap[num], err)
+		}
+		failed = append(failed, num)
+		outcomes[num] = IssueResult{Issue: num, Title: titleMap[num], Outcome: IssueFailed, Detail: err.Error()}
+		for _, b := range blocked {
+			if _, ok := blockedBy[b]; ok || started[b] || awaitingMerge[b] {
+				continue
+			}
+			blockedBy[b] = num
+			detail := fmt.Sprintf("blocked by #%d", num)
+			update(b, func(bi *state.BatchIssue) {
+				bi.Status = state.IssueBlocked
+				bi.Error = detail
+			})
+			outcomes[b] = IssueResult{Issue: b, Title: titleMap[b], Outcome: IssueBlocked, Detail: detail}
+			logger.Warn("skipping blocked issue", "issue", b, "blocked_by", num)
+		}
+	}
+
+	for {
+		// Start every ready issue that fits. Without keep-going, stop
+		// starting new issues after the first failure.
+		full := false
+		for _, num := range order {
+			if firstErr != nil && !bs.KeepGoing {
+				break
+			}
+			if _, ok := outcomes[num]; ok || started[num] || awaitingMerge[num] || !ready(num) {
+				continue
+			}
+
+			if needsMergeWait(num) {
+				depPRs := make(map[int]int, len(inBatchDeps[num]))
+				for _, dep := range inBatchDeps[num] {
+					depPRs[dep] = prByIssue[dep]
+				}
+				awaitingMerge[num] = true
+				go func(num int) {
+					ref, err := waitForMergeDeps(waitCtx, cfg, providers.VCS, num, graph.ParseMergeDeps(bodyMap[num]), depPRs, logger)
+					merged <- mergeResult{num: num, ref: ref, err: err}
+				}(num)
+				continue
+			}
+
+			if full {
+				continue
+			}
+			if maxParallel > 0 && running >= maxParallel {
+				full = true
+				continue
+			}
+
 			p := providers
+			var fa *fallbackAgent
 			if pool != nil {
-				p.Agent = NewFallbackAgent(pool, completed, logger)
-				logger.Info("running issue", "level", li+1, "issue", num, "title", titleMap[num], "agent", pool.AssignName(completed))
+				idx, ok := pool.TryAcquire()
+				if !ok {
+					full = true // every agent is at batch.max_per_agent
+					continue
+				}
+				fa = &fallbackAgent{pool: pool, startIdx: idx, held: true, logger: logger}
+				p.Agent = fa
+				logger.Info("running issue", "issue", num, "title", titleMap[num], "agent", pool.AssignName(idx), "running", running+1)
 			} else {
-				logger.Info("running issue", "level", li+1, "issue", num, "title", titleMap[num])
+				logger.Info("running issue", "issue", num, "title", titleMap[num], "running", running+1)
 			}
-			if err := runSingleIssue(ctx, cfg, p, num, titleMap[num], bodyMap[num], logger); err != nil {
-				reportFailure(ctx, providers, num, err, depsMap, issueSet, logger)
-				return fmt.Errorf("issue #%d (%s): %w", num, titleMap[num], err)
+
+			// Dependents only read their own dependencies' branches; hand
+			// them a copy so the map can keep growing here.
+			depBranches := make(map[int]string, len(inBatchDeps[num]))
+			for _, dep := range inBatchDeps[num] {
+				depBranches[dep] = branchByIssue[dep]
 			}
-			completed++
-			logger.Info("issue completed", "issue", num, "progress", fmt.Sprintf("%d/%d", completed, total))
-			continue
-		}
 
-		// Multiple independent issues — run in parallel.
-		logger.Info("running level in parallel", "level", li+1, "issues", level)
-		type result struct {
-			num int
-			err error
-		}
-		results := make([]result, len(level))
-		var wg sync.WaitGroup
-		for i, num := range level {
-			wg.Add(1)
-			go func(i, num int) {
-				defer wg.Done()
-				p := providers
-				globalIdx := completed + i
-				if pool != nil {
-					p.Agent = NewFallbackAgent(pool, globalIdx, logger)
-					logger.Info("running issue", "level", li+1, "issue", num, "title", titleMap[num], "agent", pool.AssignName(globalIdx))
-				} else {
-					logger.Info("running issue", "level", li+1, "issue", num, "title", titleMap[num])
+			started[num] = true
+			running++
+			go func(num int, prevRunID, mergeRef string) {
+				rs, err := runIssue(p, num, prevRunID, mergeRef, depBranches)
+				if fa != nil {
+					// Plan overrides may have moved the slot (see usePlan).
+					pool.Release(fa.startIdx)
 				}
-				results[i] = result{num: num, err: runSingleIssue(ctx, cfg, p, num, titleMap[num], bodyMap[num], logger)}
-			}(i, num)
+				done <- result{num: num, rs: rs, err: err}
+			}(num, prevRuns[num], mergeRefs[num])
 		}
-		wg.Wait()
 
-		// Check results — fail fast on first error.
-		for _, r := range results {
-			if r.err != nil {
-				reportFailure(ctx, providers, r.num, r.err, depsMap, issueSet, logger)
-				return fmt.Errorf("issue #%d (%s): %w", r.num, titleMap[r.num], r.err)
+		// Without keep-going, don't sit out merge waits after a failure.
+		stopping := firstErr != nil && !bs.KeepGoing
+		if running == 0 && (len(awaitingMerge) == 0 || stopping) {
+			break
+		}
+
+		var r result
+		select {
+		case m := <-merged:
+			delete(awaitingMerge, m.num)
+			if m.err != nil {
+				fail(m.num, m.err)
+			} else {
+				mergeRefs[m.num] = m.ref
 			}
-			completed++
-			logger.Info("issue completed", "issue", r.num, "progress", fmt.Sprintf("%d/%d", completed, total))
+			continue
+		case r = <-done:
+		}
+		running--
+		num := r.num
+		if r.err != nil {
+			fail(num, r.err)
+			continue
+		}
+
+		update(num, func(bi *state.BatchIssue) {
+			bi.Status = state.IssueCompleted
+			bi.Error = ""
+		})
+		prByIssue[num] = r.rs.PRNumber
+		branchByIssue[num] = r.rs.Branch
+		completed++
+		outcomes[num] = IssueResult{Issue: num, Title: titleMap[num], Outcome: IssueSucceeded, Detail: r.rs.PRUrl}
+		logger.Info("issue completed", "issue", num, "progress", fmt.Sprintf("%d/%d", completed, total))
+		restack()
+	}
+	restack()
+
+	// Without keep-going, stop once in-flight issues have finished and been recorded.
+	if firstErr != nil && !bs.KeepGoing {
+		bs.Status = state.RunFailed
+		_ = bs.Save()
+		return firstErr
+	}
+
+	results := make([]IssueResult, 0, len(outcomes))
+	for _, bi := range bs.Issues {
+		if r, ok := outcomes[bi.Number]; ok {
+			results = append(results, r)
 		}
 	}
 
-	logger.Info("batch complete", "completed", completed, "total", total)
+	bs.Status = state.RunCompleted
+	if len(failed) > 0 {
+		bs.Status = state.RunFailed
+	}
+	_ = bs.Save()
+
+	logger.Info("batch complete", "id", bs.ID, "completed", completed, "failed", len(failed), "blocked", len(blockedBy), "total", total)
+	if bs.KeepGoing {
+		summary := FormatBatchSummary(results)
+		if out != nil {
+			_, _ = fmt.Fprint(out, summary)
+		}
+		if providers.Notifier != nil {
+			_ = providers.Notifier.Notify(ctx, "forge batch summary:\n"+summary)
+		}
+	}
+	if len(failed) > 0 {
+		refs := make([]string, len(failed))
+		for i, num := range failed {
+			refs[i] = fmt.Sprintf("#%d", num)
+		}
+		return fmt.Errorf("%d of %d issues failed (%s), %d blocked", len(failed), total, strings.Join(refs, ", "), len(blockedBy))
+	}
 	return nil
 }
 
-func reportFailure(ctx context.Context, providers Providers, num int, err error, depsMap map[int][]int, issueSet map[int]bool, logger *slog.Logger) {
+// reportFailure logs a failed issue and the dependents it blocks, optionally
+// notifying. Returns the blocked issue numbers.
+func reportFailure(ctx context.Context, providers Providers, num int, err error, depsMap map[int][]int, issueSet map[int]bool, notify bool, logger *slog.Logger) []int {
 	logger.Error("issue failed", "issue", num, "error", err)
 	blocked := findBlocked(num, depsMap, issueSet)
 	if len(blocked) > 0 {
 		logger.Warn("blocked downstream issues", "blocked", blocked)
 	}
-	if providers.Notifier != nil {
+	if notify && providers.Notifier != nil {
 		msg := fmt.Sprintf("forge batch: issue #%d failed: %s\nBlocked: %v", num, err, blocked)
 		_ = providers.Notifier.Notify(ctx, msg)
 	}
+	return blocked
 }
 
-// runSingleIssue executes a single GitHub issue through the forge pipeline.
-func runSingleIssue(ctx context.Context, cfg *config.Config, providers Providers,
-	number int, title, body string, logger *slog.Logger,
-) error {
+// FormatBatchSummary renders batch results as a fixed-width table with a totals line.
+func FormatBatchSummary(results []IssueResult) string {
+	var b strings.Builder
+	counts := make(map[IssueOutcome]int, 3)
+	for _, r := range results {
+		counts[r.Outcome]++
+	}
+	fmt.Fprintf(&b, "%d succeeded, %d failed, %d blocked\n", counts[IssueSucceeded], counts[IssueFailed], counts[IssueBlocked])
+	fmt.Fprintf(&b, "%-6s  %-9s  %-30s  %s\n", "ISSUE", "STATUS", "TITLE", "DETAIL")
+	for _, r := range results {
+		title := r.Title
+		if len(title) > 30 {
+			title = title[:27] + "..."
+		}
+		detail := strings.ReplaceAll(r.Detail, "\n", " ")
+		if len(detail) > 80 {
+			detail = detail[:77] + "..."
+		}
+		fmt.Fprintf(&b, "%-6s  %-9s  %-30s  %s\n", fmt.Sprintf("#%d", r.Issue), r.Outcome, title, detail)
+	}
+	return b.String()
+}
+
+// waitForMergeDeps blocks until the PRs of num's in-batch merge dependencies
+// are merged, then fetches the base branch. Returns the ref the dependent's
+// worktree should start from, or "" when there is nothing to wait for.
+func waitForMergeDeps(ctx context.Context, cfg *config.Config, vcs provider.VCS,
+	num int, mergeDeps []int, prByIssue map[int]int, logger *slog.Logger,
+) (string, error) {
+	waited := false
+	for _, dep := range mergeDeps {
+		pr, ok := prByIssue[dep]
+		if !ok {
+			continue // external dependency, treated as resolved
+		}
+		if pr == 0 {
+			return "", fmt.Errorf("dependency #%d has no PR to wait for", dep)
+		}
+		logger.Info("waiting for dependency PR to merge", "issue", num, "dependency", dep, "pr", pr)
+		if err := waitForPRMerge(ctx, cfg, vcs, pr); err != nil {
+			return "", fmt.Errorf("waiting for dependency #%d: %w", dep, err)
+		}
+		waited = true
+	}
+	if !waited {
+		return "", nil
+	}
+
+	if err := vcs.FetchBase(ctx, "", cfg.VCS.BaseBranch); err != nil {
+		return "", fmt.Errorf("fetching base after dependency merge: %w", err)
+	}
+	return "origin/" + cfg.VCS.BaseBranch, nil
+}
+
+// stackParent returns the branch of num's in-batch stack parent, or "" when num
+// is not stacked. Parents outside the batch are treated as resolved. A branch
+// can only stack on one parent, so with several num branches off the base
+// branch instead.
+func stackParent(num int, stackDeps []int, branchByIssue map[int]string, logger *slog.Logger) (string, error) {
+	var parents []int
+	for _, dep := range stackDeps {
+		if _, ok := branchByIssue[dep]; ok {
+			parents = append(parents, dep)
+		}
+	}
+	switch len(parents) {
+	case 0:
+		return "", nil
+	case 1:
+		branch := branchByIssue[parents[0]]
+		if branch == "" {
+			return "", fmt.Errorf("stack parent #%d has no branch", parents[0])
+		}
+		return branch, nil
+	default:
+		logger.Warn("issue is stacked on more than one issue, branching off the base branch instead", "issue", num, "parents", parents)
+		return "", nil
+	}
+}
+
+// waitForPRMerge polls the PR state until it is MERGED, failing if it is
+// closed unmerged or batch.merge_timeout elapses.
+func waitForPRMerge(ctx context.Context, cfg *config.Config, vcs provider.VCS, pr int) error {
+	deadline := time.Now().Add(cfg.Batch.MergeTimeout.Duration)
+	for {
+		prState, err := vcs.GetPRState(ctx, pr)
+		if err != nil {
+			return fmt.Errorf("fetching PR #%d state: %w", pr, err)
+		}
+		switch prState {
+		case "MERGED":
+			return nil
+		case "CLOSED":
+			return fmt.Errorf("PR #%d was closed without merging", pr)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("merge timeout: PR #%d not merged after %s", pr, cfg.Batch.MergeTimeout.Duration)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.Batch.MergePollInterval.Duration):
+			// continue polling
+		}
+	}
+}
+
+// runBase is where an issue's run starts. Empty fields fall back to vcs.base_branch.
+type runBase struct {
+	ref    string // worktree start point, e.g. origin/main or a parent's branch
+	prBase string // branch the PR targets when stacked on a parent
+}
+
+// newIssueRun writes a temp plan file for a GitHub issue and saves the initial
+// run state for it.
+func newIssueRun(number int, title, body string, base runBase) (*state.RunState, error) {
 	slug := SlugFromTitle(title)
 	runID := time.Now().Format("20060102-150405") + "-" + slug
 
 	// Write temp plan file.
 	if err := os.MkdirAll(".forge/runs", 0o755); err != nil {
-		return fmt.Errorf("creating runs dir: %w", err)
+		return nil, fmt.Errorf("creating runs dir: %w", err)
 	}
 	planPath := filepath.Join(".forge/runs", runID+"-plan.md")
 	planContent := fmt.Sprintf("---\ntitle: %q\n---\n%s\n", title, body)
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
-		return fmt.Errorf("writing temp plan: %w", err)
+		return nil, fmt.Errorf("writing temp plan: %w", err)
 	}
 
 	rs := state.New(runID, planPath)
 	rs.SourceIssue = number
+	rs.BaseRef = base.ref
+	rs.PRBase = base.prBase
 	if err := rs.Save(); err != nil {
-		return fmt.Errorf("saving initial run state: %w", err)
+		return nil, fmt.Errorf("saving initial run state: %w", err)
 	}
-
-	logger.Info("starting run from issue", "id", runID, "issue", number, "title", title)
-	return Run(ctx, cfg, providers, planPath, rs, logger)
+	return rs, nil
 }
 
 // expandDeps iteratively discovers dependency issues that are not in the current
@@ -3,11 +3,17 @@ package pipeline
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/shahar-caura/forge/internal/config"
 	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,7 +68,7 @@ func TestRunBatch_DryRun_PrintsPlan(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc}
 
-	err := RunBatch(context.Background(), cfg, providers, "", true, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{DryRun: true}, batchLogger())
 
 	require.NoError(t, err)
 	assert.True(t, vc.listCalled)
@@ -79,7 +85,7 @@ func TestRunBatch_DryRun_WithLabel(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc}
 
-	err := RunBatch(context.Background(), cfg, providers, "forge", true, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{Label: "forge", DryRun: true}, batchLogger())
 
 	require.NoError(t, err)
 	assert.Equal(t, "forge", vc.listLabel)
@@ -91,7 +97,7 @@ func TestRunBatch_NoIssues(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc}
 
-	err := RunBatch(context.Background(), cfg, providers, "", false, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
 
 	require.NoError(t, err)
 }
@@ -102,7 +108,7 @@ func TestRunBatch_ListError(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc}
 
-	err := RunBatch(context.Background(), cfg, providers, "", false, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "listing issues")
@@ -119,7 +125,7 @@ func TestRunBatch_CycleError(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc}
 
-	err := RunBatch(context.Background(), cfg, providers, "", true, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{DryRun: true}, batchLogger())
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "topological sort")
@@ -172,7 +178,7 @@ func TestRunBatch_DryRun_ExternalDepsIgnored(t *testing.T) {
 	providers := Providers{VCS: vc}
 
 	// External dep #999 not in set — should not cause error.
-	err := RunBatch(context.Background(), cfg, providers, "", true, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{DryRun: true}, batchLogger())
 	require.NoError(t, err)
 }
 
@@ -271,13 +277,13 @@ func TestRunBatch_DryRun_WithLabel_ExpandsDeps(t *testing.T) {
 	providers := Providers{VCS: vc}
 
 	// With a label, expandDeps should fetch #1 even though it's not labeled.
-	err := RunBatch(context.Background(), cfg, providers, "dashboard", true, batchLogger())
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{Label: "dashboard", DryRun: true}, batchLogger())
 
 	require.NoError(t, err)
 }
 
-// Verify runSingleIssue creates temp plan and state files.
-func TestRunSingleIssue_CreatesPlanFile(t *testing.T) {
+// Verify newIssueRun creates temp plan and state files that Run can execute.
+func TestNewIssueRun_CreatesPlanFile(t *testing.T) {
 	origDir, err := os.Getwd()
 	require.NoError(t, err)
 	tmpDir := t.TempDir()
@@ -297,7 +303,14 @@ func TestRunSingleIssue_CreatesPlanFile(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc, Agent: ag, Worktree: wt}
 
-	err = runSingleIssue(context.Background(), cfg, providers, 42, "Add Auth", "Implement auth system.", batchLogger())
+	rs, err := newIssueRun(42, "Add Auth", "Implement auth system.", runBase{})
+	require.NoError(t, err)
+	assert.FileExists(t, rs.PlanPath)
+	saved, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 42, saved.SourceIssue)
+
+	err = Run(context.Background(), cfg, providers, rs.PlanPath, rs, batchLogger())
 
 	require.NoError(t, err)
 	assert.True(t, ag.Called())
@@ -373,7 +386,7 @@ func TestRunBatch_MultiAgentSpread(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc, Agent: a1, Worktree: wt, AgentPool: pool}
 
-	err = RunBatch(context.Background(), cfg, providers, "", false, batchLogger())
+	err = RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
 
 	require.NoError(t, err)
 	// Both agents should have been called (round-robin across 3 issues).
@@ -406,7 +419,7 @@ func TestRunBatch_SingleAgentPool_NoChange(t *testing.T) {
 	cfg := testConfig()
 	providers := Providers{VCS: vc, Agent: ag, Worktree: wt, AgentPool: pool}
 
-	err = RunBatch(context.Background(), cfg, providers, "", false, batchLogger())
+	err = RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
 
 	require.NoError(t, err)
 	assert.True(t, ag.Called())
@@ -436,8 +449,590 @@ func TestRunBatch_NilPool_BackwardsCompatible(t *testing.T) {
 	// No AgentPool — should work as before.
 	providers := Providers{VCS: vc, Agent: ag, Worktree: wt}
 
-	err = RunBatch(context.Background(), cfg, providers, "", false, batchLogger())
+	err = RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
 
 	require.NoError(t, err)
 	assert.True(t, ag.Called())
 }
+
+// --- Wait-for-merge dependency tests ---
+
+// mergeMockVCS returns prStates in order for each GetPRState call (last one repeats).
+type mergeMockVCS struct {
+	batchMockVCS
+	prStates       []string
+	prStateCalls   int
+	fetchBaseCalls int
+}
+
+func (m *mergeMockVCS) GetPRState(_ context.Context, _ int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.prStateCalls
+	if idx >= len(m.prStates) {
+		idx = len(m.prStates) - 1
+	}
+	m.prStateCalls++
+	return m.prStates[idx], nil
+}
+
+func (m *mergeMockVCS) FetchBase(_ context.Context, _, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetchBaseCalls++
+	return nil
+}
+
+// baseRecordingWorktree records the base passed to each Create call.
+type baseRecordingWorktree struct {
+	mockWorktree
+	bases []string
+}
+
+func (m *baseRecordingWorktree) Create(ctx context.Context, branch, baseBranch string) (string, error) {
+	m.mu.Lock()
+	m.bases = append(m.bases, baseBranch)
+	m.mu.Unlock()
+	return m.mockWorktree.Create(ctx, branch, baseBranch)
+}
+
+func mergeBatchSetup(t *testing.T, prStates []string) (*mergeMockVCS, *baseRecordingWorktree, Providers) {
+	t.Helper()
+	origDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		if err := os.Chdir(origDir); err != nil {
+			t.Errorf("restoring working dir: %v", err)
+		}
+	})
+
+	vc := &mergeMockVCS{
+		batchMockVCS: batchMockVCS{
+			mockVCS: mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/7", Number: 7}},
+			issues: []provider.GitHubIssue{
+				{Number: 1, Title: "Add schema", Body: "No deps."},
+				{Number: 2, Title: "Add API", Body: "Depends on merge of #1"},
+			},
+		},
+		prStates: prStates,
+	}
+	wt := &baseRecordingWorktree{mockWorktree: mockWorktree{createPath: t.TempDir()}}
+	return vc, wt, Providers{VCS: vc, Agent: &mockAgent{}, Worktree: wt}
+}
+
+func mergeBatchConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Batch.MergePollInterval = config.Duration{Duration: time.Millisecond}
+	cfg.Batch.MergeTimeout = config.Duration{Duration: time.Second}
+	return cfg
+}
+
+func TestRunBatch_WaitForMerge_StartsFromUpdatedBase(t *testing.T) {
+	vc, wt, providers := mergeBatchSetup(t, []string{"OPEN", "OPEN", "MERGED"})
+
+	err := RunBatch(context.Background(), mergeBatchConfig(), providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, vc.prStateCalls, "should poll until MERGED")
+	assert.Equal(t, 1, vc.fetchBaseCalls)
+	assert.Equal(t, []string{"main", "origin/main"}, wt.bases, "dependent worktree starts from fetched base")
+}
+
+func TestRunBatch_WaitForMerge_ClosedPRFails(t *testing.T) {
+	vc, wt, providers := mergeBatchSetup(t, []string{"CLOSED"})
+
+	err := RunBatch(context.Background(), mergeBatchConfig(), providers, BatchOptions{}, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "issue #2")
+	assert.Contains(t, err.Error(), "PR #7 was closed without merging")
+	assert.Zero(t, vc.fetchBaseCalls)
+	assert.Len(t, wt.bases, 1, "dependent should not start")
+}
+
+func TestRunBatch_WaitForMerge_Timeout(t *testing.T) {
+	_, _, providers := mergeBatchSetup(t, []string{"OPEN"})
+	cfg := mergeBatchConfig()
+	cfg.Batch.MergeTimeout = config.Duration{Duration: 5 * time.Millisecond}
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "merge timeout: PR #7")
+}
+
+// gatedMergeVCS reports the PR merged only once the agent has run after times.
+type gatedMergeVCS struct {
+	*mergeMockVCS
+	agent *mockAgent
+	after int
+}
+
+func (m *gatedMergeVCS) GetPRState(_ context.Context, _ int) (string, error) {
+	m.agent.mu.Lock()
+	defer m.agent.mu.Unlock()
+	if m.agent.callCount >= m.after {
+		return "MERGED", nil
+	}
+	return "OPEN", nil
+}
+
+func TestRunBatch_WaitForMerge_FreesSlotWhileWaiting(t *testing.T) {
+	merge, wt, providers := mergeBatchSetup(t, nil)
+	merge.issues = append(merge.issues, provider.GitHubIssue{Number: 3, Title: "Add docs", Body: "Depends on #1"})
+	ag := &mockAgent{}
+	// #1's PR merges only after #3 has run, which needs the only slot.
+	providers.VCS = &gatedMergeVCS{mergeMockVCS: merge, agent: ag, after: 2}
+	providers.Agent = ag
+	cfg := mergeBatchConfig()
+	cfg.Batch.MaxParallel = 1
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, ag.callCount)
+	assert.Equal(t, []string{"main", "main", "origin/main"}, wt.bases, "#3 runs while #2 waits for the merge")
+}
+
+func TestRunBatch_PlainDependency_DoesNotWaitForMerge(t *testing.T) {
+	vc, wt, providers := mergeBatchSetup(t, []string{"OPEN"})
+	vc.issues[1].Body = "Depends on #1"
+
+	err := RunBatch(context.Background(), mergeBatchConfig(), providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Zero(t, vc.prStateCalls)
+	assert.Equal(t, []string{"main", "main"}, wt.bases)
+}
+
+// --- Stacked branch tests ---
+
+// stackMockVCS records the base of each CreatePR call.
+type stackMockVCS struct {
+	*mergeMockVCS
+	prBases []string
+}
+
+func (m *stackMockVCS) CreatePR(ctx context.Context, branch, baseBranch, title, body string) (*provider.PR, error) {
+	m.mu.Lock()
+	m.prBases = append(m.prBases, baseBranch)
+	m.mu.Unlock()
+	return m.mergeMockVCS.CreatePR(ctx, branch, baseBranch, title, body)
+}
+
+func TestRunBatch_Stacked_BranchesOffParent(t *testing.T) {
+	merge, wt, providers := mergeBatchSetup(t, []string{"OPEN"})
+	vc := &stackMockVCS{mergeMockVCS: merge}
+	vc.issues[1].Body = "Stacked on #1"
+	providers.VCS = vc
+
+	err := RunBatch(context.Background(), mergeBatchConfig(), providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	require.Len(t, wt.bases, 2)
+	parent := wt.bases[1]
+	assert.True(t, strings.HasPrefix(parent, "forge/"), "child starts from parent branch, got %q", parent)
+	assert.Equal(t, []string{"main", parent}, vc.prBases, "child PR targets parent branch")
+	assert.Zero(t, vc.fetchBaseCalls, "stacking does not wait for merge; parent still open, so no restack")
+
+	runs, err := state.List()
+	require.NoError(t, err)
+	var child *state.RunState
+	for _, rs := range runs {
+		if rs.SourceIssue == 2 {
+			child = rs
+		}
+	}
+	require.NotNil(t, child)
+	assert.Equal(t, parent, child.PRBase)
+}
+
+func TestRunBatch_StackedMode_PlainDependencyStacks(t *testing.T) {
+	merge, wt, providers := mergeBatchSetup(t, []string{"OPEN"})
+	vc := &stackMockVCS{mergeMockVCS: merge}
+	vc.issues[1].Body = "Depends on #1"
+	providers.VCS = vc
+	cfg := mergeBatchConfig()
+	cfg.Batch.Stacked = true
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	require.Len(t, wt.bases, 2)
+	assert.NotEqual(t, "main", wt.bases[1])
+	assert.Equal(t, []string{"main", wt.bases[1]}, vc.prBases)
+}
+
+// restackBatchVCS numbers PRs from 10 in creation order and reports their
+// states from prStates, recording restack calls.
+type restackBatchVCS struct {
+	*stackMockVCS
+	prStates map[int]string
+	fetches  []string
+	rebases  [][]string
+	retarget map[int]string
+}
+
+func (m *restackBatchVCS) CreatePR(ctx context.Context, branch, baseBranch, title, body string) (*provider.PR, error) {
+	if _, err := m.stackMockVCS.CreatePR(ctx, branch, baseBranch, title, body); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 9 + len(m.prBases)
+	return &provider.PR{URL: fmt.Sprintf("https://github.com/owner/repo/pull/%d", n), Number: n, Base: baseBranch}, nil
+}
+
+func (m *restackBatchVCS) GetPRState(_ context.Context, pr int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.prStates[pr], nil
+}
+
+func (m *restackBatchVCS) FetchBase(_ context.Context, _, base string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetches = append(m.fetches, base)
+	return nil
+}
+
+func (m *restackBatchVCS) RebaseOnto(_ context.Context, _, branch, oldBase, newBase string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rebases = append(m.rebases, []string{branch, oldBase, newBase})
+	return nil
+}
+
+func (m *restackBatchVCS) UpdatePRBase(_ context.Context, pr int, base string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retarget[pr] = base
+	return nil
+}
+
+func TestRunBatch_Stacked_RestacksChildWhenParentMerged(t *testing.T) {
+	merge, wt, providers := mergeBatchSetup(t, []string{"OPEN"})
+	vc := &restackBatchVCS{
+		stackMockVCS: &stackMockVCS{mergeMockVCS: merge},
+		// The parent's PR (#10) merged while the child (#11) ran.
+		prStates: map[int]string{10: "MERGED", 11: "OPEN"},
+		retarget: map[int]string{},
+	}
+	vc.issues[1].Body = "Stacked on #1"
+	providers.VCS = vc
+
+	err := RunBatch(context.Background(), mergeBatchConfig(), providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	require.Len(t, wt.bases, 2)
+	parent := wt.bases[1]
+	assert.Equal(t, []string{"main"}, vc.fetches, "new base is fetched before rebasing")
+	assert.Len(t, vc.rebases, 1, "restacked once, not again at the end of the batch")
+	assert.Equal(t, []string{parent, "main"}, vc.rebases[0][1:])
+	assert.Equal(t, map[int]string{11: "main"}, vc.retarget)
+}
+
+func TestStackParent_MultipleParentsFallsBackToBase(t *testing.T) {
+	branch, err := stackParent(3, []int{1, 2}, map[int]string{1: "forge/a", 2: "forge/b"}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Empty(t, branch)
+}
+
+func TestStackParent_ExternalParentIgnored(t *testing.T) {
+	branch, err := stackParent(3, []int{99}, map[int]string{1: "forge/a"}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Empty(t, branch)
+}
+
+// --- Keep-going tests ---
+
+func keepGoingSetup(t *testing.T) (*planAgent, *mockNotifier, Providers) {
+	t.Helper()
+	origDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		if err := os.Chdir(origDir); err != nil {
+			t.Errorf("restoring working dir: %v", err)
+		}
+	})
+
+	vc := &batchMockVCS{
+		mockVCS: mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}},
+		issues: []provider.GitHubIssue{
+			{Number: 1, Title: "Broken", Body: "marker-1 FAIL"},
+			{Number: 2, Title: "Needs broken", Body: "marker-2\nDepends on #1"},
+			{Number: 3, Title: "Needs needs broken", Body: "marker-3\nDepends on #2"},
+			{Number: 4, Title: "Independent", Body: "marker-4"},
+			{Number: 5, Title: "Needs independent", Body: "marker-5\nDepends on #4"},
+		},
+	}
+	ag := &planAgent{}
+	n := &mockNotifier{}
+	wt := &mockWorktree{createPath: t.TempDir()}
+	return ag, n, Providers{VCS: vc, Agent: ag, Worktree: wt, Notifier: n}
+}
+
+func TestRunBatch_KeepGoing_SkipsOnlyDependents(t *testing.T) {
+	ag, n, providers := keepGoingSetup(t)
+	var out strings.Builder
+
+	err := RunBatch(context.Background(), testConfig(), providers, BatchOptions{KeepGoing: true, Out: &out}, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 5 issues failed (#1), 2 blocked")
+	_, ok := ag.ran("marker-2")
+	assert.False(t, ok, "#2 depends on failed #1")
+	_, ok = ag.ran("marker-3")
+	assert.False(t, ok, "#3 transitively depends on failed #1")
+	_, ok = ag.ran("marker-5")
+	assert.True(t, ok, "independent chain keeps running")
+
+	summary := out.String()
+	assert.Contains(t, summary, "2 succeeded, 1 failed, 2 blocked")
+	assert.Regexp(t, `#1\s+failed\s+Broken\s+.*agent exploded`, summary)
+	assert.Regexp(t, `#3\s+blocked\s+Needs needs broken\s+blocked by #1`, summary)
+	assert.Regexp(t, `#5\s+succeeded\s+Needs independent\s+https://github.com/owner/repo/pull/1`, summary)
+
+	last := n.messages[len(n.messages)-1]
+	assert.Contains(t, last, "forge batch summary")
+	assert.Contains(t, last, "2 succeeded, 1 failed, 2 blocked")
+	for _, m := range n.messages {
+		assert.NotContains(t, m, "forge batch: issue #1 failed", "per-issue batch alert is folded into the summary")
+	}
+}
+
+func TestRunBatch_FailFast_StopsStartingAfterFailure(t *testing.T) {
+	ag, n, providers := keepGoingSetup(t)
+	cfg := testConfig()
+	cfg.Batch.MaxParallel = 1
+	var out strings.Builder
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{Out: &out}, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "issue #1 (Broken)")
+	_, ok := ag.ran("marker-4")
+	assert.False(t, ok, "no new issues start after a failure without --keep-going")
+	assert.Empty(t, out.String())
+	assert.Contains(t, n.messages, "forge batch: issue #1 failed: step 5 (run agent): agent exploded\nBlocked: [2 3]")
+}
+
+func TestFormatBatchSummary_TruncatesLongFields(t *testing.T) {
+	summary := FormatBatchSummary([]IssueResult{
+		{Issue: 7, Title: strings.Repeat("t", 40), Outcome: IssueFailed, Detail: "line one\n" + strings.Repeat("e", 100)},
+	})
+
+	assert.Contains(t, summary, "0 succeeded, 1 failed, 0 blocked")
+	assert.Contains(t, summary, strings.Repeat("t", 27)+"...")
+	assert.NotContains(t, summary, strings.Repeat("e", 80))
+	assert.Contains(t, summary, "line one ")
+}
+
+// --- Batch resume tests ---
+
+// failSecondLevel makes #2 the failing issue, so with max_parallel 1 the batch
+// completes #1 and #4, fails #2 and leaves #5 and #3 pending.
+func failSecondLevel(providers Providers) *config.Config {
+	vc := providers.VCS.(*batchMockVCS)
+	vc.issues[0].Body = "marker-1"
+	vc.issues[1].Body = "marker-2 FAIL\nDepends on #1"
+	cfg := testConfig()
+	cfg.Batch.MaxParallel = 1
+	return cfg
+}
+
+func TestRunBatch_PersistsBatchState(t *testing.T) {
+	_, _, providers := keepGoingSetup(t)
+	cfg := failSecondLevel(providers)
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+	require.Error(t, err)
+
+	batches, err := state.ListBatches()
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	bs := batches[0]
+	assert.Equal(t, state.RunFailed, bs.Status)
+	assert.Equal(t, [][]int{{1, 4}, {2, 5}, {3}}, bs.Levels)
+	assert.Equal(t, state.IssueCompleted, bs.Issue(1).Status)
+	assert.Equal(t, state.IssueCompleted, bs.Issue(4).Status)
+	assert.Equal(t, state.IssueFailed, bs.Issue(2).Status)
+	assert.NotEmpty(t, bs.Issue(2).RunID)
+	assert.Contains(t, bs.Issue(2).Error, "agent exploded")
+	assert.Equal(t, state.IssuePending, bs.Issue(5).Status)
+	assert.Empty(t, bs.Issue(5).RunID)
+	assert.Equal(t, "marker-2 FAIL\nDepends on #1", bs.Issue(2).Body)
+}
+
+func TestResumeBatch_SkipsCompletedAndResumesFailedRun(t *testing.T) {
+	_, _, providers := keepGoingSetup(t)
+	cfg := failSecondLevel(providers)
+	require.Error(t, RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger()))
+	batches, err := state.ListBatches()
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	failedRunID := batches[0].Issue(2).RunID
+
+	// Fix the failed issue's plan; listing issues again must not be needed.
+	failedRun, err := state.Load(failedRunID)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(failedRun.PlanPath, []byte("marker-2 fixed"), 0o644))
+	ag := &planAgent{}
+	providers.Agent = ag
+	providers.VCS.(*batchMockVCS).listErr = errors.New("must not re-fetch issues")
+
+	err = ResumeBatch(context.Background(), cfg, providers, batches[0].ID, nil, batchLogger())
+
+	require.NoError(t, err)
+	for _, marker := range []string{"marker-1", "marker-4"} {
+		_, ok := ag.ran(marker)
+		assert.False(t, ok, "completed issue is skipped: %s", marker)
+	}
+	for _, marker := range []string{"marker-2", "marker-3", "marker-5"} {
+		_, ok := ag.ran(marker)
+		assert.True(t, ok, marker)
+	}
+
+	bs, err := state.LoadBatch(batches[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCompleted, bs.Status)
+	assert.Equal(t, failedRunID, bs.Issue(2).RunID, "failed run is resumed, not recreated")
+	for _, bi := range bs.Issues {
+		assert.Equal(t, state.IssueCompleted, bi.Status, "issue #%d", bi.Number)
+	}
+
+	runs, err := state.List()
+	require.NoError(t, err)
+	perIssue := map[int]int{}
+	for _, rs := range runs {
+		perIssue[rs.SourceIssue]++
+	}
+	assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 1}, perIssue, "no duplicate runs")
+
+	err = ResumeBatch(context.Background(), cfg, providers, bs.ID, nil, batchLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already completed")
+}
+
+// --- Scheduler tests ---
+
+// concurrencyAgent records the peak number of concurrent Run calls. Prompts
+// containing "wait-for-dependent" block until a prompt containing "dependent"
+// has started, or fail after a second.
+type concurrencyAgent struct {
+	mu        sync.Mutex
+	inflight  int
+	peak      int
+	dependent chan struct{}
+	once      sync.Once
+}
+
+func newConcurrencyAgent() *concurrencyAgent {
+	return &concurrencyAgent{dependent: make(chan struct{})}
+}
+
+func (a *concurrencyAgent) PromptSuffix() string { return "" }
+
+func (a *concurrencyAgent) Run(ctx context.Context, _, prompt string) (string, error) {
+	a.mu.Lock()
+	a.inflight++
+	if a.inflight > a.peak {
+		a.peak = a.inflight
+	}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.inflight--
+		a.mu.Unlock()
+	}()
+
+	switch {
+	case strings.Contains(prompt, "wait-for-dependent"):
+		select {
+		case <-a.dependent:
+		case <-time.After(time.Second):
+			return "", errors.New("dependent never started: level barrier")
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	case strings.Contains(prompt, "marker-dependent"):
+		a.once.Do(func() { close(a.dependent) })
+	default:
+		time.Sleep(20 * time.Millisecond)
+	}
+	return "", nil
+}
+
+func schedulerSetup(t *testing.T, issues []provider.GitHubIssue) Providers {
+	t.Helper()
+	origDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		if err := os.Chdir(origDir); err != nil {
+			t.Errorf("restoring working dir: %v", err)
+		}
+	})
+	vc := &batchMockVCS{
+		mockVCS: mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}},
+		issues:  issues,
+	}
+	return Providers{VCS: vc, Worktree: &mockWorktree{createPath: t.TempDir()}}
+}
+
+func independentIssues(n int) []provider.GitHubIssue {
+	issues := make([]provider.GitHubIssue, n)
+	for i := range issues {
+		issues[i] = provider.GitHubIssue{Number: i + 1, Title: fmt.Sprintf("Issue %d", i+1), Body: "No deps."}
+	}
+	return issues
+}
+
+func TestRunBatch_MaxParallelBoundsConcurrency(t *testing.T) {
+	providers := schedulerSetup(t, independentIssues(6))
+	ag := newConcurrencyAgent()
+	providers.Agent = ag
+	cfg := testConfig()
+	cfg.Batch.MaxParallel = 2
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, ag.peak)
+}
+
+func TestRunBatch_StartsDependentWithoutLevelBarrier(t *testing.T) {
+	providers := schedulerSetup(t, []provider.GitHubIssue{
+		{Number: 1, Title: "Slow", Body: "wait-for-dependent"},
+		{Number: 2, Title: "Fast", Body: "marker-fast"},
+		{Number: 3, Title: "Dependent", Body: "marker-dependent\nDepends on #2"},
+	})
+	providers.Agent = newConcurrencyAgent()
+	cfg := testConfig()
+	cfg.Batch.MaxParallel = 4
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err, "#3 must start while #1 (same level as #2) is still running")
+}
+
+func TestRunBatch_MaxPerAgentBoundsEachAgent(t *testing.T) {
+	providers := schedulerSetup(t, independentIssues(6))
+	a1, a2 := newConcurrencyAgent(), newConcurrencyAgent()
+	pool := NewAgentPool([]provider.Agent{a1, a2}, []string{"claude", "codex"})
+	pool.SetMaxConcurrent(1)
+	providers.Agent = a1
+	providers.AgentPool = pool
+	cfg := testConfig()
+	cfg.Batch.MaxParallel = 10
+
+	err := RunBatch(context.Background(), cfg, providers, BatchOptions{}, batchLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, a1.peak)
+	assert.Equal(t, 1, a2.peak)
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// BudgetExceededError reports that a run stopped because an agent.budget
+// limit was reached. Resuming fails the same way until the limit is raised.
+type BudgetExceededError struct {
+	Limit string  // agent.budget key: per_run_usd, per_day_usd or max_agent_invocations
+	Spent float64 // dollars, or agent calls for max_agent_invocations
+	Max   float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	switch e.Limit {
+	case "max_agent_invocations":
+		return fmt.Sprintf("budget exceeded: run made %.0f agent calls (agent.budget.max_agent_invocations: %.0f)", e.Spent, e.Max)
+	case "per_day_usd":
+		return fmt.Sprintf("budget exceeded: today's runs spent $%.2f (agent.budget.per_day_usd: $%.2f)", e.Spent, e.Max)
+	default:
+		return fmt.Sprintf("budget exceeded: run spent $%.2f (agent.budget.%s: $%.2f)", e.Spent, e.Limit, e.Max)
+	}
+}
+
+// agentMeter accounts the agent calls of the step executing under a context
+// and enforces agent.budget before each one.
+type agentMeter struct {
+	rs     *state.RunState
+	step   *state.StepState // the executing step, whose usage the meter keeps current
+	budget config.BudgetConfig
+	usage  state.Usage // this step attempt's calls
+}
+
+type meterKey struct{}
+
+// withMeter makes startAgent and finishAgent account agent calls under ctx to m.
+func withMeter(ctx context.Context, m *agentMeter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// startAgent must precede every agent invocation. It fails with a
+// *BudgetExceededError when a budget limit is already reached; otherwise it
+// counts the call and journals it as agent_invoked with data.
+func startAgent(ctx context.Context, data map[string]any) error {
+	if m, ok := ctx.Value(meterKey{}).(*agentMeter); ok {
+		if err := m.checkBudget(); err != nil {
+			record(ctx, state.EventBudgetExceeded, map[string]any{"limit": err.Limit, "spent": err.Spent, "max": err.Max})
+			return err
+		}
+		m.add(state.Usage{Calls: 1})
+	}
+	record(ctx, state.EventAgentInvoked, data)
+	return nil
+}
+
+// add accounts u to the step attempt and its step, saving the run so the
+// per-day checks of concurrent runs see spend while the step still runs.
+func (m *agentMeter) add(u state.Usage) {
+	m.usage.Add(u)
+	m.step.Usage.Add(u)
+	_ = m.rs.Save()
+}
+
+// checkBudget returns the first limit the run has reached, or nil.
+func (m *agentMeter) checkBudget() *BudgetExceededError {
+	b := m.budget
+	run := m.rs.TotalUsage()
+
+	if b.MaxAgentInvocations > 0 && run.Calls >= int64(b.MaxAgentInvocations) {
+		return &BudgetExceededError{Limit: "max_agent_invocations", Spent: float64(run.Calls), Max: float64(b.MaxAgentInvocations)}
+	}
+	if b.PerRunUSD > 0 && run.CostUSD >= b.PerRunUSD {
+		return &BudgetExceededError{Limit: "per_run_usd", Spent: run.CostUSD, Max: b.PerRunUSD}
+	}
+	if b.PerDayUSD > 0 {
+		if spent := m.spentToday(run); spent >= b.PerDayUSD {
+			return &BudgetExceededError{Limit: "per_day_usd", Spent: spent, Max: b.PerDayUSD}
+		}
+	}
+	return nil
+}
+
+// spentToday sums the cost of the runs created today (local time), counting
+// this run's usage run rather than its last save. Other runs are read as
+// saved, which includes their running steps' calls so far (see add). Days are
+// attributed like forge stats: by the day a run was created.
+func (m *agentMeter) spentToday(run state.Usage) float64 {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var spent float64
+	if !m.rs.CreatedAt.Before(midnight) {
+		spent = run.CostUSD
+	}
+	runs, err := state.ListQuery(state.Query{UpdatedAfter: midnight})
+	if err != nil {
+		return spent // can't see other runs; enforce what we know
+	}
+	for _, rs := range runs {
+		if rs.ID == m.rs.ID || rs.CreatedAt.Before(midnight) {
+			continue
+		}
+		spent += rs.TotalUsage().CostUSD
+	}
+	return spent
+}
@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetConfig returns a config whose pre-commit hook always fails, so every
+// hook retry asks the agent for another fix.
+func budgetConfig(b config.BudgetConfig) *config.Config {
+	cfg := testConfig()
+	cfg.Agent.Budget = b
+	cfg.Hooks.PreCommit = "exit 1"
+	cfg.Hooks.MaxHookRetries = 5
+	return cfg
+}
+
+func TestRun_BudgetMaxInvocationsStopsHookRetries(t *testing.T) {
+	chdirTemp(t)
+	ag := &mockAgent{}
+	n := &mockNotifier{}
+	providers := defaultProviders(&mockWorktree{createPath: t.TempDir()}, ag, &mockVCS{})
+	providers.Notifier = n
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), budgetConfig(config.BudgetConfig{MaxAgentInvocations: 2}), providers, planPath, rs, testLogger())
+
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "max_agent_invocations", budgetErr.Limit)
+	assert.Equal(t, 2, ag.callCount, "run agent + one hook fix, then the budget stops the loop")
+	assert.Equal(t, state.RunFailed, rs.Status)
+	assert.Equal(t, int64(2), rs.TotalUsage().Calls)
+
+	require.Len(t, n.messages, 1)
+	assert.Contains(t, n.messages[0], "budget exceeded")
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	assert.Contains(t, eventTypes(events), "commit and push/budget_exceeded")
+}
+
+func TestRun_BudgetPerRunCost(t *testing.T) {
+	chdirTemp(t)
+	ag := &mockAgent{}
+	providers := Providers{VCS: &mockVCS{}, Agent: resultAgent{ag, usageResult}, Worktree: &mockWorktree{createPath: t.TempDir()}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), budgetConfig(config.BudgetConfig{PerRunUSD: 0.8}), providers, planPath, rs, testLogger())
+
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "per_run_usd", budgetErr.Limit)
+	assert.InDelta(t, 0.84, budgetErr.Spent, 1e-9)
+	assert.Equal(t, 2, ag.callCount)
+
+	// The limit holds across resume until it is raised.
+	rs.ResetFailed()
+	err = Run(context.Background(), budgetConfig(config.BudgetConfig{PerRunUSD: 0.8}), providers, planPath, rs, testLogger())
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, 2, ag.callCount)
+}
+
+func TestRun_BudgetPerDayCountsOtherRuns(t *testing.T) {
+	chdirTemp(t)
+	earlier := state.New("20260217-110000-earlier", "plans/earlier.md")
+	earlier.Status = state.RunCompleted
+	earlier.Steps[4].Usage = state.Usage{Calls: 3, CostUSD: 4.5}
+	require.NoError(t, earlier.Save())
+
+	ag := &mockAgent{}
+	providers := defaultProviders(&mockWorktree{createPath: t.TempDir()}, ag, &mockVCS{})
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), budgetConfig(config.BudgetConfig{PerDayUSD: 4}), providers, planPath, rs, testLogger())
+
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "per_day_usd", budgetErr.Limit)
+	assert.Equal(t, 4.5, budgetErr.Spent)
+	assert.False(t, ag.called)
+}
+
+func TestRun_BudgetMetersEachFallbackCall(t *testing.T) {
+	chdirTemp(t)
+	primary := resultAgent{&mockAgent{err: errors.New("rate limit exceeded")}, usageResult}
+	backup := resultAgent{&mockAgent{}, usageResult}
+	pool := NewAgentPool([]provider.Agent{primary, backup}, []string{"claude", "codex"})
+	providers := Providers{VCS: &mockVCS{pr: &provider.PR{URL: "url", Number: 1}}, Agent: NewFallbackAgent(pool, 0, testLogger()), Worktree: &mockWorktree{createPath: t.TempDir()}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+	usage := rs.Steps[4].Usage
+	assert.Equal(t, int64(2), usage.Calls, "the failed call and its fallback")
+	assert.InDelta(t, 0.84, usage.CostUSD, 1e-9)
+}
+
+func TestRun_BudgetStopsFallback(t *testing.T) {
+	chdirTemp(t)
+	primary := &mockAgent{err: errors.New("rate limit exceeded")}
+	backup := &mockAgent{}
+	pool := NewAgentPool([]provider.Agent{primary, backup}, []string{"claude", "codex"})
+	providers := Providers{VCS: &mockVCS{}, Agent: NewFallbackAgent(pool, 0, testLogger()), Worktree: &mockWorktree{createPath: t.TempDir()}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), budgetConfig(config.BudgetConfig{MaxAgentInvocations: 1}), providers, planPath, rs, testLogger())
+
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "max_agent_invocations", budgetErr.Limit)
+	assert.True(t, primary.called)
+	assert.False(t, backup.called, "the fallback is a second call")
+}
+
+func TestBudgetPerDay_SeesRunningSteps(t *testing.T) {
+	chdirTemp(t)
+	running := state.New("20260217-110000-running", "plans/running.md")
+	running.Steps[4].BeginAttempt(time.Now())
+	require.NoError(t, running.Save())
+
+	// The other run is mid-step: its calls so far are saved, not just finished steps'.
+	ctx := withMeter(context.Background(), &agentMeter{rs: running, step: &running.Steps[4]})
+	require.NoError(t, startAgent(ctx, nil))
+	finishAgent(ctx, resultAgent{&mockAgent{}, provider.AgentResult{Usage: provider.Usage{CostUSD: 4.5}}}, "")
+
+	rs := newRunState("plans/auth.md")
+	m := &agentMeter{rs: rs, step: &rs.Steps[4], budget: config.BudgetConfig{PerDayUSD: 4}}
+	exceeded := m.checkBudget()
+	require.NotNil(t, exceeded)
+	assert.Equal(t, "per_day_usd", exceeded.Limit)
+	assert.Equal(t, 4.5, exceeded.Spent)
+}
+
+func TestStartAgent_UnlimitedOutsideStep(t *testing.T) {
+	assert.NoError(t, startAgent(context.Background(), nil))
+}
+
+func TestBudgetExceededError_Message(t *testing.T) {
+	tests := []struct {
+		err  *BudgetExceededError
+		want string
+	}{
+		{&BudgetExceededError{Limit: "per_run_usd", Spent: 5.123, Max: 5}, "budget exceeded: run spent $5.12 (agent.budget.per_run_usd: $5.00)"},
+		{&BudgetExceededError{Limit: "per_day_usd", Spent: 20.4, Max: 20}, "budget exceeded: today's runs spent $20.40 (agent.budget.per_day_usd: $20.00)"},
+		{&BudgetExceededError{Limit: "max_agent_invocations", Spent: 10, Max: 10}, "budget exceeded: run made 10 agent calls (agent.budget.max_agent_invocations: 10)"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.err.Error())
+	}
+}
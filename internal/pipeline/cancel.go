@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// ErrRunNotActive is returned by CancelRun for runs that already finished.
+var ErrRunNotActive = errors.New("run is not active")
+
+// cancelPollInterval is how often a running pipeline checks for a cancel marker.
+var cancelPollInterval = time.Second
+
+// trackRun records this process's PID in rs and returns a context that
+//   - records the agent PID in rs whenever an agent process starts or exits,
+//   - sends recorded events to the run's journal, and
+//   - is cancelled once forge cancel writes the run's cancel marker.
+//
+// It also logs a warning if lock reports that another process took the run
+// over. The returned func stops tracking, clears the PIDs and the marker, and saves.
+func trackRun(ctx context.Context, rs *state.RunState, lock *state.RunLock, logger *slog.Logger) (context.Context, func()) {
+	rs.PID = os.Getpid()
+	_ = rs.Save()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	ticker := time.NewTicker(cancelPollInterval)
+	go func() {
+		defer ticker.Stop()
+		lost := lock.Lost()
+		for {
+			select {
+			case <-done:
+				return
+			case <-lost:
+				logger.Warn("run lock was taken over by another process; its heartbeat has stopped", "run", rs.ID)
+				lost = nil
+			case <-ticker.C:
+				if state.CancelRequested(rs.ID) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	ctx = withJournal(ctx, rs.ID)
+	ctx = provider.WithRunInfo(ctx, provider.RunInfo{RunID: rs.ID})
+	ctx = provider.WithProcessHook(ctx, func(pid int) {
+		rs.AgentPID = pid
+		_ = rs.Save()
+	})
+
+	return ctx, func() {
+		close(done)
+		cancel()
+		rs.PID, rs.AgentPID = 0, 0
+		state.ClearCancel(rs.ID)
+		_ = rs.Save()
+	}
+}
+
+// runCancelled reports whether a run stopped because it was cancelled,
+// either through its context (signal) or a forge cancel marker.
+func runCancelled(ctx context.Context, rs *state.RunState) bool {
+	return ctx.Err() != nil || state.CancelRequested(rs.ID)
+}
+
+// CancelRun stops an active run: it kills the agent's process group, signals
+// the forge process executing the run so its context is cancelled, and marks
+// the run cancelled. A forge process shared with other active runs (a batch)
+// is not signalled; it picks up the run's cancel marker instead.
+//
+// Processes are only signalled while the run's lock shows its forge process
+// alive on this host: after a crash the recorded PIDs may belong to unrelated
+// processes by now. A live owner on another host gets the marker only.
+func CancelRun(id string, logger *slog.Logger) (*state.RunState, error) {
+	rs, err := state.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if rs.Status != state.RunActive {
+		return nil, fmt.Errorf("%w: %q has status %s", ErrRunNotActive, id, rs.Status)
+	}
+
+	owner := rs.LockOwner()
+	host, _ := os.Hostname()
+	if owner == nil || owner.Host == host && owner.PID != rs.PID {
+		logger.Info("no live forge process owns the run, marking it cancelled", "run", id)
+		state.ClearCancel(id)
+		rs.Status = state.RunCancelled
+		rs.PID, rs.AgentPID = 0, 0
+		if err := rs.Save(); err != nil {
+			return nil, err
+		}
+		return rs, nil
+	}
+
+	if err := state.RequestCancel(id); err != nil {
+		return nil, err
+	}
+	if owner.Host != host {
+		logger.Info("run is executing on another host, leaving it the cancel marker", "run", id, "host", owner.Host)
+		rs.Status = state.RunCancelled
+		if err := rs.Save(); err != nil {
+			return nil, err
+		}
+		return rs, nil
+	}
+
+	if rs.AgentPID > 0 {
+		logger.Info("killing agent process group", "run", id, "pid", rs.AgentPID)
+		if err := syscall.Kill(-rs.AgentPID, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return nil, fmt.Errorf("killing agent process group %d: %w", rs.AgentPID, err)
+		}
+	}
+
+	running := rs.PID > 0
+	if running && rs.PID != os.Getpid() && !pidShared(rs) {
+		logger.Info("signalling forge process", "run", id, "pid", rs.PID)
+		if err := syscall.Kill(rs.PID, syscall.SIGTERM); errors.Is(err, syscall.ESRCH) {
+			running = false
+		} else if err != nil {
+			return nil, fmt.Errorf("signalling forge process %d: %w", rs.PID, err)
+		}
+	}
+	if !running {
+		// Nothing is executing the run (e.g. forge crashed); no one will consume the marker.
+		state.ClearCancel(id)
+		rs.PID = 0
+	}
+
+	rs.Status = state.RunCancelled
+	rs.AgentPID = 0
+	if err := rs.Save(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// pidShared reports whether another active run is executed by rs's forge process.
+func pidShared(rs *state.RunState) bool {
+	runs, err := state.List()
+	if err != nil {
+		return false
+	}
+	for _, other := range runs {
+		if other.ID != rs.ID && other.Status == state.RunActive && other.PID == rs.PID {
+			return true
+		}
+	}
+	return false
+}
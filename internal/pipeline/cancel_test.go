@@ -0,0 +1,226 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// blockingAgent reports a fake agent PID and blocks until its context is cancelled.
+type blockingAgent struct {
+	started chan struct{}
+}
+
+func (b *blockingAgent) PromptSuffix() string { return "" }
+
+func (b *blockingAgent) Run(ctx context.Context, _, _ string) (string, error) {
+	provider.ProcessStarted(ctx, 999999)
+	close(b.started)
+	<-ctx.Done()
+	provider.ProcessStarted(ctx, 0)
+	return "", ctx.Err()
+}
+
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	origDir, _ := os.Getwd()
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { _ = os.Chdir(origDir) })
+}
+
+func TestRun_RecordsPIDs(t *testing.T) {
+	chdirTemp(t)
+	ag := &blockingAgent{started: make(chan struct{})}
+	providers := Providers{VCS: &mockVCS{}, Agent: ag, Worktree: &mockWorktree{createPath: "/tmp/wt"}}
+
+	planPath := writePlan(t, "plan")
+	rs := newRunState(planPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- Run(ctx, testConfig(), providers, planPath, rs, testLogger()) }()
+
+	<-ag.started
+	onDisk, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), onDisk.PID)
+	assert.Equal(t, 999999, onDisk.AgentPID)
+
+	cancel()
+	require.Error(t, <-errc)
+
+	onDisk, err = state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCancelled, onDisk.Status, "a cancelled context is not a failure")
+	assert.Zero(t, onDisk.PID)
+	assert.Zero(t, onDisk.AgentPID)
+}
+
+func TestCancelRun_StopsRunViaMarker(t *testing.T) {
+	chdirTemp(t)
+	origInterval := cancelPollInterval
+	cancelPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { cancelPollInterval = origInterval })
+
+	ag := &blockingAgent{started: make(chan struct{})}
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	providers := Providers{VCS: &mockVCS{}, Agent: ag, Worktree: wt}
+
+	planPath := writePlan(t, "plan")
+	rs := newRunState(planPath)
+
+	errc := make(chan error, 1)
+	go func() { errc <- Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()) }()
+	<-ag.started
+
+	// The run executes in this process, so CancelRun must not signal it; the
+	// runner notices the cancel marker instead. AgentPID is cleared first so
+	// the fake PID is never signalled.
+	onDisk, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	onDisk.AgentPID = 0
+	require.NoError(t, onDisk.Save())
+
+	cancelled, err := CancelRun(rs.ID, testLogger())
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCancelled, cancelled.Status)
+
+	select {
+	case err := <-errc:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not stop after cancel")
+	}
+
+	onDisk, err = state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCancelled, onDisk.Status)
+	assert.Equal(t, state.StepFailed, onDisk.Steps[4].Status, "interrupted step is resumable")
+	assert.False(t, state.CancelRequested(rs.ID), "marker is cleared once the run stops")
+	assert.False(t, wt.RemoveCalled(), "worktree is preserved for resume")
+}
+
+func TestCancelRun_NotActive(t *testing.T) {
+	chdirTemp(t)
+	rs := state.New("run-done", "plan.md")
+	rs.Status = state.RunCompleted
+	require.NoError(t, rs.Save())
+
+	_, err := CancelRun("run-done", testLogger())
+	require.ErrorIs(t, err, ErrRunNotActive)
+}
+
+// startAgentGroup starts a stand-in agent in its own process group and
+// returns it with a channel that receives its exit.
+func startAgentGroup(t *testing.T) (*exec.Cmd, chan error) {
+	t.Helper()
+	agent := exec.Command("sh", "-c", "sleep 60 & wait")
+	agent.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, agent.Start())
+	waited := make(chan error, 1)
+	go func() { waited <- agent.Wait() }()
+	t.Cleanup(func() { _ = syscall.Kill(-agent.Process.Pid, syscall.SIGKILL) })
+	return agent, waited
+}
+
+func TestCancelRun_KillsAgentProcessGroup(t *testing.T) {
+	chdirTemp(t)
+	agent, waited := startAgentGroup(t)
+
+	// This process stands in for the forge process executing the run.
+	lock, err := state.AcquireLock("run-live")
+	require.NoError(t, err)
+	defer lock.Release()
+	rs := state.New("run-live", "plan.md")
+	rs.PID = os.Getpid()
+	rs.AgentPID = agent.Process.Pid
+	require.NoError(t, rs.Save())
+
+	cancelled, err := CancelRun("run-live", testLogger())
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCancelled, cancelled.Status)
+	assert.Zero(t, cancelled.AgentPID)
+	assert.True(t, state.CancelRequested("run-live"), "the runner consumes the marker")
+
+	select {
+	case err := <-waited:
+		require.Error(t, err, "agent should have been killed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent process group was not killed")
+	}
+}
+
+func TestCancelRun_NoLiveOwnerSignalsNothing(t *testing.T) {
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		lock *state.LockInfo // nil: no lock file
+	}{
+		{"absent lock", nil},
+		{"stale lock", &state.LockInfo{Host: host, Heartbeat: time.Now().Add(-time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			// After a crash the recorded PIDs may have been reused by unrelated processes.
+			agent, waited := startAgentGroup(t)
+			rs := state.New("run-crashed", "plan.md")
+			rs.PID = agent.Process.Pid
+			rs.AgentPID = agent.Process.Pid
+			require.NoError(t, rs.Save())
+			if tt.lock != nil {
+				tt.lock.PID = rs.PID
+				data, err := yaml.Marshal(tt.lock)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(".forge", "runs", "run-crashed.lock"), data, 0o644))
+			}
+
+			cancelled, err := CancelRun("run-crashed", testLogger())
+			require.NoError(t, err)
+			assert.Equal(t, state.RunCancelled, cancelled.Status)
+			assert.Zero(t, cancelled.PID)
+			assert.Zero(t, cancelled.AgentPID)
+			assert.False(t, state.CancelRequested("run-crashed"), "no runner left to consume the marker")
+
+			select {
+			case <-waited:
+				t.Fatal("an unowned process was signalled")
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestPIDShared(t *testing.T) {
+	chdirTemp(t)
+
+	a := state.New("run-a", "a.md")
+	a.PID = 4242
+	require.NoError(t, a.Save())
+
+	b := state.New("run-b", "b.md")
+	b.PID = 4242
+	require.NoError(t, b.Save())
+
+	c := state.New("run-c", "c.md")
+	c.PID = 4343
+	require.NoError(t, c.Save())
+
+	assert.True(t, pidShared(a))
+	assert.False(t, pidShared(c))
+
+	b.Status = state.RunCompleted
+	require.NoError(t, b.Save())
+	assert.False(t, pidShared(a), "finished runs do not share the process")
+}
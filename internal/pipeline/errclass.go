@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errorClass is what a failed provider call says about trying again. The
+// retry policy reruns steps on transient and rate-limited errors; the agent
+// pool cools rate-limited agents down and falls back to another agent on
+// anything but a permanent error.
+type errorClass int
+
+const (
+	errPermanent   errorClass = iota // the same call would fail again
+	errTransient                     // network blip, 5xx: the same call may succeed later
+	errRateLimited                   // provider throttling, quota or overload
+	errAgentLimit                    // this agent's credentials, permissions, limits or timeout
+)
+
+// httpStatusPattern finds an HTTP error status where gh, an API or an agent
+// CLI reports one ("HTTP 503", "HTTP/2 429", "status 502", "status code: 504",
+// "API Error: 529"), so line numbers, PR numbers or test output don't match.
+// Only 4xx and 5xx count, which also keeps "exit status 128" out.
+var httpStatusPattern = regexp.MustCompile(`(?i)\b(?:http(?:/[0-9.]+)?|status(?:[ _]code)?|api error)\s*[:=]?\s*([45][0-9]{2})\b`)
+
+// httpStatus returns the HTTP error status reported in msg, or 0 if none is.
+func httpStatus(msg string) int {
+	m := httpStatusPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+// statusClasses classify the HTTP statuses worth acting on. 529 is
+// Anthropic's "overloaded".
+var statusClasses = map[int]errorClass{
+	401: errAgentLimit, 403: errAgentLimit,
+	429: errRateLimited, 529: errRateLimited,
+	500: errTransient, 502: errTransient, 503: errTransient, 504: errTransient,
+}
+
+// Message patterns for errors that carry no status code, checked in this
+// order against the lowercased message.
+var (
+	rateLimitPatterns = []string{
+		"rate limit", "rate_limit", "too many requests", "quota", "usage limit", "overloaded",
+	}
+	transientPatterns = []string{
+		"connection reset", "connection refused", "broken pipe", "unexpected eof",
+		"i/o timeout", "tls handshake timeout", "no such host", "could not resolve host",
+		"temporary failure in name resolution", "network is unreachable",
+		"bad gateway", "service unavailable", "gateway timeout", "internal server error",
+	}
+	agentLimitPatterns = []string{
+		"unauthorized", "forbidden", "credentials", "invalid api key", "timed out", "limit exceeded",
+	}
+)
+
+// classifyError classifies err by its HTTP status, if it reports one, and
+// otherwise by its message.
+func classifyError(err error) errorClass {
+	var budgetErr *BudgetExceededError
+	if err == nil || errors.As(err, &budgetErr) || errors.Is(err, context.Canceled) {
+		return errPermanent
+	}
+	msg := err.Error()
+	if class, ok := statusClasses[httpStatus(msg)]; ok {
+		return class
+	}
+	lower := strings.ToLower(msg)
+	for _, group := range []struct {
+		patterns []string
+		class    errorClass
+	}{
+		{rateLimitPatterns, errRateLimited},
+		{transientPatterns, errTransient},
+		{agentLimitPatterns, errAgentLimit},
+	} {
+		for _, p := range group.patterns {
+			if strings.Contains(lower, p) {
+				return group.class
+			}
+		}
+	}
+	return errPermanent
+}
@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want int
+	}{
+		{"gh pr create: HTTP 502 Bad Gateway", 502},
+		{"POST https://api.github.com/graphql: HTTP/2 429", 429},
+		{"request failed with status code: 504", 504},
+		{"status=503", 503},
+		{`API Error: 529 {"type":"overloaded_error"}`, 529},
+		{"handler.go:503: undefined: foo", 0},
+		{"pull request #429 is not mergeable", 0},
+		{"exit status 1", 0},
+		{"git push: exit status 128: fatal: HTTP 503", 503},
+		{"commit 5029abc", 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, httpStatus(tt.msg), tt.msg)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want errorClass
+	}{
+		{nil, errPermanent},
+		{errors.New("HTTP 429 Too Many Requests"), errRateLimited},
+		{errors.New("API Error: 529 overloaded"), errRateLimited},
+		{errors.New("Claude usage limit reached"), errRateLimited},
+		{errors.New("rate limit exceeded"), errRateLimited},
+		{errors.New("HTTP 503 Service Unavailable"), errTransient},
+		{errors.New("read tcp: connection reset by peer"), errTransient},
+		{errors.New("HTTP 403 Forbidden"), errAgentLimit},
+		{errors.New("invalid credentials"), errAgentLimit},
+		{errors.New("agent timed out after 45m"), errAgentLimit},
+		{errors.New("token limit exceeded"), errAgentLimit},
+		{errors.New("agent failed: exit status 1: --- FAIL: parser_test.go:429: got 403"), errPermanent},
+		{errors.New("gh api: HTTP 404: Not Found"), errPermanent},
+		{fmt.Errorf("agent: %w", context.Canceled), errPermanent},
+		{fmt.Errorf("step: %w", &BudgetExceededError{Limit: "per_run_usd"}), errPermanent},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, classifyError(tt.err), "%v", tt.err)
+	}
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// journal identifies where events recorded under a context go: the run being
+// executed and, inside runStep, the current step.
+type journal struct {
+	runID string
+	step  string
+}
+
+type journalKey struct{}
+
+// withJournal makes record append to the journal of run id.
+func withJournal(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, journalKey{}, journal{runID: runID})
+}
+
+// withJournalStep tags events recorded under ctx with step.
+func withJournalStep(ctx context.Context, step string) context.Context {
+	j, ok := ctx.Value(journalKey{}).(journal)
+	if !ok {
+		return ctx
+	}
+	j.step = step
+	return context.WithValue(ctx, journalKey{}, j)
+}
+
+// record appends an event to the journal of the run executing under ctx.
+// Best-effort: the journal is an audit trail and never fails a run.
+// A no-op outside a tracked run.
+func record(ctx context.Context, typ state.EventType, data map[string]any) {
+	j, ok := ctx.Value(journalKey{}).(journal)
+	if !ok {
+		return
+	}
+	_ = state.AppendEvent(state.Event{RunID: j.runID, Type: typ, Step: j.step, Data: data})
+}
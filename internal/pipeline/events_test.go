@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// Most tests share .forge/runs in the package dir; don't leave journals behind.
+	journals, _ := filepath.Glob(filepath.Join(".forge", "runs", "*.events.jsonl"))
+	for _, path := range journals {
+		_ = os.Remove(path)
+	}
+	os.Exit(code)
+}
+
+// eventTypes returns "step/type" for each event, in order.
+func eventTypes(events []state.Event) []string {
+	out := make([]string, len(events))
+	for i, ev := range events {
+		out[i] = ev.Step + "/" + string(ev.Type)
+	}
+	return out
+}
+
+func TestRun_JournalsEvents(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/7", Number: 7}}
+	providers := defaultProviders(wt, &mockAgent{}, vc)
+	providers.Notifier = &mockNotifier{}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	types := eventTypes(events)
+
+	assert.Equal(t, "read plan/step_started", types[0])
+	assert.Contains(t, types, "run agent/agent_invoked")
+	assert.Contains(t, types, "create pr/pr_created")
+	assert.Contains(t, types, "notify/notification_sent")
+	assert.Equal(t, "notify/step_finished", types[len(types)-1])
+
+	for _, ev := range events {
+		assert.Equal(t, rs.ID, ev.RunID)
+		assert.False(t, ev.Time.IsZero())
+		if ev.Type == state.EventPRCreated {
+			assert.Equal(t, "https://github.com/owner/repo/pull/7", ev.Data["url"])
+		}
+		if ev.Type == state.EventStepFinished {
+			assert.Equal(t, "completed", ev.Data["status"])
+		}
+	}
+}
+
+// runInfoAgent records the RunInfo each call sees.
+type runInfoAgent struct {
+	mockAgent
+	infos []provider.RunInfo
+}
+
+func (a *runInfoAgent) Run(ctx context.Context, dir, prompt string) (string, error) {
+	a.infos = append(a.infos, provider.RunInfoFromContext(ctx))
+	return a.mockAgent.Run(ctx, dir, prompt)
+}
+
+func TestRun_PassesRunInfoToAgent(t *testing.T) {
+	chdirTemp(t)
+	ag := &runInfoAgent{}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	providers := Providers{VCS: vc, Agent: ag, Worktree: &mockWorktree{createPath: t.TempDir()}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+	assert.Equal(t, []provider.RunInfo{{RunID: rs.ID, Step: "run agent"}}, ag.infos)
+}
+
+func TestRun_JournalsHookFailure(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	providers := defaultProviders(wt, &mockAgent{}, &mockVCS{})
+	cfg := testConfig()
+	cfg.Hooks.PreCommit = "echo lint broke; exit 1"
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.Error(t, Run(context.Background(), cfg, providers, planPath, rs, testLogger()))
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	types := eventTypes(events)
+	assert.Contains(t, types, "commit and push/hook_failed")
+
+	last := events[len(events)-1]
+	assert.Equal(t, state.EventStepFinished, last.Type)
+	assert.Equal(t, "failed", last.Data["status"])
+	assert.Contains(t, last.Data["error"], "lint broke")
+}
+
+func TestRecord_NoopOutsideRun(t *testing.T) {
+	chdirTemp(t)
+	record(context.Background(), state.EventAgentInvoked, nil)
+
+	_, err := os.Stat(filepath.Join(".forge", "runs"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -1,24 +1,42 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os/exec"
 	"strings"
 
 	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
 )
 
+// preCommitLabel names the pre-commit hook in logs and agent fix prompts.
+const preCommitLabel = "pre-commit hook"
+
 // runHook executes a shell command in the given directory.
 // Used for lifecycle hooks like pre-commit formatting.
 func runHook(ctx context.Context, command, dir string, logger *slog.Logger) error {
-	logger.Info("running pre-commit hook", "cmd", command)
+	return runCommand(ctx, preCommitLabel, command, dir, nil, logger)
+}
+
+// runCommand executes a shell command in dir. Combined output is copied to out
+// when non-nil and included in the returned error on failure.
+func runCommand(ctx context.Context, label, command, dir string, out io.Writer, logger *slog.Logger) error {
+	logger.Info("running "+label, "cmd", command)
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	var buf bytes.Buffer
+	if out != nil {
+		cmd.Stdout = io.MultiWriter(&buf, out)
+	} else {
+		cmd.Stdout = &buf
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(buf.String()))
 	}
 	return nil
 }
@@ -27,35 +45,53 @@ func runHook(ctx context.Context, command, dir string, logger *slog.Logger) erro
 // output to the agent to fix. Retries up to maxRetries times.
 // If agent is nil or maxRetries is 0, fails fast on first hook failure.
 func runHookWithRetry(ctx context.Context, command, dir string, agent provider.Agent, maxRetries int, logger *slog.Logger) error {
-	err := runHook(ctx, command, dir, logger)
+	return runCommandWithRetry(ctx, preCommitLabel, command, dir, nil, agent, maxRetries, logger)
+}
+
+// runCommandWithRetry is runHookWithRetry for any labeled command (e.g. custom steps).
+func runCommandWithRetry(ctx context.Context, label, command, dir string, out io.Writer, agent provider.Agent, maxRetries int, logger *slog.Logger) error {
+	err := runCommand(ctx, label, command, dir, out, logger)
 	if err == nil {
 		return nil
 	}
+	record(ctx, state.EventHookFailed, map[string]any{"hook": label, "command": command, "error": err.Error(), "attempt": 0})
 
 	if agent == nil || maxRetries <= 0 {
 		return err
 	}
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		logger.Warn("pre-commit hook failed, asking agent to fix", "attempt", attempt, "max", maxRetries, "error", err)
+		logger.Warn(label+" failed, asking agent to fix", "attempt", attempt, "max", maxRetries, "error", err)
 
-		prompt := buildHookFixPrompt(command, err.Error())
-		if _, agentErr := agent.Run(ctx, dir, prompt); agentErr != nil {
+		prompt := buildFixPrompt(label, command, err.Error())
+		if err := startAgent(ctx, map[string]any{"purpose": "fix " + label, "attempt": attempt}); err != nil {
+			return fmt.Errorf("agent fix attempt %d: %w", attempt, err)
+		}
+		output, agentErr := agent.Run(ctx, dir, prompt)
+		finishAgent(ctx, agent, output)
+		if agentErr != nil {
 			return fmt.Errorf("agent fix attempt %d: %w", attempt, agentErr)
 		}
 
-		err = runHook(ctx, command, dir, logger)
+		err = runCommand(ctx, label, command, dir, out, logger)
 		if err == nil {
-			logger.Info("pre-commit hook passed after agent fix", "attempt", attempt)
+			logger.Info(label+" passed after agent fix", "attempt", attempt)
 			return nil
 		}
+		record(ctx, state.EventHookFailed, map[string]any{"hook": label, "command": command, "error": err.Error(), "attempt": attempt})
 	}
 
-	return fmt.Errorf("pre-commit hook failed after %d retries: %w", maxRetries, err)
+	return fmt.Errorf("%s failed after %d retries: %w", label, maxRetries, err)
 }
 
 // buildHookFixPrompt constructs a prompt telling the agent to fix hook failures.
 func buildHookFixPrompt(command, hookOutput string) string {
+	return buildFixPrompt(preCommitLabel, command, hookOutput)
+}
+
+// buildFixPrompt constructs a prompt telling the agent to fix a failing hook;
+// label names it in the first line (custom steps are hooks too).
+func buildFixPrompt(label, command, hookOutput string) string {
 	// Truncate to last 4000 chars — the tail contains the actual errors,
 	// the head is usually passing tests and noise.
 	const maxOutput = 4000
@@ -64,7 +100,7 @@ func buildHookFixPrompt(command, hookOutput string) string {
 		truncated = "...[truncated]\n" + truncated[len(truncated)-maxOutput:]
 	}
 
-	return `The pre-commit hook failed. Fix ALL reported errors so the hook passes.
+	return `The ` + label + ` failed. Fix ALL reported errors so the hook passes.
 
 Hook command: ` + command + `
 
@@ -0,0 +1,215 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/graph"
+	"github.com/shahar-caura/forge/internal/plan"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+var nodeRefRe = regexp.MustCompile(`#\d+`)
+
+// planNode is a plan file in a multi-plan run.
+type planNode struct {
+	id        string
+	path      string
+	title     string
+	dependsOn []string
+}
+
+// planID returns the plan's frontmatter id, or the file name without extension.
+func planID(path string, p *plan.Plan) string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// loadPlanNodes parses each plan's frontmatter and validates ids and depends_on references.
+// Ids must also differ once slugged, since the slug names each plan's run.
+func loadPlanNodes(paths []string) ([]planNode, error) {
+	nodes := make([]planNode, 0, len(paths))
+	byID := make(map[string]string, len(paths))
+	bySlug := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading plan: %w", err)
+		}
+		p, err := plan.Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("plan %s: %w", path, err)
+		}
+		id := planID(path, p)
+		if prev, ok := byID[id]; ok {
+			return nil, fmt.Errorf("duplicate plan id %q in %s and %s", id, prev, path)
+		}
+		byID[id] = path
+		slug := SlugFromTitle(id)
+		if prev, ok := bySlug[slug]; ok {
+			return nil, fmt.Errorf("plan ids %q and %q both map to run id slug %q", prev, id, slug)
+		}
+		bySlug[slug] = id
+		title := p.Title
+		if title == "" {
+			title = TitleFromFilename(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		}
+		nodes = append(nodes, planNode{id: id, path: path, title: title, dependsOn: p.DependsOn})
+	}
+
+	for _, n := range nodes {
+		for _, dep := range n.dependsOn {
+			if _, ok := byID[dep]; !ok {
+				return nil, fmt.Errorf("plan %q depends on unknown plan %q", n.id, dep)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// RunPlans executes plan files in dependency order (depends_on frontmatter).
+// Plans in the same level run in parallel. A failed plan blocks its transitive
+// dependents; independent plans keep running.
+func RunPlans(ctx context.Context, cfg *config.Config, providers Providers,
+	paths []string, dryRun bool, logger *slog.Logger,
+) error {
+	nodes, err := loadPlanNodes(paths)
+	if err != nil {
+		return err
+	}
+
+	// Map plan ids onto graph node numbers so Topsort and findBlocked apply unchanged.
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	numByID := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		numByID[n.id] = i + 1
+	}
+	nodeNums := make([]int, len(nodes))
+	nodeSet := make(map[int]bool, len(nodes))
+	depsMap := make(map[int][]int, len(nodes))
+	for i, n := range nodes {
+		num := i + 1
+		nodeNums[i] = num
+		nodeSet[num] = true
+		for _, dep := range n.dependsOn {
+			depsMap[num] = append(depsMap[num], numByID[dep])
+		}
+	}
+	node := func(num int) planNode { return nodes[num-1] }
+
+	levels, err := graph.Topsort(nodeNums, depsMap)
+	if err != nil {
+		// Cycle errors name nodes as #N; report plan ids instead.
+		msg := nodeRefRe.ReplaceAllStringFunc(err.Error(), func(ref string) string {
+			num, _ := strconv.Atoi(ref[1:])
+			if num < 1 || num > len(nodes) {
+				return ref
+			}
+			return node(num).id
+		})
+		return fmt.Errorf("topological sort: %s", msg)
+	}
+
+	if dryRun {
+		for i, level := range levels {
+			for _, num := range level {
+				logger.Info("plan", "level", i+1, "id", node(num).id, "path", node(num).path, "title", node(num).title)
+			}
+		}
+		return nil
+	}
+
+	var (
+		failed    []string
+		completed int
+		started   int
+	)
+	blocked := make(map[int]bool)
+	total := len(nodes)
+	pool := providers.AgentPool
+	for li, level := range levels {
+		var runnable []int
+		for _, num := range level {
+			if blocked[num] {
+				logger.Warn("skipping blocked plan", "plan", node(num).id)
+				continue
+			}
+			runnable = append(runnable, num)
+		}
+
+		logger.Info("running plan level", "level", li+1, "plans", len(runnable))
+		errs := make([]error, len(runnable))
+		var wg sync.WaitGroup
+		for i, num := range runnable {
+			wg.Add(1)
+			go func(i, num int) {
+				defer wg.Done()
+				p := providers
+				globalIdx := started + i
+				n := node(num)
+				if pool != nil {
+					p.Agent = NewFallbackAgent(pool, globalIdx, logger)
+					logger.Info("running plan", "level", li+1, "plan", n.id, "title", n.title, "agent", pool.AssignName(globalIdx))
+				} else {
+					logger.Info("running plan", "level", li+1, "plan", n.id, "title", n.title)
+				}
+				errs[i] = runSinglePlan(ctx, cfg, p, n, logger)
+			}(i, num)
+		}
+		wg.Wait()
+		started += len(runnable)
+
+		for i, num := range runnable {
+			n := node(num)
+			if errs[i] == nil {
+				completed++
+				logger.Info("plan completed", "plan", n.id, "progress", fmt.Sprintf("%d/%d", completed, total))
+				continue
+			}
+			failed = append(failed, n.id)
+			var blockedIDs []string
+			for _, b := range findBlocked(num, depsMap, nodeSet) {
+				blocked[b] = true
+				blockedIDs = append(blockedIDs, node(b).id)
+			}
+			logger.Error("plan failed", "plan", n.id, "error", errs[i])
+			if len(blockedIDs) > 0 {
+				logger.Warn("blocked downstream plans", "blocked", blockedIDs)
+			}
+			if providers.Notifier != nil {
+				msg := fmt.Sprintf("forge: plan %s failed: %s\nBlocked: %v", n.id, errs[i], blockedIDs)
+				_ = providers.Notifier.Notify(ctx, msg)
+			}
+		}
+	}
+
+	logger.Info("plans complete", "completed", completed, "failed", len(failed), "blocked", len(blocked), "total", total)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d plans failed (%s), %d blocked", len(failed), total, strings.Join(failed, ", "), len(blocked))
+	}
+	return nil
+}
+
+// runSinglePlan executes one plan file through the forge pipeline.
+func runSinglePlan(ctx context.Context, cfg *config.Config, providers Providers, n planNode, logger *slog.Logger) error {
+	runID := time.Now().Format("20060102-150405") + "-" + SlugFromTitle(n.id)
+	rs := state.New(runID, n.path)
+	if err := rs.Save(); err != nil {
+		return fmt.Errorf("saving initial run state: %w", err)
+	}
+
+	logger.Info("starting run", "id", runID, "plan", n.path)
+	return Run(ctx, cfg, providers, n.path, rs, logger)
+}
@@ -0,0 +1,188 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planAgent records plan prompts in call order and fails prompts containing "FAIL".
+type planAgent struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (a *planAgent) PromptSuffix() string { return "" }
+
+func (a *planAgent) Run(_ context.Context, _, prompt string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prompts = append(a.prompts, prompt)
+	if strings.Contains(prompt, "FAIL") {
+		return "", errors.New("agent exploded")
+	}
+	return "", nil
+}
+
+// ran reports whether a prompt containing marker was sent, and its call position.
+func (a *planAgent) ran(marker string) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, p := range a.prompts {
+		if strings.Contains(p, marker) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// writePlans chdirs to a temp dir and writes name → content plan files, returning their paths.
+func writePlans(t *testing.T, plans map[string]string) []string {
+	t.Helper()
+	origDir, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() {
+		if err := os.Chdir(origDir); err != nil {
+			t.Errorf("restoring working dir: %v", err)
+		}
+	})
+
+	require.NoError(t, os.MkdirAll("plans", 0o755))
+	paths := make([]string, 0, len(plans))
+	for name, content := range plans {
+		path := filepath.Join("plans", name+".md")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func planProviders(t *testing.T, ag provider.Agent) Providers {
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	return Providers{VCS: vc, Agent: ag, Worktree: wt}
+}
+
+func TestRunPlans_DependencyOrder(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"db":  "---\nid: db\n---\nmarker-db",
+		"api": "---\nid: api\ndepends_on: [db]\n---\nmarker-api",
+		"ui":  "---\ndepends_on: [api]\n---\nmarker-ui",
+	})
+	ag := &planAgent{}
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, ag), paths, false, batchLogger())
+
+	require.NoError(t, err)
+	dbIdx, ok := ag.ran("marker-db")
+	require.True(t, ok)
+	apiIdx, ok := ag.ran("marker-api")
+	require.True(t, ok)
+	uiIdx, ok := ag.ran("marker-ui")
+	require.True(t, ok, "id defaults to the file name")
+	assert.Less(t, dbIdx, apiIdx)
+	assert.Less(t, apiIdx, uiIdx)
+}
+
+func TestRunPlans_FailureBlocksDependents(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"a": "---\nid: a\n---\nmarker-a FAIL",
+		"b": "---\nid: b\ndepends_on: [a]\n---\nmarker-b",
+		"c": "---\nid: c\ndepends_on: [b]\n---\nmarker-c",
+		"d": "---\nid: d\n---\nmarker-d",
+		"e": "---\nid: e\ndepends_on: [d]\n---\nmarker-e",
+	})
+	ag := &planAgent{}
+	n := &mockNotifier{}
+	p := planProviders(t, ag)
+	p.Notifier = n
+
+	err := RunPlans(context.Background(), testConfig(), p, paths, false, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 5 plans failed (a), 2 blocked")
+	_, ok := ag.ran("marker-b")
+	assert.False(t, ok, "b depends on failed a")
+	_, ok = ag.ran("marker-c")
+	assert.False(t, ok, "c transitively depends on failed a")
+	_, ok = ag.ran("marker-e")
+	assert.True(t, ok, "independent chain keeps running")
+
+	var failMsg string
+	for _, m := range n.messages {
+		if strings.Contains(m, "plan a failed") {
+			failMsg = m
+		}
+	}
+	assert.Contains(t, failMsg, "Blocked: [b c]")
+}
+
+func TestRunPlans_DryRun(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"a": "---\nid: a\n---\nmarker-a",
+		"b": "---\nid: b\ndepends_on: [a]\n---\nmarker-b",
+	})
+	ag := &planAgent{}
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, ag), paths, true, batchLogger())
+
+	require.NoError(t, err)
+	assert.Empty(t, ag.prompts)
+}
+
+func TestRunPlans_UnknownDependency(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"a": "---\nid: a\ndepends_on: [missing]\n---\nbody",
+		"b": "body",
+	})
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, &planAgent{}), paths, false, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `plan "a" depends on unknown plan "missing"`)
+}
+
+func TestRunPlans_DuplicateID(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"a": "---\nid: same\n---\nbody",
+		"b": "---\nid: same\n---\nbody",
+	})
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, &planAgent{}), paths, false, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `duplicate plan id "same"`)
+}
+
+func TestRunPlans_DuplicateSlug(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"one": "---\nid: a_b\n---\nbody",
+		"two": "---\nid: a-b\n---\nbody",
+	})
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, &planAgent{}), paths, false, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `both map to run id slug "a-b"`)
+}
+
+func TestRunPlans_Cycle(t *testing.T) {
+	paths := writePlans(t, map[string]string{
+		"a": "---\nid: a\ndepends_on: [b]\n---\nbody",
+		"b": "---\nid: b\ndepends_on: [a]\n---\nbody",
+	})
+
+	err := RunPlans(context.Background(), testConfig(), planProviders(t, &planAgent{}), paths, false, batchLogger())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle: a \u2192 b \u2192 a")
+}
@@ -4,21 +4,195 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/shahar-caura/forge/internal/plan"
 	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
 )
 
-// AgentPool holds a list of agents for round-robin assignment and fallback.
+// Rate-limit cooldown: an agent that hits a rate limit is skipped for
+// baseCooldown, doubling with each consecutive hit up to maxCooldown, unless
+// the error says how long to wait.
+const (
+	baseCooldown = time.Minute
+	maxCooldown  = 30 * time.Minute
+)
+
+// AgentPool holds a list of agents for assignment and fallback, routing work
+// to the healthiest agent: agents cooling down after rate limits are skipped,
+// then the least busy, most successful and fastest agent wins, with ties
+// going round-robin. It also tracks how many batch runs each agent is serving
+// (see TryAcquire).
 type AgentPool struct {
 	agents []provider.Agent
 	names  []string
+
+	mu            sync.Mutex
+	busy          []int
+	next          int
+	maxConcurrent int                           // per agent; 0 = unlimited
+	health        map[string]*state.AgentHealth // by agent name
+	persist       bool                          // save health updates (see LoadHealth)
+	now           func() time.Time
 }
 
 // NewAgentPool creates a pool from agents and their display names.
 // agents and names must have the same length and at least one entry.
+// Health is tracked in memory until LoadHealth is called.
 func NewAgentPool(agents []provider.Agent, names []string) *AgentPool {
-	return &AgentPool{agents: agents, names: names}
+	return &AgentPool{
+		agents: agents,
+		names:  names,
+		busy:   make([]int, len(agents)),
+		health: make(map[string]*state.AgentHealth),
+		now:    time.Now,
+	}
+}
+
+// LoadHealth seeds routing with the agent health recorded by earlier forge
+// invocations (.forge/agent-health.yaml) and saves every update from now on,
+// so a batch started after a 429 doesn't try the throttled agent first.
+func (p *AgentPool) LoadHealth() error {
+	health, err := state.LoadAgentHealth()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.health = health
+	p.persist = true
+	return nil
+}
+
+// Health returns the named agent's current health record.
+func (p *AgentPool) Health(name string) state.AgentHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if h := p.health[name]; h != nil {
+		return *h
+	}
+	return state.AgentHealth{}
+}
+
+// healthOf returns agent idx's health. Callers hold p.mu.
+func (p *AgentPool) healthOf(idx int) state.AgentHealth {
+	if h := p.health[p.names[idx]]; h != nil {
+		return *h
+	}
+	return state.AgentHealth{}
+}
+
+// healthier reports whether agent a should be preferred over agent b by
+// health alone: not cooling down (or, if both are, recovering sooner), then a
+// higher success rate, then a lower average latency. Callers hold p.mu.
+func (p *AgentPool) healthier(a, b int, now time.Time) bool {
+	ha, hb := p.healthOf(a), p.healthOf(b)
+	ca, cb := ha.CoolingDown(now), hb.CoolingDown(now)
+	if ca != cb {
+		return !ca
+	}
+	if ca {
+		return ha.CooldownUntil.Before(hb.CooldownUntil)
+	}
+	if ra, rb := ha.SuccessRate(), hb.SuccessRate(); ra != rb {
+		return ra > rb
+	}
+	return ha.AvgLatency > 0 && (hb.AvgLatency == 0 || ha.AvgLatency < hb.AvgLatency)
+}
+
+// SetMaxConcurrent limits each agent to n runs handed out by TryAcquire (0 = unlimited).
+func (p *AgentPool) SetMaxConcurrent(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxConcurrent = n
+}
+
+// TryAcquire reserves a run slot on the agent with capacity that is not
+// cooling down, least busy, then healthiest, rotating the starting point so
+// ties spread round-robin. When every agent is cooling down, the one that
+// recovers first is picked (RunWithFallback waits out its cooldown). Returns
+// the agent index, or false when every agent is at its limit. Pair with
+// Release.
+func (p *AgentPool) TryAcquire() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	n := len(p.agents)
+	best := -1
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		if p.maxConcurrent > 0 && p.busy[idx] >= p.maxConcurrent {
+			continue
+		}
+		if best == -1 {
+			best = idx
+			continue
+		}
+		if ci, cb := p.healthOf(idx).CoolingDown(now), p.healthOf(best).CoolingDown(now); ci != cb {
+			if !ci {
+				best = idx
+			}
+			continue
+		}
+		if p.busy[idx] != p.busy[best] {
+			if p.busy[idx] < p.busy[best] {
+				best = idx
+			}
+			continue
+		}
+		if p.healthier(idx, best, now) {
+			best = idx
+		}
+	}
+	if best == -1 {
+		return 0, false
+	}
+	p.busy[best]++
+	p.next = best + 1
+	return best, true
+}
+
+// Release frees a run slot reserved by TryAcquire.
+func (p *AgentPool) Release(idx int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.busy[idx] > 0 {
+		p.busy[idx]--
+	}
+}
+
+// move transfers a run slot reserved by TryAcquire from agent from to agent
+// to, unless to is already at its limit.
+func (p *AgentPool) move(from, to int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if from == to {
+		return true
+	}
+	if p.maxConcurrent > 0 && p.busy[to] >= p.maxConcurrent {
+		return false
+	}
+	if p.busy[from] > 0 {
+		p.busy[from]--
+	}
+	p.busy[to]++
+	return true
+}
+
+// index returns the position of the agent named name.
+func (p *AgentPool) index(name string) (int, bool) {
+	for i, n := range p.names {
+		if n == name {
+			return i, true
+		}
+	}
+	return 0, false
 }
 
 // Assign returns the agent at index % pool size (round-robin).
@@ -41,46 +215,186 @@ func (p *AgentPool) Len() int {
 	return len(p.agents)
 }
 
-// retryableError returns true if the error looks like a rate-limit, quota, or
-// credential issue that a different agent might not hit.
+// retryableError returns true if the error looks like a rate limit, server
+// error, or credential, limit or timeout issue that a different agent might
+// not hit.
 func retryableError(err error) bool {
-	if err == nil {
-		return false
+	return classifyError(err) != errPermanent
+}
+
+// rateLimited returns true if the error looks like the agent's provider is
+// throttling it, so retrying the same agent soon would fail again.
+func rateLimited(err error) bool {
+	return classifyError(err) == errRateLimited
+}
+
+// retryAfterPattern matches wait hints like "retry after 120s",
+// "Retry-After: 30" or "try again in 5 minutes".
+var retryAfterPattern = regexp.MustCompile(`(?i)(?:retry[- ]after|try again in)[:\s]+(\d+)\s*(s|sec|secs|seconds?|m|min|mins|minutes?|h|hours?)?\b`)
+
+// cooldownFor returns how long to skip an agent after its hits-th
+// consecutive rate limit, preferring the wait the error asks for.
+func cooldownFor(hits int, err error) time.Duration {
+	if m := retryAfterPattern.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Second
+		switch {
+		case strings.HasPrefix(m[2], "m"):
+			unit = time.Minute
+		case strings.HasPrefix(m[2], "h"):
+			unit = time.Hour
+		}
+		if d := time.Duration(n) * unit; d > 0 {
+			return min(d, maxCooldown)
+		}
+	}
+	d := baseCooldown
+	for i := 1; i < hits && d < maxCooldown; i++ {
+		d *= 2
 	}
-	msg := strings.ToLower(err.Error())
-	patterns := []string{
-		"rate limit", "429", "quota", "exceeded",
-		"unauthorized", "403", "credentials", "timed out",
+	return min(d, maxCooldown)
+}
+
+// record updates agent idx's health with the outcome of one call.
+func (p *AgentPool) record(idx int, latency time.Duration, err error, logger *slog.Logger) {
+	now := p.now()
+	update := func(h *state.AgentHealth) {
+		switch {
+		case err == nil:
+			h.Successes++
+			h.RateLimits = 0
+			h.CooldownUntil = time.Time{}
+			if h.AvgLatency == 0 {
+				h.AvgLatency = latency
+			} else {
+				h.AvgLatency += (latency - h.AvgLatency) / 5
+			}
+		case rateLimited(err):
+			h.Failures++
+			h.RateLimits++
+			h.CooldownUntil = now.Add(cooldownFor(h.RateLimits, err))
+		default:
+			h.Failures++
+		}
 	}
-	for _, p := range patterns {
-		if strings.Contains(msg, p) {
-			return true
+
+	name := p.names[idx]
+	p.mu.Lock()
+	persist := p.persist
+	p.mu.Unlock()
+	if persist {
+		health, saveErr := state.UpdateAgentHealth(name, update)
+		if saveErr == nil {
+			p.mu.Lock()
+			p.health = health
+			p.mu.Unlock()
+			return
 		}
+		logger.Warn("saving agent health", "agent", name, "error", saveErr)
 	}
-	return false
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.health[name]
+	if h == nil {
+		h = &state.AgentHealth{}
+		p.health[name] = h
+	}
+	update(h)
+	h.UpdatedAt = now
 }
 
-// RunWithFallback tries the agent at startIdx, falling back to the next agent
-// on retryable errors. Returns the output, the name of the agent that
-// succeeded, and any final error.
-func (p *AgentPool) RunWithFallback(ctx context.Context, startIdx int, dir, prompt string, logger *slog.Logger) (string, string, error) {
+// fallbackOrder returns the agents to try for a call assigned to startIdx:
+// the assigned agent first unless it is cooling down, then the others by
+// health, ties in round-robin order from startIdx. Agents cooling down come
+// last, soonest to recover first.
+func (p *AgentPool) fallbackOrder(startIdx int) []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
 	n := len(p.agents)
-	for i := 0; i < n; i++ {
-		idx := (startIdx + i) % n
+	order := make([]int, n)
+	for i := range order {
+		order[i] = (startIdx + i) % n
+	}
+	sort.SliceStable(order[1:], func(i, j int) bool {
+		return p.healthier(order[1+i], order[1+j], now)
+	})
+	if p.healthOf(startIdx).CoolingDown(now) {
+		sort.SliceStable(order, func(i, j int) bool {
+			return p.healthier(order[i], order[j], now)
+		})
+	}
+	return order
+}
+
+// cooldownLeft returns how long agent idx is still cooling down.
+func (p *AgentPool) cooldownLeft(idx int) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.healthOf(idx)
+	if !h.CoolingDown(p.now()) {
+		return 0
+	}
+	return h.CooldownUntil.Sub(p.now())
+}
+
+// RunWithFallback tries the agent at startIdx, falling back to the others in
+// fallbackOrder on retryable errors, and records each call's outcome in the
+// agents' health. An agent cooling down is only tried once the others have
+// failed, after waiting out its cooldown. Returns the output, the name of the
+// agent that succeeded, and any final error.
+func (p *AgentPool) RunWithFallback(ctx context.Context, startIdx int, dir, prompt string, logger *slog.Logger) (string, string, error) {
+	return p.runWithFallback(ctx, startIdx, nil, dir, prompt, logger)
+}
+
+// runWithFallback is RunWithFallback with override, if non-nil, standing in
+// for the agent at startIdx under that agent's name and health.
+//
+// The caller starts and finishes the call as a whole (startAgent,
+// finishAgent); each fallback to another agent is started here, and each
+// failed attempt finished here, so every agent call is metered and budgeted.
+func (p *AgentPool) runWithFallback(ctx context.Context, startIdx int, override provider.Agent, dir, prompt string, logger *slog.Logger) (string, string, error) {
+	startIdx %= len(p.agents)
+	order := p.fallbackOrder(startIdx)
+	for i, idx := range order {
 		agent := p.agents[idx]
+		if idx == startIdx && override != nil {
+			agent = override
+		}
 		name := p.names[idx]
 
+		if i > 0 {
+			if err := startAgent(ctx, map[string]any{"purpose": "fallback", "agent": name}); err != nil {
+				return "", name, err
+			}
+		}
+
+		if wait := p.cooldownLeft(idx); wait > 0 {
+			logger.Warn("agent cooling down after rate limits, waiting", "agent", name, "wait", wait.Round(time.Second))
+			if err := sleepCtx(ctx, wait); err != nil {
+				return "", name, fmt.Errorf("agent %s: waiting for rate-limit cooldown: %w", name, err)
+			}
+		}
+
+		start := time.Now()
 		output, err := agent.Run(ctx, dir, prompt)
+		if err != nil && ctx.Err() != nil {
+			// Cancelled by forge, not the agent's fault: no health update, no fallback.
+			return output, name, fmt.Errorf("agent %s: %w", name, err)
+		}
+		p.record(idx, time.Since(start), err, logger)
 		if err == nil {
 			return output, name, nil
 		}
 
-		if !retryableError(err) || i == n-1 {
+		if !retryableError(err) || i == len(order)-1 {
 			return output, name, fmt.Errorf("agent %s: %w", name, err)
 		}
+		finishAgent(ctx, agent, output)
 
 		logger.Warn("agent failed with retryable error, trying next",
-			"agent", name, "error", err, "next", p.names[(startIdx+i+1)%n])
+			"agent", name, "error", err, "next", p.names[order[i+1]])
 	}
 	// unreachable, but satisfies the compiler
 	return "", "", fmt.Errorf("all agents exhausted")
@@ -92,16 +406,61 @@ func (p *AgentPool) RunWithFallback(ctx context.Context, startIdx int, dir, prom
 type fallbackAgent struct {
 	pool     *AgentPool
 	startIdx int
+	held     bool           // startIdx's run slot was reserved with TryAcquire
+	override provider.Agent // plan-frontmatter agent standing in for startIdx (see usePlan)
 	logger   *slog.Logger
+	last     string // name of the agent that produced the latest output
 }
 
 func (f *fallbackAgent) Run(ctx context.Context, dir, prompt string) (string, error) {
-	output, _, err := f.pool.RunWithFallback(ctx, f.startIdx, dir, prompt, f.logger)
+	output, name, err := f.pool.runWithFallback(ctx, f.startIdx, f.override, dir, prompt, f.logger)
+	f.last = name
 	return output, err
 }
 
+// agent returns the agent that runs for the pool slot idx.
+func (f *fallbackAgent) agent(idx int) provider.Agent {
+	if idx == f.startIdx%f.pool.Len() && f.override != nil {
+		return f.override
+	}
+	return f.pool.Assign(idx)
+}
+
+// ParseResult parses output with the parser of the agent that produced it.
+func (f *fallbackAgent) ParseResult(output string) provider.AgentResult {
+	idx := f.startIdx
+	if i, ok := f.pool.index(f.last); ok {
+		idx = i
+	}
+	return agentResult(f.agent(idx), output)
+}
+
 func (f *fallbackAgent) PromptSuffix() string {
-	return f.pool.Assign(f.startIdx).PromptSuffix()
+	return f.agent(f.startIdx).PromptSuffix()
+}
+
+// usePlan routes the run's calls to the pool agent a plan's frontmatter names
+// (or the assigned one), built with the plan's overrides by build, so they
+// keep the pool's health, cooldowns and fallback. A reserved run slot moves
+// with the run, and fails the run if that agent is at batch.max_per_agent.
+func (f *fallbackAgent) usePlan(p *plan.Plan, build func(p *plan.Plan, name string) (provider.Agent, error)) error {
+	idx := f.startIdx % f.pool.Len()
+	if p.Agent != "" {
+		i, ok := f.pool.index(p.Agent)
+		if !ok {
+			return fmt.Errorf("agent %q is not in agent.providers", p.Agent)
+		}
+		idx = i
+	}
+	ag, err := build(p, f.pool.names[idx])
+	if err != nil {
+		return err
+	}
+	if f.held && !f.pool.move(f.startIdx%f.pool.Len(), idx) {
+		return fmt.Errorf("agent %s is at batch.max_per_agent", f.pool.names[idx])
+	}
+	f.startIdx, f.override = idx, ag
+	return nil
 }
 
 // NewFallbackAgent creates a provider.Agent that delegates to the pool with fallback.
@@ -6,8 +6,10 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -171,3 +173,168 @@ func TestRunWithFallback_StartIdxWraps(t *testing.T) {
 	assert.Equal(t, "result from codex", output)
 	assert.Equal(t, "codex", name)
 }
+
+func TestAgentPool_TryAcquire_SpreadsAndRespectsLimit(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+	pool.SetMaxConcurrent(1)
+
+	first, ok := pool.TryAcquire()
+	require.True(t, ok)
+	second, ok := pool.TryAcquire()
+	require.True(t, ok)
+	assert.NotEqual(t, first, second, "second run goes to the idle agent")
+
+	_, ok = pool.TryAcquire()
+	assert.False(t, ok, "both agents at max_per_agent")
+
+	pool.Release(second)
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok)
+	assert.Equal(t, second, idx)
+}
+
+func TestAgentPool_TryAcquire_Unlimited(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+
+	counts := map[int]int{}
+	for i := 0; i < 6; i++ {
+		idx, ok := pool.TryAcquire()
+		require.True(t, ok)
+		counts[idx]++
+	}
+	assert.Equal(t, map[int]int{0: 3, 1: 3}, counts)
+}
+
+// --- health-based routing tests ---
+
+func TestRunWithFallback_RateLimitStartsCooldown(t *testing.T) {
+	a1 := &mockAgent{err: errors.New("HTTP 429 Too Many Requests")}
+	a2 := &mockAgent{output: "from codex"}
+	pool := NewAgentPool([]provider.Agent{a1, a2}, []string{"claude", "codex"})
+
+	_, name, err := pool.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "codex", name)
+
+	h := pool.Health("claude")
+	assert.Equal(t, 1, h.RateLimits)
+	assert.True(t, h.CoolingDown(time.Now()))
+	assert.Equal(t, 1, pool.Health("codex").Successes)
+
+	// The next call assigned to claude goes straight to codex.
+	_, name, err = pool.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "codex", name)
+	assert.Equal(t, 1, a1.callCount, "cooling agent should not be retried")
+
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok)
+	assert.Equal(t, "codex", pool.AssignName(idx), "new runs avoid the cooling agent")
+}
+
+func TestRunWithFallback_SuccessResetsRateLimits(t *testing.T) {
+	a := &mockAgent{output: "ok"}
+	pool := NewAgentPool([]provider.Agent{a}, []string{"claude"})
+	pool.health["claude"] = &state.AgentHealth{RateLimits: 3, Failures: 3}
+
+	_, _, err := pool.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+
+	h := pool.Health("claude")
+	assert.Zero(t, h.RateLimits)
+	assert.Equal(t, 1, h.Successes)
+	assert.Positive(t, h.AvgLatency)
+}
+
+func TestRunWithFallback_WaitsOutCooldownWhenAllCooling(t *testing.T) {
+	a := &mockAgent{output: "ok"}
+	pool := NewAgentPool([]provider.Agent{a}, []string{"claude"})
+	pool.health["claude"] = &state.AgentHealth{CooldownUntil: time.Now().Add(time.Hour)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := pool.RunWithFallback(ctx, 0, "/dir", "prompt", poolLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for rate-limit cooldown")
+	assert.False(t, a.Called(), "agent must not run during its cooldown")
+
+	pool.health["claude"].CooldownUntil = time.Now().Add(10 * time.Millisecond)
+	out, _, err := pool.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out)
+}
+
+func TestRunWithFallback_FallsBackToHealthiestFirst(t *testing.T) {
+	a1 := &mockAgent{err: errors.New("invalid credentials")}
+	a2 := &mockAgent{output: "from codex"}
+	a3 := &mockAgent{output: "from gemini"}
+	pool := NewAgentPool([]provider.Agent{a1, a2, a3}, []string{"claude", "codex", "gemini"})
+	pool.health["codex"] = &state.AgentHealth{Successes: 1, Failures: 4}
+	pool.health["gemini"] = &state.AgentHealth{Successes: 5}
+
+	_, name, err := pool.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", name)
+	assert.False(t, a2.Called())
+}
+
+func TestAgentPool_TryAcquire_PrefersHealthiest(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}, &mockAgent{}}, []string{"claude", "codex", "gemini"})
+	pool.health["claude"] = &state.AgentHealth{Successes: 2, Failures: 6}
+	pool.health["gemini"] = &state.AgentHealth{Successes: 8}
+
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok)
+	assert.Equal(t, "gemini", pool.AssignName(idx))
+}
+
+func TestAgentPool_TryAcquire_AllCoolingPicksSoonestRecovery(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+	pool.health["claude"] = &state.AgentHealth{CooldownUntil: time.Now().Add(20 * time.Minute)}
+	pool.health["codex"] = &state.AgentHealth{CooldownUntil: time.Now().Add(2 * time.Minute)}
+
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok, "cooling agents still get work so batches don't stall")
+	assert.Equal(t, "codex", pool.AssignName(idx))
+}
+
+func TestAgentPool_LoadHealth_PersistsAcrossPools(t *testing.T) {
+	chdirTemp(t)
+	first := NewAgentPool([]provider.Agent{
+		&mockAgent{err: errors.New("rate limit exceeded")},
+		&mockAgent{output: "ok"},
+	}, []string{"claude", "codex"})
+	require.NoError(t, first.LoadHealth())
+	_, _, err := first.RunWithFallback(context.Background(), 0, "/dir", "prompt", poolLogger())
+	require.NoError(t, err)
+
+	// A later forge invocation doesn't start with the throttled agent.
+	second := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+	require.NoError(t, second.LoadHealth())
+	assert.True(t, second.Health("claude").CoolingDown(time.Now()))
+	idx, ok := second.TryAcquire()
+	require.True(t, ok)
+	assert.Equal(t, "codex", second.AssignName(idx))
+}
+
+func TestCooldownFor(t *testing.T) {
+	rl := errors.New("rate limit exceeded")
+	assert.Equal(t, time.Minute, cooldownFor(1, rl))
+	assert.Equal(t, 2*time.Minute, cooldownFor(2, rl))
+	assert.Equal(t, 16*time.Minute, cooldownFor(5, rl))
+	assert.Equal(t, 30*time.Minute, cooldownFor(40, rl), "capped")
+
+	assert.Equal(t, 90*time.Second, cooldownFor(1, errors.New("429: retry after 90s")))
+	assert.Equal(t, 30*time.Second, cooldownFor(3, errors.New("Retry-After: 30")))
+	assert.Equal(t, 5*time.Minute, cooldownFor(1, errors.New("usage limit reached, try again in 5 minutes")))
+	assert.Equal(t, 30*time.Minute, cooldownFor(1, errors.New("quota exhausted, try again in 3 hours")), "capped")
+}
+
+func TestRateLimited(t *testing.T) {
+	assert.True(t, rateLimited(errors.New("HTTP 429 Too Many Requests")))
+	assert.True(t, rateLimited(errors.New("anthropic API: overloaded_error")))
+	assert.True(t, rateLimited(errors.New("quota exceeded for model")))
+	assert.False(t, rateLimited(errors.New("invalid credentials")))
+	assert.False(t, rateLimited(errors.New("agent timed out after 45m")))
+	assert.False(t, rateLimited(nil))
+}
@@ -7,7 +7,6 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/shahar-caura/forge/internal/config"
 	"github.com/shahar-caura/forge/internal/state"
@@ -24,226 +23,184 @@ type PushOpts struct {
 // Push executes the push pipeline: create issue → commit+push → PR → notify.
 // Steps that don't apply to push mode are auto-completed.
 func Push(ctx context.Context, cfg *config.Config, providers Providers, opts PushOpts, rs *state.RunState, logger *slog.Logger) error {
+	lock, err := state.AcquireLock(rs.ID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	var lastErr error
 
+	ctx, untrack := trackRun(ctx, rs, lock, logger)
+	defer untrack()
+
 	defer func() {
 		if rs.Status != state.RunCompleted {
+			if runCancelled(ctx, rs) {
+				rs.Status = state.RunCancelled
+				_ = rs.Save()
+				logger.Info("run cancelled", "id", rs.ID)
+				return
+			}
 			rs.Status = state.RunFailed
 			_ = rs.Save()
 
 			if providers.Notifier != nil && lastErr != nil {
 				failMsg := fmt.Sprintf("forge push failed (run `%s`): %s\n`forge status %s` · `forge logs %s`", rs.ID, lastErr, rs.ID, rs.ID)
-				_ = providers.Notifier.Notify(ctx, failMsg)
+				if providers.Notifier.Notify(ctx, failMsg) == nil {
+					record(ctx, state.EventNotificationSent, map[string]any{"message": failMsg})
+				}
 			}
 		}
 	}()
 
-	title := opts.Title
-	body := opts.Message
+	rc := &RunContext{
+		Config:       cfg,
+		Providers:    providers,
+		State:        rs,
+		Logger:       logger,
+		PlanBody:     opts.Message,
+		PlanTitle:    opts.Title,
+		DisplayTitle: opts.Title,
+		Branch:       opts.Branch,
+		WorktreePath: opts.Dir,
+		Push:         opts,
+	}
 
-	// Step 0: Read plan — auto-complete (title from flag/branch).
-	if err := runStep(rs, 0, logger, func() error {
-		if title == "" {
-			title = TitleFromBranch(opts.Branch)
-		}
-		rs.PlanTitle = title
-		return nil
-	}); err != nil {
+	rs.SyncSteps(StepNames(pushSteps))
+	if err := executeSteps(ctx, rc, pushSteps); err != nil {
 		lastErr = err
 		return err
 	}
-	// Restore title on resume.
-	if title == "" {
-		title = rs.PlanTitle
-	}
 
-	displayTitle := title
+	rs.Status = state.RunCompleted
+	_ = rs.Save()
+	return nil
+}
 
-	// Step 1: Create issue (optional).
-	if err := runStep(rs, 1, logger, func() error {
-		if providers.Tracker == nil {
-			logger.Info("no tracker configured, skipping")
-			return nil
-		}
-		issue, err := providers.Tracker.CreateIssue(ctx, displayTitle, body)
-		if err != nil {
-			return err
-		}
-		rs.IssueKey = issue.Key
-		rs.IssueURL = issue.URL
-		logger.Info("created issue", "key", issue.Key, "url", issue.URL)
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
-	}
+// pushSteps mirror the run step names so push runs share the state layout.
+var pushSteps = []Step{
+	stepFunc{name: "read plan", run: pushReadPlan, resume: pushRestoreTitle},
+	stepFunc{name: "create issue", run: createIssue},
+	stepFunc{name: "generate branch", run: pushUseBranch},
+	stepFunc{name: "create worktree", run: pushUseDir},
+	stepFunc{name: "run agent", run: pushSkipAgent},
+	stepFunc{name: "commit and push", run: pushCommit},
+	stepFunc{name: "create pr", run: pushCreatePR, idempotent: true},
+	stepFunc{name: "poll cr", run: pushPollCR, idempotent: true},
+	stepFunc{name: "fix cr", run: pushFixCR},
+	stepFunc{name: "push cr fix", run: pushSkipCRFix},
+	stepFunc{name: "notify", run: notify},
+}
 
-	// Step 2: Generate branch — auto-complete (use current branch).
-	if err := runStep(rs, 2, logger, func() error {
-		rs.Branch = opts.Branch
-		logger.Info("using current branch", "branch", opts.Branch)
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
+// pushReadPlan auto-completes: title comes from the flag or the branch name.
+func pushReadPlan(_ context.Context, rc *RunContext) error {
+	if rc.PlanTitle == "" {
+		rc.PlanTitle = TitleFromBranch(rc.Push.Branch)
 	}
+	rc.DisplayTitle = rc.PlanTitle
+	rc.State.PlanTitle = rc.PlanTitle
+	return nil
+}
 
-	// Step 3: Create worktree — auto-complete (use cwd).
-	if err := runStep(rs, 3, logger, func() error {
-		rs.WorktreePath = opts.Dir
-		logger.Info("using current directory", "dir", opts.Dir)
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
+// pushRestoreTitle restores the title on resume.
+func pushRestoreTitle(_ context.Context, rc *RunContext) error {
+	if rc.PlanTitle == "" {
+		rc.PlanTitle = rc.State.PlanTitle
 	}
+	rc.DisplayTitle = rc.PlanTitle
+	return nil
+}
 
-	// Step 4: Run agent — auto-complete (no agent for push).
-	if err := runStep(rs, 4, logger, func() error {
-		logger.Info("no agent for push mode, skipping")
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
-	}
+// pushUseBranch auto-completes: push mode uses the current branch.
+func pushUseBranch(_ context.Context, rc *RunContext) error {
+	rc.State.Branch = rc.Push.Branch
+	rc.Logger.Info("using current branch", "branch", rc.Push.Branch)
+	return nil
+}
 
-	// Step 5: Commit and push.
-	if err := runStep(rs, 5, logger, func() error {
-		hasChanges, err := providers.VCS.HasChanges(ctx, opts.Dir)
-		if err != nil {
-			return fmt.Errorf("checking for changes: %w", err)
-		}
-		if hasChanges {
-			if cfg.Hooks.PreCommit != "" {
-				if err := runHook(ctx, cfg.Hooks.PreCommit, opts.Dir, logger); err != nil {
-					return fmt.Errorf("pre-commit hook: %w", err)
-				}
+// pushUseDir auto-completes: push mode uses the current directory.
+func pushUseDir(_ context.Context, rc *RunContext) error {
+	rc.State.WorktreePath = rc.Push.Dir
+	rc.Logger.Info("using current directory", "dir", rc.Push.Dir)
+	return nil
+}
+
+// pushSkipAgent auto-completes: push mode has no agent.
+func pushSkipAgent(_ context.Context, rc *RunContext) error {
+	rc.Logger.Info("no agent for push mode, skipping")
+	return nil
+}
+
+// pushCommit commits uncommitted changes (running the hook without agent retry),
+// or just pushes existing commits.
+func pushCommit(ctx context.Context, rc *RunContext) error {
+	opts := rc.Push
+	hasChanges, err := rc.Providers.VCS.HasChanges(ctx, opts.Dir)
+	if err != nil {
+		return fmt.Errorf("checking for changes: %w", err)
+	}
+	if hasChanges {
+		if rc.Config.Hooks.PreCommit != "" {
+			if err := runHook(ctx, rc.Config.Hooks.PreCommit, opts.Dir, rc.Logger); err != nil {
+				return fmt.Errorf("pre-commit hook: %w", err)
 			}
-			commitMsg := fmt.Sprintf("forge: %s", displayTitle)
-			return providers.VCS.CommitAndPush(ctx, opts.Dir, opts.Branch, commitMsg)
 		}
-		// No uncommitted changes — just push existing commits.
-		return providers.VCS.Push(ctx, opts.Dir, opts.Branch)
-	}); err != nil {
-		lastErr = err
-		return err
+		commitMsg := fmt.Sprintf("forge: %s", rc.DisplayTitle)
+		return rc.Providers.VCS.CommitAndPush(ctx, opts.Dir, opts.Branch, commitMsg)
 	}
+	// No uncommitted changes — just push existing commits.
+	return rc.Providers.VCS.Push(ctx, opts.Dir, opts.Branch)
+}
 
-	// Infer body from commit log if not provided.
+// pushCreatePR opens the PR, inferring the body from the commit log if not provided.
+func pushCreatePR(ctx context.Context, rc *RunContext) error {
+	opts := rc.Push
+	body := rc.PlanBody
 	if body == "" {
-		body = commitLogSummary(opts.Dir, cfg.VCS.BaseBranch)
+		body = commitLogSummary(opts.Dir, rc.Config.VCS.BaseBranch)
 	}
-
-	// Step 6: Create PR.
-	if err := runStep(rs, 6, logger, func() error {
-		if rs.IssueURL != "" {
-			body = fmt.Sprintf("[%s](%s)\n\n%s", rs.IssueKey, rs.IssueURL, body)
-		}
-		pr, err := providers.VCS.CreatePR(ctx, opts.Branch, cfg.VCS.BaseBranch, displayTitle, body)
-		if err != nil {
-			return err
-		}
-		rs.PRUrl = pr.URL
-		rs.PRNumber = pr.Number
-		logger.Info("created PR", "pr", pr.URL)
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
+	if rc.State.IssueURL != "" {
+		body = fmt.Sprintf("[%s](%s)\n\n%s", rc.State.IssueKey, rc.State.IssueURL, body)
 	}
-
-	// Step 7: Poll CR (optional — skipped if CR not enabled or local mode).
-	if err := runStep(rs, 7, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
-		if cfg.CR.Mode == "local" {
-			logger.Info("local CR mode requires agent context, skipping in push mode")
-			return nil
-		}
-		logger.Info("polling for CR comment...", "pattern", cfg.CR.CommentPattern, "timeout", cfg.CR.PollTimeout.Duration)
-
-		pattern, err := regexp.Compile(cfg.CR.CommentPattern)
-		if err != nil {
-			return fmt.Errorf("compiling comment_pattern: %w", err)
-		}
-
-		deadline := time.Now().Add(cfg.CR.PollTimeout.Duration)
-		for {
-			if time.Now().After(deadline) {
-				return fmt.Errorf("poll timeout: no matching CR comment found after %s", cfg.CR.PollTimeout.Duration)
-			}
-
-			comments, err := providers.VCS.GetPRComments(ctx, rs.PRNumber)
-			if err != nil {
-				return fmt.Errorf("fetching PR comments: %w", err)
-			}
-
-			for _, c := range comments {
-				if pattern.MatchString(c.Body) {
-					logger.Info("matched CR comment", "author", c.Author, "id", c.ID)
-					rs.CRFeedback = c.Body
-					return nil
-				}
-			}
-
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(cfg.CR.PollInterval.Duration):
-				// continue polling
-			}
-		}
-	}); err != nil {
-		lastErr = err
+	pr, err := rc.Providers.VCS.CreatePR(ctx, opts.Branch, rc.Config.VCS.BaseBranch, rc.DisplayTitle, body)
+	if err != nil {
 		return err
 	}
+	rc.setPR(ctx, pr)
+	return nil
+}
 
-	// Step 8: Fix CR — auto-complete (no agent for push; feedback logged for user).
-	if err := runStep(rs, 8, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
-		logger.Info("CR feedback received; no agent in push mode — fix manually or re-run forge push", "feedback", rs.CRFeedback)
+// pushPollCR polls for CR (optional — skipped if CR not enabled or local mode).
+func pushPollCR(ctx context.Context, rc *RunContext) error {
+	if !rc.Config.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
 		return nil
-	}); err != nil {
-		lastErr = err
-		return err
 	}
-
-	// Step 9: Push CR fix — auto-complete (user fixes manually).
-	if err := runStep(rs, 9, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
-		logger.Info("no auto-fix in push mode, skipping")
+	if rc.Config.CR.Mode == "local" {
+		rc.Logger.Info("local CR mode requires agent context, skipping in push mode")
 		return nil
-	}); err != nil {
-		lastErr = err
-		return err
 	}
+	return pollCRComment(ctx, rc)
+}
 
-	// Step 10: Notify (optional).
-	if err := runStep(rs, 10, logger, func() error {
-		if providers.Notifier == nil {
-			logger.Info("no notifier configured, skipping")
-			return nil
-		}
-		msg := fmt.Sprintf("PR ready for review: %s", rs.PRUrl)
-		if rs.IssueKey != "" {
-			msg += fmt.Sprintf(" (issue: %s)", rs.IssueURL)
-		}
-		return providers.Notifier.Notify(ctx, msg)
-	}); err != nil {
-		lastErr = err
-		return err
+// pushFixCR auto-completes: no agent for push; feedback is logged for the user.
+func pushFixCR(_ context.Context, rc *RunContext) error {
+	if !rc.Config.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
+		return nil
 	}
+	rc.Logger.Info("CR feedback received; no agent in push mode — fix manually or re-run forge push", "feedback", rc.State.CRFeedback)
+	return nil
+}
 
-	rs.Status = state.RunCompleted
-	_ = rs.Save()
+// pushSkipCRFix auto-completes: the user fixes CR feedback manually.
+func pushSkipCRFix(_ context.Context, rc *RunContext) error {
+	if !rc.Config.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
+		return nil
+	}
+	rc.Logger.Info("no auto-fix in push mode, skipping")
 	return nil
 }
 
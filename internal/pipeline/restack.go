@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// RestackMerged retargets stacked runs whose parent PR has merged. Each child
+// branch is rebased off the parent's branch onto the freshly fetched branch
+// the parent merged into, force-pushed, and its PR base is updated. Runs a
+// live process is executing are left alone. Called by forge cleanup and by
+// stacked batches as issues finish. Returns the count of restacked runs.
+func RestackMerged(ctx context.Context, vcs provider.VCS, baseBranch string, logger *slog.Logger) (int, error) {
+	runs, err := state.List()
+	if err != nil {
+		return 0, err
+	}
+
+	byBranch := make(map[string]*state.RunState, len(runs))
+	for _, rs := range runs {
+		if rs.Branch != "" {
+			if _, ok := byBranch[rs.Branch]; !ok {
+				byBranch[rs.Branch] = rs // newest run wins
+			}
+		}
+	}
+
+	restacked := 0
+	for _, rs := range runs {
+		if rs.PRBase == "" || rs.PRNumber == 0 {
+			continue
+		}
+
+		parent, ok := byBranch[rs.PRBase]
+		if !ok || parent.PRNumber == 0 {
+			logger.Warn("no run found for stack parent, skipping", "id", rs.ID, "parent", rs.PRBase)
+			continue
+		}
+
+		parentState, err := vcs.GetPRState(ctx, parent.PRNumber)
+		if err != nil {
+			logger.Warn("failed to check parent PR state, skipping", "pr", parent.PRNumber, "error", err)
+			continue
+		}
+		if parentState != "MERGED" {
+			continue
+		}
+
+		prState, err := vcs.GetPRState(ctx, rs.PRNumber)
+		if err != nil {
+			logger.Warn("failed to check PR state, skipping", "pr", rs.PRNumber, "error", err)
+			continue
+		}
+		if prState != "OPEN" {
+			continue
+		}
+
+		if owner := rs.LockOwner(); owner != nil {
+			logger.Info("stacked run is in progress, restacking later", "id", rs.ID, "pid", owner.PID)
+			continue
+		}
+
+		// The parent may itself have been stacked; follow it to where it merged.
+		newBase := parent.PRBase
+		if newBase == "" {
+			newBase = baseBranch
+		}
+
+		// Completed runs usually had their worktree removed (worktree.cleanup);
+		// RebaseOnto then works in a temporary one.
+		dir := rs.WorktreePath
+		if dir != "" {
+			if _, err := os.Stat(dir); err != nil {
+				dir = ""
+			}
+		}
+
+		logger.Info("restacking PR onto merged parent's base", "pr", rs.PRNumber, "from", rs.PRBase, "onto", newBase, "worktree", dir)
+		if err := vcs.FetchBase(ctx, dir, newBase); err != nil {
+			logger.Warn("failed to fetch new base for stacked branch", "branch", rs.Branch, "base", newBase, "error", err)
+			continue
+		}
+		if err := vcs.RebaseOnto(ctx, dir, rs.Branch, rs.PRBase, newBase); err != nil {
+			logger.Warn("failed to rebase stacked branch", "branch", rs.Branch, "error", err)
+			continue
+		}
+		if err := vcs.UpdatePRBase(ctx, rs.PRNumber, newBase); err != nil {
+			logger.Warn("failed to retarget PR", "pr", rs.PRNumber, "error", err)
+			continue
+		}
+
+		if newBase == baseBranch {
+			rs.PRBase = ""
+		} else {
+			rs.PRBase = newBase
+		}
+		rs.BaseRef = ""
+		if err := rs.Save(); err != nil {
+			logger.Warn("failed to save run state after restack", "id", rs.ID, "error", err)
+		}
+		restacked++
+	}
+
+	return restacked, nil
+}
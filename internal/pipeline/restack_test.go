@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restackMockVCS records FetchBase, RebaseOnto and UpdatePRBase calls.
+type restackMockVCS struct {
+	cleanupMockVCS
+	fetches   []string
+	rebases   [][]string
+	retargets map[int]string
+}
+
+func (m *restackMockVCS) FetchBase(_ context.Context, dir, baseBranch string) error {
+	m.fetches = append(m.fetches, dir+" "+baseBranch)
+	return nil
+}
+
+func (m *restackMockVCS) RebaseOnto(_ context.Context, dir, branch, oldBase, newBase string) error {
+	m.rebases = append(m.rebases, []string{dir, branch, oldBase, newBase})
+	return nil
+}
+
+func (m *restackMockVCS) UpdatePRBase(_ context.Context, prNumber int, baseBranch string) error {
+	if m.retargets == nil {
+		m.retargets = map[int]string{}
+	}
+	m.retargets[prNumber] = baseBranch
+	return nil
+}
+
+// saveStackedRuns saves a parent run and a child stacked on it, returning the
+// child's worktree.
+func saveStackedRuns(t *testing.T) string {
+	t.Helper()
+	origDir, _ := os.Getwd()
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { _ = os.Chdir(origDir) })
+
+	parent := state.New("run-parent", "plan.md")
+	parent.Branch = "forge/parent"
+	parent.PRNumber = 10
+	require.NoError(t, parent.Save())
+
+	child := state.New("run-child", "plan.md")
+	child.Branch = "forge/child"
+	child.PRNumber = 11
+	child.PRBase = "forge/parent"
+	child.BaseRef = "forge/parent"
+	child.WorktreePath = t.TempDir()
+	require.NoError(t, child.Save())
+	return child.WorktreePath
+}
+
+func TestRestackMerged_RetargetsChildOfMergedParent(t *testing.T) {
+	wt := saveStackedRuns(t)
+	vc := &restackMockVCS{cleanupMockVCS: cleanupMockVCS{prStates: map[int]string{10: "MERGED", 11: "OPEN"}}}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	restacked, err := RestackMerged(context.Background(), vc, "main", logger)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, restacked)
+	assert.Equal(t, []string{wt + " main"}, vc.fetches)
+	assert.Equal(t, [][]string{{wt, "forge/child", "forge/parent", "main"}}, vc.rebases)
+	assert.Equal(t, map[int]string{11: "main"}, vc.retargets)
+
+	reloaded, err := state.Load("run-child")
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.PRBase)
+	assert.Empty(t, reloaded.BaseRef)
+}
+
+func TestRestackMerged_WorktreeRemoved(t *testing.T) {
+	wt := saveStackedRuns(t)
+	require.NoError(t, os.RemoveAll(wt))
+	vc := &restackMockVCS{cleanupMockVCS: cleanupMockVCS{prStates: map[int]string{10: "MERGED", 11: "OPEN"}}}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	restacked, err := RestackMerged(context.Background(), vc, "main", logger)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, restacked)
+	assert.Equal(t, [][]string{{"", "forge/child", "forge/parent", "main"}}, vc.rebases, "rebases without the removed worktree")
+	assert.Equal(t, map[int]string{11: "main"}, vc.retargets)
+}
+
+func TestRestackMerged_ParentOpen_NoOp(t *testing.T) {
+	saveStackedRuns(t)
+	vc := &restackMockVCS{cleanupMockVCS: cleanupMockVCS{prStates: map[int]string{10: "OPEN", 11: "OPEN"}}}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	restacked, err := RestackMerged(context.Background(), vc, "main", logger)
+
+	require.NoError(t, err)
+	assert.Zero(t, restacked)
+	assert.Empty(t, vc.rebases)
+	assert.Empty(t, vc.retargets)
+
+	reloaded, err := state.Load("run-child")
+	require.NoError(t, err)
+	assert.Equal(t, "forge/parent", reloaded.PRBase)
+}
+
+func TestRestackMerged_SkipsRunInProgress(t *testing.T) {
+	saveStackedRuns(t)
+	lock, err := state.AcquireLock("run-child")
+	require.NoError(t, err)
+	defer lock.Release()
+	vc := &restackMockVCS{cleanupMockVCS: cleanupMockVCS{prStates: map[int]string{10: "MERGED", 11: "OPEN"}}}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	restacked, err := RestackMerged(context.Background(), vc, "main", logger)
+
+	require.NoError(t, err)
+	assert.Zero(t, restacked)
+	assert.Empty(t, vc.rebases)
+}
+
+func TestRestackMerged_FollowsStackedParent(t *testing.T) {
+	saveStackedRuns(t)
+	parent, err := state.Load("run-parent")
+	require.NoError(t, err)
+	parent.PRBase = "forge/grandparent"
+	require.NoError(t, parent.Save())
+	vc := &restackMockVCS{cleanupMockVCS: cleanupMockVCS{prStates: map[int]string{10: "MERGED", 11: "OPEN"}}}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err = RestackMerged(context.Background(), vc, "main", logger)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{11: "forge/grandparent"}, vc.retargets)
+	reloaded, err := state.Load("run-child")
+	require.NoError(t, err)
+	assert.Equal(t, "forge/grandparent", reloaded.PRBase)
+}
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+)
+
+// idempotentStep is implemented by steps that are safe to run again after a
+// failure. The runner only retries steps that report true.
+type idempotentStep interface {
+	Idempotent() bool
+}
+
+// retryPolicy decides whether a failed step runs again, and after how long.
+type retryPolicy struct {
+	maxAttempts int
+	initial     time.Duration
+	max         time.Duration
+	multiplier  float64
+	jitter      float64
+	extra       []*regexp.Regexp
+	rand        func() float64 // [0, 1); overridable for testing
+}
+
+// newRetryPolicy builds the policy from cfg.Retry. A nil or unloaded config
+// (zero max_attempts) yields a policy that never retries.
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	p := retryPolicy{maxAttempts: 1, rand: rand.Float64}
+	if cfg == nil || cfg.Retry.MaxAttempts <= 1 {
+		return p
+	}
+	rc := cfg.Retry
+	p.maxAttempts = rc.MaxAttempts
+	p.initial = rc.InitialBackoff.Duration
+	p.max = rc.MaxBackoff.Duration
+	p.multiplier = rc.Multiplier
+	p.jitter = rc.Jitter
+	for _, pattern := range rc.RetryableErrors {
+		// Patterns are validated at config load.
+		if re, err := regexp.Compile("(?i)" + pattern); err == nil {
+			p.extra = append(p.extra, re)
+		}
+	}
+	return p
+}
+
+// shouldRetry reports whether s, having failed attempt (1-based) with err,
+// should run again: the step is idempotent, attempts remain, the run wasn't
+// cancelled, and the error is transient.
+func (p retryPolicy) shouldRetry(ctx context.Context, s Step, err error, attempt int) bool {
+	if attempt >= p.maxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if is, ok := s.(idempotentStep); !ok || !is.Idempotent() {
+		return false
+	}
+	return p.transient(err)
+}
+
+// transient reports whether err looks like a failure that may not recur:
+// a network blip, server error or rate limit (see classifyError), or a match
+// for one of the configured retryable_errors.
+func (p retryPolicy) transient(err error) bool {
+	var budgetErr *BudgetExceededError
+	if err == nil || errors.As(err, &budgetErr) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if class := classifyError(err); class == errTransient || class == errRateLimited {
+		return true
+	}
+	for _, re := range p.extra {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before retrying after the given failed attempt:
+// initial * multiplier^(attempt-1), capped at max, randomized by ± jitter.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.initial) * math.Pow(p.multiplier, float64(attempt-1))
+	d = math.Min(d, float64(p.max))
+	if p.jitter > 0 {
+		d *= 1 + p.jitter*(2*p.rand()-1)
+	}
+	return time.Duration(d)
+}
+
+// sleepCtx waits for d or until ctx is done, returning ctx's error if so.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
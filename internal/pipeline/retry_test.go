@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRetryConfig retries up to three times with near-zero waits.
+func testRetryConfig() *config.Config {
+	cfg := testConfig()
+	cfg.Retry = config.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: config.Duration{Duration: time.Millisecond},
+		MaxBackoff:     config.Duration{Duration: time.Millisecond},
+		Multiplier:     1,
+	}
+	return cfg
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	cfg := testConfig()
+	cfg.Retry = config.RetryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: config.Duration{Duration: 5 * time.Second},
+		MaxBackoff:     config.Duration{Duration: 30 * time.Second},
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+	p := newRetryPolicy(cfg)
+
+	p.rand = func() float64 { return 0.5 } // no jitter
+	assert.Equal(t, 5*time.Second, p.backoff(1))
+	assert.Equal(t, 10*time.Second, p.backoff(2))
+	assert.Equal(t, 20*time.Second, p.backoff(3))
+	assert.Equal(t, 30*time.Second, p.backoff(4), "capped at max_backoff")
+
+	p.rand = func() float64 { return 0 }
+	assert.Equal(t, 4*time.Second, p.backoff(1))
+	p.rand = func() float64 { return 1 }
+	assert.Equal(t, 6*time.Second, p.backoff(1))
+}
+
+func TestRetryPolicy_Transient(t *testing.T) {
+	cfg := testRetryConfig()
+	cfg.Retry.RetryableErrors = []string{`secondary rate`, `^lock held`}
+	p := newRetryPolicy(cfg)
+
+	for _, msg := range []string{
+		"gh pr create: exit status 1: HTTP 502 Bad Gateway",
+		"agent failed: exit status 1: API Error: 529 {\"type\":\"overloaded_error\"}",
+		"git push: exit status 128: fatal: unable to access: Could not resolve host: github.com",
+		"read tcp 10.0.0.1:443: connection reset by peer",
+		"You have exceeded a SECONDARY RATE limit",
+		"Lock held by another process",
+		"gh: HTTP 429: API rate quota used up",
+		"POST https://api.github.com/graphql: HTTP/2 500",
+		"request failed with status code: 504",
+	} {
+		assert.True(t, p.transient(errors.New(msg)), msg)
+	}
+	for _, err := range []error{
+		nil,
+		errors.New("gh pr create: exit status 1: auth required"),
+		errors.New("agent produced no file changes"),
+		fmt.Errorf("step: %w", &BudgetExceededError{Limit: "per_run_usd"}),
+		fmt.Errorf("agent: %w", context.Canceled),
+		errors.New("hook failed: internal/api/handler.go:503: undefined: foo"),
+		errors.New("gh pr merge: pull request #429 is not mergeable"),
+		errors.New("--- FAIL: TestParse (0.529s)"),
+		errors.New("gh api: HTTP 404: Not Found"),
+	} {
+		assert.False(t, p.transient(err), "%v", err)
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	p := newRetryPolicy(testRetryConfig())
+	ctx := context.Background()
+	blip := errors.New("connection refused")
+	idem := stepFunc{name: "create pr", idempotent: true}
+
+	assert.True(t, p.shouldRetry(ctx, idem, blip, 1))
+	assert.True(t, p.shouldRetry(ctx, idem, blip, 2))
+	assert.False(t, p.shouldRetry(ctx, idem, blip, 3), "attempts exhausted")
+	assert.False(t, p.shouldRetry(ctx, stepFunc{name: "create issue"}, blip, 1), "not idempotent")
+	assert.False(t, p.shouldRetry(ctx, idem, errors.New("syntax error"), 1), "not transient")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, p.shouldRetry(cancelled, idem, blip, 1))
+
+	assert.False(t, newRetryPolicy(testConfig()).shouldRetry(ctx, idem, blip, 1), "unset policy never retries")
+}
+
+func TestSteps_Idempotence(t *testing.T) {
+	retried := func(steps []Step) []string {
+		var names []string
+		for _, s := range steps {
+			if s.(idempotentStep).Idempotent() {
+				names = append(names, s.Name())
+			}
+		}
+		return names
+	}
+	// Agent steps are paid and change the worktree; local poll cr posts comments.
+	assert.Equal(t, []string{"read plan", "generate branch", "create pr"}, retried(runSteps))
+	assert.Equal(t, []string{"create pr", "poll cr"}, retried(pushSteps))
+}
+
+func TestRun_RetriesTransientCreatePR(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{
+		pr:     &provider.PR{URL: "https://github.com/owner/repo/pull/3", Number: 3},
+		prErrs: []error{errors.New("gh pr create: exit status 1: HTTP 502 Bad Gateway")},
+	}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testRetryConfig(), defaultProviders(wt, &mockAgent{}, vc), planPath, rs, testLogger()))
+	assert.Equal(t, 2, vc.createPRCalls)
+	assert.Equal(t, 3, rs.PRNumber)
+
+	idx, _ := rs.StepIndex("create pr")
+	require.Len(t, rs.Steps[idx].Attempts, 2)
+	assert.NotEmpty(t, rs.Steps[idx].Attempts[0].Error)
+	assert.Equal(t, state.StepCompleted, rs.Steps[idx].Status)
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	assert.Contains(t, eventTypes(events), "create pr/step_retrying")
+}
+
+func TestRun_NoRetryForNonIdempotentStep(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{commitErr: errors.New("git push: connection reset by peer")}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), testRetryConfig(), defaultProviders(wt, &mockAgent{}, vc), planPath, rs, testLogger())
+	require.Error(t, err)
+
+	idx, _ := rs.StepIndex("commit and push")
+	assert.Len(t, rs.Steps[idx].Attempts, 1)
+	assert.Equal(t, state.RunFailed, rs.Status)
+}
+
+func TestRun_RetryGivesUpAfterMaxAttempts(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{prErr: errors.New("HTTP 503 Service Unavailable")}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), testRetryConfig(), defaultProviders(wt, &mockAgent{}, vc), planPath, rs, testLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	assert.Equal(t, 3, vc.createPRCalls)
+
+	idx, _ := rs.StepIndex("create pr")
+	assert.Len(t, rs.Steps[idx].Attempts, 3)
+	assert.Equal(t, state.StepFailed, rs.Steps[idx].Status)
+}
@@ -2,7 +2,6 @@ package pipeline
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -27,395 +26,481 @@ type Providers struct {
 	Tracker     provider.Tracker  // nil if unconfigured
 	Notifier    provider.Notifier // nil if unconfigured
 	AgentPool   *AgentPool        // nil means single-agent mode
+
+	// PlanAgent builds agent name with the settings a plan's frontmatter
+	// overrides (model, timeout, allowed_tools, env); name is the plan's agent,
+	// or else the one the run was given. Nil ignores overrides.
+	PlanAgent func(p *plan.Plan, name string) (provider.Agent, error)
 }
 
-// Run executes the forge pipeline:
+// Run executes the forge pipeline. The default step sequence is:
 //
 //	read plan → create issue → generate branch → create worktree → run agent →
 //	commit → PR → poll cr → fix cr → push cr fix → notify.
 //
+// pipeline.steps in forge.yaml may remove, reorder, or add registered steps.
 // If rs has completed steps (resume), those steps are skipped and locals are restored from rs artifacts.
 func Run(ctx context.Context, cfg *config.Config, providers Providers, planPath string, rs *state.RunState, logger *slog.Logger) error {
-	var (
-		parsedPlan   *plan.Plan
-		planBody     string
-		planTitle    string
-		branch       string
-		worktreePath string
-		lastErr      error
-	)
+	// Refuse to drive a run another forge process is executing; the run is
+	// left untouched (not marked failed).
+	lock, err := state.AcquireLock(rs.ID)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	var lastErr error
 
 	// Restore artifacts from state on resume.
-	branch = rs.Branch
-	worktreePath = rs.WorktreePath
+	rc := &RunContext{
+		Config:       cfg,
+		Providers:    providers,
+		State:        rs,
+		Logger:       logger,
+		PlanPath:     planPath,
+		Branch:       rs.Branch,
+		WorktreePath: rs.WorktreePath,
+	}
 
-	// On failure, mark run as failed, preserve worktree, and best-effort notify.
+	ctx, untrack := trackRun(ctx, rs, lock, logger)
+	defer untrack()
+
+	// On failure, mark run as failed (or cancelled), preserve worktree, and best-effort notify.
 	defer func() {
 		if rs.Status != state.RunCompleted {
+			if runCancelled(ctx, rs) {
+				rs.Status = state.RunCancelled
+				_ = rs.Save()
+				logger.Info("run cancelled", "id", rs.ID)
+				return
+			}
 			rs.Status = state.RunFailed
 			_ = rs.Save()
 
 			// Best-effort failure notification — can't fail-fast when already failing.
 			if providers.Notifier != nil && lastErr != nil {
 				failMsg := fmt.Sprintf("forge pipeline failed (run `%s`): %s\n`forge status %s` · `forge logs %s`", rs.ID, lastErr, rs.ID, rs.ID)
-				_ = providers.Notifier.Notify(ctx, failMsg)
+				if providers.Notifier.Notify(ctx, failMsg) == nil {
+					record(ctx, state.EventNotificationSent, map[string]any{"message": failMsg})
+				}
 			}
 		}
 	}()
 
-	// Step 0: Read plan file and parse frontmatter.
-	if err := runStep(rs, 0, logger, func() error {
-		planBytes, err := os.ReadFile(planPath)
-		if err != nil {
-			return err
-		}
-		parsedPlan, err = plan.Parse(string(planBytes))
-		if err != nil {
-			return fmt.Errorf("parsing plan frontmatter: %w", err)
-		}
-		planBody = parsedPlan.Body
-		planTitle = parsedPlan.Title
-		rs.PlanTitle = planTitle
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
-	}
-	// Re-read plan on resume (plan content not stored in state).
-	if parsedPlan == nil {
-		planBytes, err := os.ReadFile(planPath)
-		if err != nil {
-			return fmt.Errorf("re-reading plan on resume: %w", err)
+	// Defer cleanup: remove worktree only on success.
+	defer func() {
+		if rc.WorktreePath == "" {
+			return
 		}
-		parsedPlan, err = plan.Parse(string(planBytes))
-		if err != nil {
-			return fmt.Errorf("parsing plan frontmatter on resume: %w", err)
+		if rs.Status != state.RunCompleted {
+			logger.Info("preserving worktree for resume", "path", rc.WorktreePath)
+			return
 		}
-		planBody = parsedPlan.Body
-		planTitle = parsedPlan.Title
-		if planTitle == "" {
-			planTitle = rs.PlanTitle
+		if cleanupErr := providers.Worktree.Remove(ctx, rc.WorktreePath); cleanupErr != nil {
+			logger.Error("worktree cleanup failed", "error", cleanupErr)
 		}
-	}
-
-	// Determine display title: frontmatter title, or fallback to filename.
-	displayTitle := planTitle
-	if displayTitle == "" {
-		displayTitle = TitleFromFilename(filepath.Base(strings.TrimSuffix(planPath, filepath.Ext(planPath))))
-	}
+	}()
 
-	// Step 1: Create issue (optional — skipped if no tracker configured).
-	if err := runStep(rs, 1, logger, func() error {
-		if providers.Tracker == nil {
-			logger.Info("no tracker configured, skipping")
-			return nil
-		}
-		issue, err := providers.Tracker.CreateIssue(ctx, displayTitle, planBody)
-		if err != nil {
-			return err
-		}
-		rs.IssueKey = issue.Key
-		rs.IssueURL = issue.URL
-		logger.Info("created issue", "key", issue.Key, "url", issue.URL)
-		return nil
-	}); err != nil {
+	steps, err := BuildSteps(cfg)
+	if err != nil {
 		lastErr = err
 		return err
 	}
+	rs.SyncSteps(StepNames(steps))
 
-	// Step 2: Generate branch name.
-	if err := runStep(rs, 2, logger, func() error {
-		branch = BranchName(rs.IssueKey, displayTitle)
-		if err := ValidateBranchName(branch); err != nil {
-			logger.Warn("branch name validation failed, using as-is", "branch", branch, "error", err)
-		}
-		rs.Branch = branch
-		logger.Info("generated branch name", "branch", branch)
-		return nil
-	}); err != nil {
+	if err := executeSteps(ctx, rc, steps); err != nil {
 		lastErr = err
 		return err
 	}
 
-	// Step 3: Create worktree.
-	worktreeWasCompleted := rs.Steps[3].Status == state.StepCompleted
-	if err := runStep(rs, 3, logger, func() error {
-		path, err := providers.Worktree.Create(ctx, branch, cfg.VCS.BaseBranch)
-		if err != nil {
-			return err
-		}
-		worktreePath = path
-		rs.WorktreePath = worktreePath
-		return nil
-	}); err != nil {
-		lastErr = err
+	rs.Status = state.RunCompleted
+	_ = rs.Save()
+	return nil
+}
+
+// runSteps are the built-in plan-run steps, registered by name in init.
+// Steps that create something outside forge without checking for it first
+// (issue, worktree, commits, local CR comments, notifications) or rerun a
+// paid agent on an already-changed worktree are not idempotent and never retried.
+var runSteps = []Step{
+	stepFunc{name: "read plan", run: readPlan, resume: reloadPlan, idempotent: true},
+	stepFunc{name: "create issue", run: createIssue},
+	stepFunc{name: "generate branch", run: generateBranch, idempotent: true},
+	stepFunc{name: "create worktree", run: createWorktree, resume: ensureWorktree},
+	stepFunc{name: "run agent", run: runAgent},
+	stepFunc{name: "commit and push", run: commitAndPush},
+	stepFunc{name: "create pr", run: createPR, idempotent: true},
+	stepFunc{name: "poll cr", run: pollCR},
+	stepFunc{name: "fix cr", run: fixCR},
+	stepFunc{name: "push cr fix", run: pushCRFix},
+	stepFunc{name: "notify", run: notify},
+}
+
+// readPlan reads the plan file and parses frontmatter.
+func readPlan(_ context.Context, rc *RunContext) error {
+	planBytes, err := os.ReadFile(rc.PlanPath)
+	if err != nil {
 		return err
 	}
-	// On resume with completed worktree step, re-create if cleaned up.
-	if worktreeWasCompleted && worktreePath != "" {
-		if _, err := os.Stat(worktreePath); err != nil {
-			logger.Info("worktree no longer exists, re-creating", "path", worktreePath)
-			path, err := providers.Worktree.Create(ctx, branch, cfg.VCS.BaseBranch)
-			if err != nil {
-				lastErr = fmt.Errorf("step 4 (create worktree): re-creating: %w", err)
-				return lastErr
-			}
-			worktreePath = path
-			rs.WorktreePath = worktreePath
-			_ = rs.Save()
-		}
+	parsedPlan, err := plan.Parse(string(planBytes))
+	if err != nil {
+		return fmt.Errorf("parsing plan frontmatter: %w", err)
 	}
+	rc.setPlan(parsedPlan)
+	rc.State.PlanTitle = rc.PlanTitle
+	return rc.usePlanAgent(parsedPlan)
+}
 
-	// Defer cleanup: remove worktree only on success.
-	defer func() {
-		if worktreePath == "" {
-			return
+// reloadPlan re-reads the plan on resume (plan content not stored in state).
+func reloadPlan(_ context.Context, rc *RunContext) error {
+	planBytes, err := os.ReadFile(rc.PlanPath)
+	if err != nil {
+		return fmt.Errorf("re-reading plan on resume: %w", err)
+	}
+	parsedPlan, err := plan.Parse(string(planBytes))
+	if err != nil {
+		return fmt.Errorf("parsing plan frontmatter on resume: %w", err)
+	}
+	if parsedPlan.Title == "" {
+		parsedPlan.Title = rc.State.PlanTitle
+	}
+	rc.setPlan(parsedPlan)
+	return rc.usePlanAgent(parsedPlan)
+}
+
+// usePlanAgent switches the run to the agent the plan's frontmatter asks for.
+// With an agent pool the override runs in place of its pool agent, so pool
+// routing and limits still apply. The CR review agent (cr.agent) is unaffected.
+func (rc *RunContext) usePlanAgent(p *plan.Plan) error {
+	if !p.HasAgentOverrides() || rc.Providers.PlanAgent == nil {
+		return nil
+	}
+	if fa, ok := rc.Providers.Agent.(*fallbackAgent); ok {
+		if err := fa.usePlan(p, rc.Providers.PlanAgent); err != nil {
+			return fmt.Errorf("plan agent overrides: %w", err)
 		}
-		if rs.Status == state.RunFailed {
-			logger.Info("preserving worktree for resume", "path", worktreePath)
-			return
+	} else {
+		name := p.Agent
+		if name == "" {
+			name = rc.Config.Agent.Provider
 		}
-		if cleanupErr := providers.Worktree.Remove(ctx, worktreePath); cleanupErr != nil {
-			logger.Error("worktree cleanup failed", "error", cleanupErr)
+		ag, err := rc.Providers.PlanAgent(p, name)
+		if err != nil {
+			return fmt.Errorf("plan agent overrides: %w", err)
 		}
-	}()
+		rc.Providers.Agent = ag
+	}
+	rc.Logger.Info("using plan agent overrides", "agent", p.Agent, "model", p.Model, "timeout", p.Timeout, "env", len(p.Env))
+	return nil
+}
 
-	// Step 4: Run agent.
-	if err := runStep(rs, 4, logger, func() error {
-		logFile, cleanup := openAgentLog(rs.ID, 4, providers.Agent, logger)
-		defer cleanup()
+// setPlan stores the parsed plan and derives the display title:
+// frontmatter title, or fallback to filename.
+func (rc *RunContext) setPlan(p *plan.Plan) {
+	rc.PlanBody = p.Body
+	rc.PlanTitle = p.Title
+	rc.DisplayTitle = p.Title
+	if rc.DisplayTitle == "" {
+		rc.DisplayTitle = TitleFromFilename(filepath.Base(strings.TrimSuffix(rc.PlanPath, filepath.Ext(rc.PlanPath))))
+	}
+}
 
-		agentPrompt := buildAgentPrompt(planBody) + providers.Agent.PromptSuffix()
-		output, err := providers.Agent.Run(ctx, worktreePath, agentPrompt)
-		if logFile == nil {
-			saveAgentLog(rs.ID, 4, output)
-		}
-		if err != nil {
-			return err
-		}
-		// Verify agent produced file changes — fail fast on no-op.
-		hasChanges, chkErr := providers.VCS.HasChanges(ctx, worktreePath)
-		if chkErr != nil {
-			return fmt.Errorf("checking for changes: %w", chkErr)
-		}
-		if !hasChanges {
-			reply := agentResultText(output)
-			if len(reply) > 300 {
-				reply = reply[:300] + "..."
-			}
-			return fmt.Errorf("agent produced no file changes; agent replied: %s", reply)
-		}
+// createIssue creates a tracker issue (optional — skipped if no tracker configured).
+func createIssue(ctx context.Context, rc *RunContext) error {
+	if rc.Providers.Tracker == nil {
+		rc.Logger.Info("no tracker configured, skipping")
 		return nil
-	}); err != nil {
-		lastErr = err
+	}
+	issue, err := rc.Providers.Tracker.CreateIssue(ctx, rc.DisplayTitle, rc.PlanBody)
+	if err != nil {
 		return err
 	}
+	rc.State.IssueKey = issue.Key
+	rc.State.IssueURL = issue.URL
+	rc.Logger.Info("created issue", "key", issue.Key, "url", issue.URL)
+	return nil
+}
+
+// generateBranch derives the branch name from the issue key and title.
+func generateBranch(_ context.Context, rc *RunContext) error {
+	rc.Branch = BranchName(rc.State.IssueKey, rc.DisplayTitle)
+	if err := ValidateBranchName(rc.Branch); err != nil {
+		rc.Logger.Warn("branch name validation failed, using as-is", "branch", rc.Branch, "error", err)
+	}
+	rc.State.Branch = rc.Branch
+	rc.Logger.Info("generated branch name", "branch", rc.Branch)
+	return nil
+}
 
-	// Step 5: Commit and push.
-	if err := runStep(rs, 5, logger, func() error {
-		// Rebase onto latest base branch so the worktree picks up any
-		// fixes that landed on master since it was created.
-		if err := providers.VCS.FetchAndRebase(ctx, worktreePath, cfg.VCS.BaseBranch); err != nil {
-			logger.Warn("rebase onto base branch failed, continuing", "error", err)
-		}
-		if cfg.Hooks.PreCommit != "" {
-			if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, worktreePath, providers.Agent, cfg.Hooks.MaxHookRetries, logger); err != nil {
-				return fmt.Errorf("pre-commit hook: %w", err)
-			}
-		}
-		commitMsg := fmt.Sprintf("forge: %s", displayTitle)
-		return providers.VCS.CommitAndPush(ctx, worktreePath, branch, commitMsg)
-	}); err != nil {
-		lastErr = err
+// worktreeBase returns the ref new worktrees start from: the run's BaseRef
+// when set (e.g. a freshly fetched origin/<base>), otherwise vcs.base_branch.
+func (rc *RunContext) worktreeBase() string {
+	if rc.State.BaseRef != "" {
+		return rc.State.BaseRef
+	}
+	return rc.Config.VCS.BaseBranch
+}
+
+// prBase returns the branch the run's PR targets and rebases onto: the parent
+// branch for stacked runs, otherwise vcs.base_branch.
+func (rc *RunContext) prBase() string {
+	if rc.State.PRBase != "" {
+		return rc.State.PRBase
+	}
+	return rc.Config.VCS.BaseBranch
+}
+
+// createWorktree creates an isolated worktree for the branch.
+func createWorktree(ctx context.Context, rc *RunContext) error {
+	path, err := rc.Providers.Worktree.Create(ctx, rc.Branch, rc.worktreeBase())
+	if err != nil {
 		return err
 	}
+	rc.WorktreePath = path
+	rc.State.WorktreePath = path
+	return nil
+}
 
-	// Step 6: Create PR.
-	if err := runStep(rs, 6, logger, func() error {
-		title := displayTitle
-		prBody := planBody
-		if rs.SourceIssue > 0 {
-			prBody = fmt.Sprintf("Closes #%d\n\n%s", rs.SourceIssue, planBody)
-		}
-		if rs.IssueURL != "" {
-			prBody = fmt.Sprintf("[%s](%s)\n\n%s", rs.IssueKey, rs.IssueURL, prBody)
-		}
-		pr, err := providers.VCS.CreatePR(ctx, branch, cfg.VCS.BaseBranch, title, prBody)
-		if err != nil {
-			return err
-		}
-		rs.PRUrl = pr.URL
-		rs.PRNumber = pr.Number
-		logger.Info("created PR", "pr", pr.URL)
+// ensureWorktree re-creates the worktree on resume if it was cleaned up.
+func ensureWorktree(ctx context.Context, rc *RunContext) error {
+	if rc.WorktreePath == "" {
 		return nil
-	}); err != nil {
-		lastErr = err
-		return err
 	}
+	if _, err := os.Stat(rc.WorktreePath); err == nil {
+		return nil
+	}
+	rc.Logger.Info("worktree no longer exists, re-creating", "path", rc.WorktreePath)
+	path, err := rc.Providers.Worktree.Create(ctx, rc.Branch, rc.worktreeBase())
+	if err != nil {
+		return fmt.Errorf("re-creating: %w", err)
+	}
+	rc.WorktreePath = path
+	rc.State.WorktreePath = path
+	_ = rc.State.Save()
+	return nil
+}
 
-	// Step 7: CR review (optional — skipped if CR not enabled).
-	// In "local" mode, the full review-fix loop runs here. In "poll" mode, polls for external review.
-	if err := runStep(rs, 7, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
+// runAgent runs the coding agent on the plan and verifies it changed files.
+func runAgent(ctx context.Context, rc *RunContext) error {
+	ag := rc.Providers.Agent
+	logIdx := rc.StepIndex("run agent")
+	logFile, cleanup := openAgentLog(rc.State.ID, logIdx, ag, rc.Logger)
+	defer cleanup()
 
-		if cfg.CR.Mode == "local" {
-			return localReview(ctx, cfg, providers, rs, worktreePath, planBody, displayTitle, logger)
+	agentPrompt := buildAgentPrompt(rc.PlanBody) + ag.PromptSuffix()
+	if err := startAgent(ctx, map[string]any{"purpose": "implement plan"}); err != nil {
+		return err
+	}
+	output, err := ag.Run(ctx, rc.WorktreePath, agentPrompt)
+	res := finishAgent(ctx, ag, output)
+	if logFile == nil {
+		saveAgentLog(rc.State.ID, logIdx, output)
+	}
+	if err != nil {
+		return err
+	}
+	// Verify agent produced file changes — fail fast on no-op.
+	hasChanges, chkErr := rc.Providers.VCS.HasChanges(ctx, rc.WorktreePath)
+	if chkErr != nil {
+		return fmt.Errorf("checking for changes: %w", chkErr)
+	}
+	if !hasChanges {
+		reply := res.Text
+		if len(reply) > 300 {
+			reply = reply[:300] + "..."
 		}
+		return fmt.Errorf("agent produced no file changes; agent replied: %s", reply)
+	}
+	return nil
+}
 
-		// Poll mode (default).
-		logger.Info("polling for CR comment...", "pattern", cfg.CR.CommentPattern, "timeout", cfg.CR.PollTimeout.Duration)
-
-		pattern, err := regexp.Compile(cfg.CR.CommentPattern)
-		if err != nil {
-			return fmt.Errorf("compiling comment_pattern: %w", err)
+// commitAndPush rebases, runs the pre-commit hook, commits, and pushes.
+func commitAndPush(ctx context.Context, rc *RunContext) error {
+	cfg := rc.Config
+	// Rebase onto latest base branch so the worktree picks up any
+	// fixes that landed on master since it was created.
+	if err := rc.Providers.VCS.FetchAndRebase(ctx, rc.WorktreePath, rc.prBase()); err != nil {
+		rc.Logger.Warn("rebase onto base branch failed, continuing", "error", err)
+	}
+	if cfg.Hooks.PreCommit != "" {
+		if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, rc.WorktreePath, rc.Providers.Agent, cfg.Hooks.MaxHookRetries, rc.Logger); err != nil {
+			return fmt.Errorf("pre-commit hook: %w", err)
 		}
+	}
+	commitMsg := fmt.Sprintf("forge: %s", rc.DisplayTitle)
+	return rc.Providers.VCS.CommitAndPush(ctx, rc.WorktreePath, rc.Branch, commitMsg)
+}
 
-		deadline := time.Now().Add(cfg.CR.PollTimeout.Duration)
-		for {
-			if time.Now().After(deadline) {
-				return fmt.Errorf("poll timeout: no matching CR comment found after %s", cfg.CR.PollTimeout.Duration)
-			}
-
-			comments, err := providers.VCS.GetPRComments(ctx, rs.PRNumber)
-			if err != nil {
-				return fmt.Errorf("fetching PR comments: %w", err)
-			}
+// createPR opens the pull request, linking the source and tracker issues.
+// A PR already open for the branch is adopted (see provider.VCS.CreatePR).
+func createPR(ctx context.Context, rc *RunContext) error {
+	rs := rc.State
+	prBody := rc.PlanBody
+	if rs.SourceIssue > 0 {
+		prBody = fmt.Sprintf("Closes #%d\n\n%s", rs.SourceIssue, prBody)
+	}
+	if rs.IssueURL != "" {
+		prBody = fmt.Sprintf("[%s](%s)\n\n%s", rs.IssueKey, rs.IssueURL, prBody)
+	}
+	pr, err := rc.Providers.VCS.CreatePR(ctx, rc.Branch, rc.prBase(), rc.DisplayTitle, prBody)
+	if err != nil {
+		return err
+	}
+	rc.setPR(ctx, pr)
+	return nil
+}
 
-			for _, c := range comments {
-				if pattern.MatchString(c.Body) {
-					logger.Info("matched CR comment", "author", c.Author, "id", c.ID)
-					rs.CRFeedback = c.Body
-					return nil
-				}
-			}
+// setPR records pr in the run state and journal.
+func (rc *RunContext) setPR(ctx context.Context, pr *provider.PR) {
+	rc.State.PRUrl = pr.URL
+	rc.State.PRNumber = pr.Number
+	data := map[string]any{"url": pr.URL, "number": pr.Number}
+	if pr.Existing {
+		rc.Logger.Info("adopted existing PR", "pr", pr.URL)
+		data["existing"] = true
+	} else {
+		rc.Logger.Info("created PR", "pr", pr.URL)
+	}
+	record(ctx, state.EventPRCreated, data)
+}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(cfg.CR.PollInterval.Duration):
-				// continue polling
-			}
-		}
-	}); err != nil {
-		lastErr = err
-		return err
+// pollCR runs the CR review (optional — skipped if CR not enabled).
+// In "local" mode, the full review-fix loop runs here. In "poll" mode, polls for external review.
+func pollCR(ctx context.Context, rc *RunContext) error {
+	if !rc.Config.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
+		return nil
 	}
+	if rc.Config.CR.Mode == "local" {
+		return localReview(ctx, rc)
+	}
+	return pollCRComment(ctx, rc)
+}
 
-	// Step 8: Fix CR — re-run agent with CR feedback.
-	if err := runStep(rs, 8, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
-		if cfg.CR.Mode == "local" {
-			logger.Info("handled in local CR loop (step 7), skipping")
-			return nil
-		}
+// pollCRComment polls PR comments until one matches cr.comment_pattern or the poll times out.
+func pollCRComment(ctx context.Context, rc *RunContext) error {
+	cfg := rc.Config
+	rc.Logger.Info("polling for CR comment...", "pattern", cfg.CR.CommentPattern, "timeout", cfg.CR.PollTimeout.Duration)
 
-		logFile, cleanup := openAgentLog(rs.ID, 8, providers.Agent, logger)
-		defer cleanup()
+	pattern, err := regexp.Compile(cfg.CR.CommentPattern)
+	if err != nil {
+		return fmt.Errorf("compiling comment_pattern: %w", err)
+	}
 
-		fixPrompt := buildFixCRPrompt(rs.CRFeedback, planBody)
-		output, err := providers.Agent.Run(ctx, worktreePath, fixPrompt)
-		if logFile == nil {
-			saveAgentLog(rs.ID, 8, output)
+	deadline := time.Now().Add(cfg.CR.PollTimeout.Duration)
+	for {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("poll timeout: no matching CR comment found after %s", cfg.CR.PollTimeout.Duration)
 		}
+
+		comments, err := rc.Providers.VCS.GetPRComments(ctx, rc.State.PRNumber)
 		if err != nil {
-			return err
+			return fmt.Errorf("fetching PR comments: %w", err)
 		}
-		rs.CRFixSummary = extractCRSummary(output)
-		return nil
-	}); err != nil {
-		lastErr = err
-		return err
-	}
 
-	// Step 9: Push CR fix.
-	if err := runStep(rs, 9, logger, func() error {
-		if !cfg.CR.Enabled {
-			logger.Info("CR feedback loop disabled, skipping")
-			return nil
-		}
-		if cfg.CR.Mode == "local" {
-			logger.Info("handled in local CR loop (step 7), skipping")
-			return nil
-		}
-		if cfg.Hooks.PreCommit != "" {
-			if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, worktreePath, providers.Agent, cfg.Hooks.MaxHookRetries, logger); err != nil {
-				return fmt.Errorf("pre-commit hook: %w", err)
+		for _, c := range comments {
+			if pattern.MatchString(c.Body) {
+				rc.Logger.Info("matched CR comment", "author", c.Author, "id", c.ID)
+				record(ctx, state.EventCRCommentMatched, map[string]any{"author": c.Author, "comment_id": c.ID})
+				rc.State.CRFeedback = c.Body
+				return nil
 			}
 		}
-		if cfg.CR.FixStrategy == "new-commit" {
-			commitMsg := fmt.Sprintf("forge: address CR feedback for %s", displayTitle)
-			if err := providers.VCS.CommitAndPush(ctx, worktreePath, branch, commitMsg); err != nil {
-				return err
-			}
-		} else {
-			// Default: amend.
-			if err := providers.VCS.AmendAndForcePush(ctx, worktreePath, branch); err != nil {
-				return err
-			}
-		}
-		// Best-effort reply comment — use agent summary if available.
-		comment := "CR feedback addressed. Changes pushed."
-		if rs.CRFixSummary != "" {
-			comment = rs.CRFixSummary
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.CR.PollInterval.Duration):
+			// continue polling
 		}
-		_ = providers.VCS.PostPRComment(ctx, rs.PRNumber, comment)
+	}
+}
+
+// fixCR re-runs the agent with CR feedback.
+func fixCR(ctx context.Context, rc *RunContext) error {
+	if !rc.Config.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
 		return nil
-	}); err != nil {
-		lastErr = err
+	}
+	if rc.Config.CR.Mode == "local" {
+		rc.Logger.Info("handled in local CR loop (step 7), skipping")
+		return nil
+	}
+
+	ag := rc.Providers.Agent
+	logIdx := rc.StepIndex("fix cr")
+	logFile, cleanup := openAgentLog(rc.State.ID, logIdx, ag, rc.Logger)
+	defer cleanup()
+
+	fixPrompt := buildFixCRPrompt(rc.State.CRFeedback, rc.PlanBody)
+	if err := startAgent(ctx, map[string]any{"purpose": "fix cr"}); err != nil {
+		return err
+	}
+	output, err := ag.Run(ctx, rc.WorktreePath, fixPrompt)
+	res := finishAgent(ctx, ag, output)
+	if logFile == nil {
+		saveAgentLog(rc.State.ID, logIdx, output)
+	}
+	if err != nil {
 		return err
 	}
+	rc.State.CRFixSummary = extractCRSummary(res.Text)
+	return nil
+}
 
-	// Step 10: Notify (optional — skipped if no notifier configured).
-	if err := runStep(rs, 10, logger, func() error {
-		if providers.Notifier == nil {
-			logger.Info("no notifier configured, skipping")
-			return nil
+// pushCRFix pushes the CR fix and replies on the PR.
+func pushCRFix(ctx context.Context, rc *RunContext) error {
+	cfg := rc.Config
+	if !cfg.CR.Enabled {
+		rc.Logger.Info("CR feedback loop disabled, skipping")
+		return nil
+	}
+	if cfg.CR.Mode == "local" {
+		rc.Logger.Info("handled in local CR loop (step 7), skipping")
+		return nil
+	}
+	if cfg.Hooks.PreCommit != "" {
+		if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, rc.WorktreePath, rc.Providers.Agent, cfg.Hooks.MaxHookRetries, rc.Logger); err != nil {
+			return fmt.Errorf("pre-commit hook: %w", err)
 		}
-		msg := fmt.Sprintf("PR ready for review: %s", rs.PRUrl)
-		if rs.IssueKey != "" {
-			msg += fmt.Sprintf(" (issue: %s)", rs.IssueURL)
+	}
+	if cfg.CR.FixStrategy == "new-commit" {
+		commitMsg := fmt.Sprintf("forge: address CR feedback for %s", rc.DisplayTitle)
+		if err := rc.Providers.VCS.CommitAndPush(ctx, rc.WorktreePath, rc.Branch, commitMsg); err != nil {
+			return err
+		}
+	} else {
+		// Default: amend.
+		if err := rc.Providers.VCS.AmendAndForcePush(ctx, rc.WorktreePath, rc.Branch); err != nil {
+			return err
 		}
-		return providers.Notifier.Notify(ctx, msg)
-	}); err != nil {
-		lastErr = err
-		return err
 	}
-
-	rs.Status = state.RunCompleted
-	_ = rs.Save()
+	// Best-effort reply comment — use agent summary if available.
+	comment := "CR feedback addressed. Changes pushed."
+	if rc.State.CRFixSummary != "" {
+		comment = rc.State.CRFixSummary
+	}
+	_ = rc.Providers.VCS.PostPRComment(ctx, rc.State.PRNumber, comment)
 	return nil
 }
 
-// runStep executes fn for the given step index, skipping if already completed.
-// It persists state transitions: pending → running → completed/failed.
-func runStep(rs *state.RunState, idx int, logger *slog.Logger, fn func() error) error {
-	step := &rs.Steps[idx]
-
-	if step.Status == state.StepCompleted {
-		logger.Info("skipping completed step", "step", step.Name)
+// notify sends the PR-ready notification (optional — skipped if no notifier configured).
+func notify(ctx context.Context, rc *RunContext) error {
+	if rc.Providers.Notifier == nil {
+		rc.Logger.Info("no notifier configured, skipping")
 		return nil
 	}
-
-	step.Status = state.StepRunning
-	step.Error = ""
-	_ = rs.Save()
-
-	if err := fn(); err != nil {
-		step.Status = state.StepFailed
-		step.Error = err.Error()
-		rs.Status = state.RunFailed
-		_ = rs.Save()
-		return fmt.Errorf("step %d (%s): %w", idx+1, step.Name, err)
+	msg := fmt.Sprintf("PR ready for review: %s", rc.State.PRUrl)
+	if rc.State.IssueKey != "" {
+		msg += fmt.Sprintf(" (issue: %s)", rc.State.IssueURL)
 	}
-
-	step.Status = state.StepCompleted
-	_ = rs.Save()
+	if usage := rc.State.TotalUsage(); !usage.IsZero() {
+		msg += fmt.Sprintf("\nAgent usage: %s", usage)
+	}
+	if err := rc.Providers.Notifier.Notify(ctx, msg); err != nil {
+		return err
+	}
+	record(ctx, state.EventNotificationSent, map[string]any{"message": msg})
 	return nil
 }
 
@@ -503,30 +588,14 @@ Omit any section that has no items. Every issue from the review must appear in e
 `
 }
 
-// agentResultText extracts the "result" field from `claude -p --output-format json` output.
-// Falls back to the raw string if parsing fails or the field is missing.
-func agentResultText(output string) string {
-	var parsed struct {
-		Result string `json:"result"`
-	}
-	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
-		return output
-	}
-	if parsed.Result == "" {
-		return output
-	}
-	return parsed.Result
-}
-
 var (
 	crReviewMarker  = "---CRREVIEW---"
 	crSummaryMarker = "---CRSUMMARY---"
 )
 
-// extractCRSummary extracts the text between ---CRSUMMARY--- markers from agent output.
+// extractCRSummary extracts the text between ---CRSUMMARY--- markers from an agent's reply.
 // Returns empty string if markers are missing or content is empty.
-func extractCRSummary(output string) string {
-	text := agentResultText(output)
+func extractCRSummary(text string) string {
 	parts := strings.SplitN(text, crSummaryMarker, 3)
 	if len(parts) < 3 {
 		return ""
@@ -583,10 +652,9 @@ NO_ISSUES
 `
 }
 
-// extractReviewFeedback extracts the text between ---CRREVIEW--- markers from agent output.
+// extractReviewFeedback extracts the text between ---CRREVIEW--- markers from an agent's reply.
 // Returns empty string if markers are missing or content is empty.
-func extractReviewFeedback(output string) string {
-	text := agentResultText(output)
+func extractReviewFeedback(text string) string {
 	parts := strings.SplitN(text, crReviewMarker, 3)
 	if len(parts) < 3 {
 		return ""
@@ -611,9 +679,11 @@ func reviewAgent(providers Providers) provider.Agent {
 
 // localReview runs the local review-then-fix loop.
 // It runs a review agent read-only, parses structured feedback, fixes issues, and pushes.
-func localReview(ctx context.Context, cfg *config.Config, providers Providers, rs *state.RunState, worktreePath, planBody, displayTitle string, logger *slog.Logger) error {
-	branch := rs.Branch
+func localReview(ctx context.Context, rc *RunContext) error {
+	cfg, providers, rs, logger := rc.Config, rc.Providers, rc.State, rc.Logger
 	ra := reviewAgent(providers)
+	reviewLogIdx := rc.StepIndex("poll cr")
+	fixLogIdx := rc.StepIndex("fix cr")
 
 	for round := 1; round <= cfg.CR.MaxRetries; round++ {
 		logger.Info("local CR review round", "round", round, "max", cfg.CR.MaxRetries)
@@ -621,11 +691,16 @@ func localReview(ctx context.Context, cfg *config.Config, providers Providers, r
 		_ = rs.Save()
 
 		// 1. Run review agent (read-only).
-		logFile, cleanup := openAgentLog(rs.ID, 7, ra, logger)
-		reviewPrompt := buildReviewPrompt(cfg.VCS.BaseBranch) + ra.PromptSuffix()
-		reviewOutput, err := ra.Run(ctx, worktreePath, reviewPrompt)
+		logFile, cleanup := openAgentLog(rs.ID, reviewLogIdx, ra, logger)
+		reviewPrompt := buildReviewPrompt(rc.prBase()) + ra.PromptSuffix()
+		if err := startAgent(ctx, map[string]any{"purpose": "review", "round": round}); err != nil {
+			cleanup()
+			return fmt.Errorf("review agent (round %d): %w", round, err)
+		}
+		reviewOutput, err := ra.Run(ctx, rc.WorktreePath, reviewPrompt)
+		review := finishAgent(ctx, ra, reviewOutput)
 		if logFile == nil {
-			saveAgentLog(rs.ID, 7, reviewOutput)
+			saveAgentLog(rs.ID, reviewLogIdx, reviewOutput)
 		}
 		cleanup()
 		if err != nil {
@@ -633,7 +708,7 @@ func localReview(ctx context.Context, cfg *config.Config, providers Providers, r
 		}
 
 		// 2. Extract and check feedback.
-		feedback := extractReviewFeedback(reviewOutput)
+		feedback := extractReviewFeedback(review.Text)
 		if !hasActionableIssues(feedback) {
 			logger.Info("review clean, no issues found", "round", round)
 			return nil
@@ -642,33 +717,38 @@ func localReview(ctx context.Context, cfg *config.Config, providers Providers, r
 		rs.CRFeedback = feedback
 
 		// 3. Run fix agent.
-		logFile, cleanup = openAgentLog(rs.ID, 8, providers.Agent, logger)
-		fixPrompt := buildFixCRPrompt(feedback, planBody)
-		fixOutput, err := providers.Agent.Run(ctx, worktreePath, fixPrompt)
+		logFile, cleanup = openAgentLog(rs.ID, fixLogIdx, providers.Agent, logger)
+		fixPrompt := buildFixCRPrompt(feedback, rc.PlanBody)
+		if err := startAgent(ctx, map[string]any{"purpose": "fix cr", "round": round}); err != nil {
+			cleanup()
+			return fmt.Errorf("fix agent (round %d): %w", round, err)
+		}
+		fixOutput, err := providers.Agent.Run(ctx, rc.WorktreePath, fixPrompt)
+		fix := finishAgent(ctx, providers.Agent, fixOutput)
 		if logFile == nil {
-			saveAgentLog(rs.ID, 8, fixOutput)
+			saveAgentLog(rs.ID, fixLogIdx, fixOutput)
 		}
 		cleanup()
 		if err != nil {
 			return fmt.Errorf("fix agent (round %d): %w", round, err)
 		}
-		rs.CRFixSummary = extractCRSummary(fixOutput)
+		rs.CRFixSummary = extractCRSummary(fix.Text)
 
 		// 4. Pre-commit hook.
 		if cfg.Hooks.PreCommit != "" {
-			if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, worktreePath, providers.Agent, cfg.Hooks.MaxHookRetries, logger); err != nil {
+			if err := runHookWithRetry(ctx, cfg.Hooks.PreCommit, rc.WorktreePath, providers.Agent, cfg.Hooks.MaxHookRetries, logger); err != nil {
 				return fmt.Errorf("pre-commit hook (round %d): %w", round, err)
 			}
 		}
 
 		// 5. Push.
 		if cfg.CR.FixStrategy == "new-commit" {
-			commitMsg := fmt.Sprintf("forge: address CR feedback (round %d) for %s", round, displayTitle)
-			if err := providers.VCS.CommitAndPush(ctx, worktreePath, branch, commitMsg); err != nil {
+			commitMsg := fmt.Sprintf("forge: address CR feedback (round %d) for %s", round, rc.DisplayTitle)
+			if err := providers.VCS.CommitAndPush(ctx, rc.WorktreePath, rc.Branch, commitMsg); err != nil {
 				return fmt.Errorf("push fix (round %d): %w", round, err)
 			}
 		} else {
-			if err := providers.VCS.AmendAndForcePush(ctx, worktreePath, branch); err != nil {
+			if err := providers.VCS.AmendAndForcePush(ctx, rc.WorktreePath, rc.Branch); err != nil {
 				return fmt.Errorf("push fix (round %d): %w", round, err)
 			}
 		}
@@ -699,6 +779,12 @@ func AgentLogPath(runID string, step int) string {
 	return filepath.Join(".forge/runs", fmt.Sprintf("%s-agent-step%d.log", runID, step))
 }
 
+// AttemptLogPath returns the path of the log kept for attempt (1-based) of a
+// run's step; AgentLogPath holds the latest attempt's.
+func AttemptLogPath(runID string, step, attempt int) string {
+	return filepath.Join(".forge/runs", fmt.Sprintf("%s-agent-step%d-attempt%d.log", runID, step, attempt))
+}
+
 // logWriterAgent is implemented by agent providers that support streaming output.
 type logWriterAgent interface {
 	SetLogWriter(w io.Writer)
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/plan"
 	"github.com/shahar-caura/forge/internal/provider"
 	"github.com/shahar-caura/forge/internal/state"
 	"github.com/stretchr/testify/assert"
@@ -104,7 +105,9 @@ type mockVCS struct {
 	postCommentErr    error
 	amendCalled       bool
 	amendErr          error
-	noChanges         bool // when true, HasChanges returns false
+	noChanges         bool    // when true, HasChanges returns false
+	prErrs            []error // per-call CreatePR errors; takes precedence over prErr
+	createPRCalls     int
 }
 
 func (m *mockVCS) CommitAndPush(_ context.Context, _, _, _ string) error {
@@ -125,6 +128,11 @@ func (m *mockVCS) CreatePR(_ context.Context, _, _, _, body string) (*provider.P
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.prBody = body
+	idx := m.createPRCalls
+	m.createPRCalls++
+	if idx < len(m.prErrs) && m.prErrs[idx] != nil {
+		return nil, m.prErrs[idx]
+	}
 	if m.prErr != nil {
 		return nil, m.prErr
 	}
@@ -170,6 +178,18 @@ func (m *mockVCS) FetchAndRebase(_ context.Context, _, _ string) error {
 	return nil
 }
 
+func (m *mockVCS) FetchBase(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (m *mockVCS) RebaseOnto(_ context.Context, _, _, _, _ string) error {
+	return nil
+}
+
+func (m *mockVCS) UpdatePRBase(_ context.Context, _ int, _ string) error {
+	return nil
+}
+
 func (m *mockVCS) GetIssue(_ context.Context, _ int) (*provider.GitHubIssue, error) {
 	return nil, nil
 }
@@ -284,6 +304,178 @@ func TestRun_PlanNotFound(t *testing.T) {
 	assert.False(t, wt.RemoveCalled())
 }
 
+func TestRun_PlanAgentOverrides(t *testing.T) {
+	wt := &mockWorktree{createPath: t.TempDir()}
+	global := &mockAgent{}
+	override := &mockAgent{}
+	vc := &mockVCS{pr: &provider.PR{URL: "url", Number: 1}}
+	providers := defaultProviders(wt, global, vc)
+
+	var got *plan.Plan
+	var gotName string
+	providers.PlanAgent = func(p *plan.Plan, name string) (provider.Agent, error) {
+		got, gotName = p, name
+		return override, nil
+	}
+
+	planPath := writePlan(t, "---\nagent: codex\nmodel: o3\ntimeout: 10m\n---\nimplement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+	require.NotNil(t, got)
+	assert.Equal(t, "codex", gotName)
+	assert.Equal(t, "codex", got.Agent)
+	assert.Equal(t, "o3", got.Model)
+	assert.Equal(t, 10*time.Minute, got.Timeout)
+	assert.True(t, override.Called(), "plan agent should run the plan")
+	assert.False(t, global.Called(), "global agent should be bypassed")
+}
+
+func TestRun_PlanAgentOverrides_NoFrontmatter(t *testing.T) {
+	wt := &mockWorktree{createPath: t.TempDir()}
+	ag := &mockAgent{}
+	vc := &mockVCS{pr: &provider.PR{URL: "url", Number: 1}}
+	providers := defaultProviders(wt, ag, vc)
+	providers.PlanAgent = func(*plan.Plan, string) (provider.Agent, error) {
+		t.Fatal("plan agent built for a plan without overrides")
+		return nil, nil
+	}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), providers, planPath, rs, testLogger()))
+	assert.True(t, ag.Called())
+}
+
+func TestRun_PlanAgentOverrides_Error(t *testing.T) {
+	wt := &mockWorktree{createPath: t.TempDir()}
+	ag := &mockAgent{}
+	providers := defaultProviders(wt, ag, &mockVCS{})
+	providers.PlanAgent = func(*plan.Plan, string) (provider.Agent, error) {
+		return nil, errors.New(`unrecognized agent "bogus"`)
+	}
+
+	planPath := writePlan(t, "---\nagent: bogus\n---\nimplement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), testConfig(), providers, planPath, rs, testLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plan agent overrides")
+	assert.False(t, ag.Called())
+}
+
+// pooledOverrideRun runs a plan with frontmatter overrides through a batch
+// agent slot on a [claude, codex] pool holding one run per agent.
+func pooledOverrideRun(t *testing.T, frontmatter string, pool *AgentPool) (*fallbackAgent, *mockAgent, error) {
+	t.Helper()
+	pool.SetMaxConcurrent(1)
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok)
+	require.Equal(t, 0, idx)
+	fa := &fallbackAgent{pool: pool, startIdx: idx, held: true, logger: testLogger()}
+
+	override := &mockAgent{}
+	providers := defaultProviders(&mockWorktree{createPath: t.TempDir()}, nil, &mockVCS{pr: &provider.PR{URL: "url", Number: 1}})
+	providers.Agent = fa
+	providers.PlanAgent = func(_ *plan.Plan, name string) (provider.Agent, error) {
+		assert.Equal(t, "codex", name)
+		return override, nil
+	}
+
+	planPath := writePlan(t, "---\n"+frontmatter+"\n---\nimplement auth")
+	err := Run(context.Background(), testConfig(), providers, planPath, newRunState(planPath), testLogger())
+	return fa, override, err
+}
+
+func TestRun_PlanAgentOverrides_RoutedThroughPool(t *testing.T) {
+	claude, codex := &mockAgent{}, &mockAgent{}
+	pool := NewAgentPool([]provider.Agent{claude, codex}, []string{"claude", "codex"})
+
+	fa, override, err := pooledOverrideRun(t, "agent: codex\nmodel: o3", pool)
+
+	require.NoError(t, err)
+	assert.True(t, override.Called(), "plan agent should run the plan")
+	assert.False(t, claude.Called())
+	assert.False(t, codex.Called())
+	assert.Equal(t, 1, fa.startIdx, "run moves to the plan's agent")
+	assert.Positive(t, pool.Health("codex").Successes, "override calls count toward the agent's health")
+
+	// The run's slot moved from claude to codex.
+	idx, ok := pool.TryAcquire()
+	require.True(t, ok)
+	assert.Equal(t, 0, idx)
+	_, ok = pool.TryAcquire()
+	assert.False(t, ok, "codex is at batch.max_per_agent")
+}
+
+func TestRun_PlanAgentOverrides_PoolAgentBusy(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+	pool.SetMaxConcurrent(1)
+	pool.next = 1
+	busy, ok := pool.TryAcquire()
+	require.True(t, ok)
+	require.Equal(t, 1, busy)
+
+	_, override, err := pooledOverrideRun(t, "agent: codex", pool)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent codex is at batch.max_per_agent")
+	assert.False(t, override.Called())
+}
+
+func TestRun_PlanAgentOverrides_AgentNotInPool(t *testing.T) {
+	pool := NewAgentPool([]provider.Agent{&mockAgent{}, &mockAgent{}}, []string{"claude", "codex"})
+
+	_, override, err := pooledOverrideRun(t, "agent: gemini", pool)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `agent "gemini" is not in agent.providers`)
+	assert.False(t, override.Called())
+}
+
+func TestRun_ResumeFromCreatePR_AdoptsExistingPR(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: t.TempDir()}
+	ag := &mockAgent{}
+	// gh failed after GitHub had already opened the PR.
+	vc := &mockVCS{prErr: errors.New("gh pr create: signal: killed")}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), testConfig(), defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+	require.Error(t, err)
+	idx, _ := rs.StepIndex("create pr")
+	require.Equal(t, state.StepFailed, rs.Steps[idx].Status)
+	for i := range idx {
+		require.Equal(t, state.StepCompleted, rs.Steps[i].Status, rs.Steps[i].Name)
+	}
+
+	// On resume, the VCS finds the PR already open for the branch and adopts it.
+	vc.prErr = nil
+	vc.pr = &provider.PR{URL: "https://github.com/owner/repo/pull/5", Number: 5, Existing: true}
+	rs.ResetFailed()
+	require.NoError(t, Run(context.Background(), testConfig(), defaultProviders(wt, ag, vc), planPath, rs, testLogger()))
+
+	assert.Equal(t, 5, rs.PRNumber)
+	assert.Equal(t, "https://github.com/owner/repo/pull/5", rs.PRUrl)
+	assert.Equal(t, 2, vc.createPRCalls)
+	assert.Equal(t, 1, ag.callCount, "steps before create pr are not re-run")
+	assert.Len(t, rs.Steps[idx].Attempts, 2)
+
+	events, err := state.ReadEvents(rs.ID)
+	require.NoError(t, err)
+	var created []state.Event
+	for _, e := range events {
+		if e.Type == state.EventPRCreated {
+			created = append(created, e)
+		}
+	}
+	require.Len(t, created, 1)
+	assert.Equal(t, true, created[0].Data["existing"])
+}
+
 func TestRun_WorktreeCreateFails(t *testing.T) {
 	wt := &mockWorktree{createErr: errors.New("worktree failed")}
 	ag := &mockAgent{}
@@ -547,6 +739,33 @@ func TestRun_ResumeAfterAgentFailure(t *testing.T) {
 	assert.Equal(t, state.RunCompleted, rs.Status)
 }
 
+func TestRun_RecordsAttemptsAcrossResume(t *testing.T) {
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	ag := &mockAgent{err: errors.New("agent crashed")}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), testConfig(), defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+	require.Error(t, err)
+
+	ag.err = nil
+	rs.ResetFailed()
+	err = Run(context.Background(), testConfig(), defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+	require.NoError(t, err)
+
+	step := rs.Steps[4]
+	require.Len(t, step.Attempts, 2)
+	assert.Contains(t, step.Attempts[0].Error, "agent crashed")
+	assert.False(t, step.Attempts[0].FinishedAt.IsZero())
+	assert.Empty(t, step.Attempts[1].Error)
+	assert.Equal(t, step.Attempts[1].StartedAt, step.StartedAt)
+	assert.Equal(t, step.FinishedAt.Sub(step.StartedAt), step.Duration)
+
+	require.Len(t, rs.Steps[0].Attempts, 1, "completed steps are not re-run")
+}
+
 func TestRun_WorktreePreservedOnFailure(t *testing.T) {
 	wt := &mockWorktree{createPath: "/tmp/wt"}
 	ag := &mockAgent{err: errors.New("agent crashed")}
@@ -900,40 +1119,6 @@ func TestRun_CRLoop_Resume(t *testing.T) {
 
 // --- CR summary extraction tests ---
 
-func TestAgentResultText(t *testing.T) {
-	tests := []struct {
-		name  string
-		input string
-		want  string
-	}{
-		{
-			name:  "valid JSON with result",
-			input: `{"result": "hello world"}`,
-			want:  "hello world",
-		},
-		{
-			name:  "empty result field",
-			input: `{"result": ""}`,
-			want:  `{"result": ""}`,
-		},
-		{
-			name:  "non-JSON input",
-			input: "just plain text",
-			want:  "just plain text",
-		},
-		{
-			name:  "missing result field",
-			input: `{"other": "value"}`,
-			want:  `{"other": "value"}`,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, agentResultText(tt.input))
-		})
-	}
-}
-
 func TestExtractCRSummary(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -941,18 +1126,18 @@ func TestExtractCRSummary(t *testing.T) {
 		want  string
 	}{
 		{
-			name:  "valid markers in JSON",
-			input: `{"result": "some text\n---CRSUMMARY---\nFixed the auth bug.\n---CRSUMMARY---\nmore text"}`,
+			name:  "valid markers",
+			input: "some text\n---CRSUMMARY---\nFixed the auth bug.\n---CRSUMMARY---\nmore text",
 			want:  "Fixed the auth bug.",
 		},
 		{
 			name:  "no markers",
-			input: `{"result": "just fixed stuff"}`,
+			input: "just fixed stuff",
 			want:  "",
 		},
 		{
 			name:  "single marker only",
-			input: `{"result": "---CRSUMMARY---\nno closing marker"}`,
+			input: "---CRSUMMARY---\nno closing marker",
 			want:  "",
 		},
 		{
@@ -1307,11 +1492,6 @@ func TestExtractReviewFeedback(t *testing.T) {
 			input: "---CRREVIEW---\n\n---CRREVIEW---",
 			want:  "",
 		},
-		{
-			name:  "JSON wrapped",
-			input: `{"result": "text\n---CRREVIEW---\n### Issue\n---CRREVIEW---\nend"}`,
-			want:  "### Issue",
-		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1348,3 +1528,43 @@ func TestBuildReviewPrompt(t *testing.T) {
 	assert.Contains(t, prompt, "NO_ISSUES")
 	assert.Contains(t, prompt, "do NOT modify any files")
 }
+
+func TestRun_RefusesLockedRun(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	ag := &mockAgent{}
+	vc := &mockVCS{}
+
+	planPath := writePlan(t, "plan")
+	rs := newRunState(planPath)
+	require.NoError(t, rs.Save())
+
+	lock, err := state.AcquireLock(rs.ID)
+	require.NoError(t, err)
+	defer lock.Release()
+
+	err = Run(context.Background(), testConfig(), defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+
+	var locked *state.LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.False(t, ag.Called(), "locked run must not execute")
+
+	onDisk, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.RunActive, onDisk.Status, "a refused run is not marked failed")
+}
+
+func TestRun_ReleasesLock(t *testing.T) {
+	chdirTemp(t)
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	vc := &mockVCS{pr: &provider.PR{URL: "url", Number: 1}}
+
+	planPath := writePlan(t, "plan")
+	rs := newRunState(planPath)
+
+	require.NoError(t, Run(context.Background(), testConfig(), defaultProviders(wt, &mockAgent{}, vc), planPath, rs, testLogger()))
+
+	owner, err := state.ReadLock(rs.ID)
+	require.NoError(t, err)
+	assert.Nil(t, owner)
+}
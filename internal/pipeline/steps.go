@@ -0,0 +1,409 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+)
+
+// Step is a single checkpointed unit of pipeline work. Completed steps are
+// recorded by name in state.RunState and skipped on resume.
+type Step interface {
+	Name() string
+	Run(ctx context.Context, rc *RunContext) error
+}
+
+// resumer is implemented by steps that must restore in-memory artifacts
+// (plan body, worktree) when they are skipped on resume.
+type resumer interface {
+	Resume(ctx context.Context, rc *RunContext) error
+}
+
+// RunContext carries config, providers, and artifacts accumulated across steps.
+type RunContext struct {
+	Config    *config.Config
+	Providers Providers
+	State     *state.RunState
+	Logger    *slog.Logger
+
+	PlanPath     string
+	PlanBody     string
+	PlanTitle    string
+	DisplayTitle string
+	Branch       string
+	WorktreePath string
+
+	// Push holds the push-mode options; zero for plan runs.
+	Push PushOpts
+}
+
+// StepIndex returns the position of the named step in the run's state,
+// used for per-step agent log paths. Returns -1 if the step is not in the run.
+func (rc *RunContext) StepIndex(name string) int {
+	idx, _ := rc.State.StepIndex(name)
+	return idx
+}
+
+// stepFunc adapts plain functions to the Step interface.
+type stepFunc struct {
+	name       string
+	run        func(ctx context.Context, rc *RunContext) error
+	resume     func(ctx context.Context, rc *RunContext) error
+	idempotent bool // safe to rerun after a transient failure (see retryPolicy)
+}
+
+func (s stepFunc) Name() string { return s.name }
+
+func (s stepFunc) Idempotent() bool { return s.idempotent }
+
+func (s stepFunc) Run(ctx context.Context, rc *RunContext) error { return s.run(ctx, rc) }
+
+func (s stepFunc) Resume(ctx context.Context, rc *RunContext) error {
+	if s.resume == nil {
+		return nil
+	}
+	return s.resume(ctx, rc)
+}
+
+// StepFactory builds a step for a run with the given config.
+type StepFactory func(cfg *config.Config) (Step, error)
+
+var (
+	registryMu   sync.RWMutex
+	stepRegistry = map[string]StepFactory{}
+)
+
+// RegisterStep makes a step available to pipeline.steps under name.
+// Registering an existing name replaces it.
+func RegisterStep(name string, f StepFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	stepRegistry[state.NormalizeStepName(name)] = f
+}
+
+// RegisteredSteps returns the sorted names of all registered steps.
+func RegisteredSteps() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(stepRegistry))
+	for name := range stepRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requiredSteps produce artifacts every other step relies on.
+var requiredSteps = []string{"read plan", "generate branch", "create worktree"}
+
+// BuildSteps resolves the configured step sequence for a plan run.
+// Uses pipeline.steps when set, otherwise the default state.StepNames order.
+// Custom steps from the top-level steps list are placed where pipeline.steps
+// names them, or else inserted after their "after" step; either way they must
+// come after "create worktree".
+func BuildSteps(cfg *config.Config) ([]Step, error) {
+	names, err := stepSequence(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := make(map[string]config.StepConfig, len(cfg.Steps))
+	for _, sc := range cfg.Steps {
+		custom[state.NormalizeStepName(sc.Name)] = sc
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	seen := make(map[string]bool, len(names))
+	steps := make([]Step, 0, len(names))
+	var early []string // custom steps placed before the worktree exists
+	for _, name := range names {
+		key := state.NormalizeStepName(name)
+		if seen[key] {
+			return nil, fmt.Errorf("pipeline.steps: duplicate step %q", name)
+		}
+		seen[key] = true
+
+		if sc, ok := custom[key]; ok {
+			if !seen["create worktree"] {
+				early = append(early, sc.Name)
+			}
+			steps = append(steps, shellStep{cfg: sc})
+			continue
+		}
+		factory, ok := stepRegistry[key]
+		if !ok {
+			return nil, fmt.Errorf("pipeline.steps: unknown step %q", name)
+		}
+		step, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline.steps: building %q: %w", name, err)
+		}
+		steps = append(steps, step)
+	}
+
+	var missing []string
+	for _, name := range requiredSteps {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("pipeline.steps: missing required steps: %s", strings.Join(missing, ", "))
+	}
+	if len(early) > 0 {
+		return nil, fmt.Errorf(`steps: %s must run after "create worktree" (custom steps run in the worktree)`, strings.Join(early, ", "))
+	}
+
+	return steps, nil
+}
+
+// stepSequence returns the ordered step names, with custom steps not already
+// listed in pipeline.steps inserted after their anchor step.
+func stepSequence(cfg *config.Config) ([]string, error) {
+	names := cfg.Pipeline.Steps
+	if len(names) == 0 {
+		names = state.StepNames
+	}
+	names = append([]string(nil), names...)
+
+	indexOf := func(name string) int {
+		key := state.NormalizeStepName(name)
+		for i, n := range names {
+			if state.NormalizeStepName(n) == key {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// lastAfter keeps custom steps sharing an anchor in config order.
+	lastAfter := make(map[string]string)
+	for _, sc := range cfg.Steps {
+		registryMu.RLock()
+		_, builtin := stepRegistry[state.NormalizeStepName(sc.Name)]
+		registryMu.RUnlock()
+		if builtin {
+			return nil, fmt.Errorf("steps: %q conflicts with a built-in step", sc.Name)
+		}
+		if indexOf(sc.Name) >= 0 {
+			continue
+		}
+		if sc.After == "" {
+			names = append(names, sc.Name)
+			continue
+		}
+		anchorKey := state.NormalizeStepName(sc.After)
+		anchor := sc.After
+		if prev, ok := lastAfter[anchorKey]; ok {
+			anchor = prev
+		}
+		idx := indexOf(anchor)
+		if idx < 0 {
+			return nil, fmt.Errorf("steps: %q runs after %q, which is not in the pipeline", sc.Name, sc.After)
+		}
+		names = append(names[:idx+1], append([]string{sc.Name}, names[idx+1:]...)...)
+		lastAfter[anchorKey] = sc.Name
+	}
+	return names, nil
+}
+
+// StepNames returns the names of steps in order.
+func StepNames(steps []Step) []string {
+	names := make([]string, len(steps))
+	for i, s := range steps {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// builtin wraps a step that needs no config into a StepFactory.
+func builtin(s Step) StepFactory {
+	return func(*config.Config) (Step, error) { return s, nil }
+}
+
+// shellStep runs a user-defined command from the steps config in the worktree.
+// Output goes to the step's log file (forge logs --step N).
+type shellStep struct {
+	cfg config.StepConfig
+}
+
+func (s shellStep) Name() string { return s.cfg.Name }
+
+func (s shellStep) Idempotent() bool { return s.cfg.Idempotent }
+
+func (s shellStep) Run(ctx context.Context, rc *RunContext) error {
+	if rc.WorktreePath == "" {
+		return fmt.Errorf("step %q: no worktree to run in", s.cfg.Name)
+	}
+
+	var out io.Writer
+	logPath := AgentLogPath(rc.State.ID, rc.StepIndex(s.cfg.Name))
+	if f, err := os.Create(logPath); err != nil {
+		rc.Logger.Warn("failed to open step log file", "path", logPath, "error", err)
+	} else {
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	label := fmt.Sprintf("step %q", s.cfg.Name)
+	var err error
+	if s.cfg.OnFail == "agent-fix" {
+		err = runCommandWithRetry(ctx, label, s.cfg.Run, rc.WorktreePath, out, rc.Providers.Agent, rc.Config.Hooks.MaxHookRetries, rc.Logger)
+	} else {
+		err = runCommand(ctx, label, s.cfg.Run, rc.WorktreePath, out, rc.Logger)
+	}
+	if err != nil && s.cfg.OnFail == "warn" {
+		rc.Logger.Warn("custom step failed, continuing", "step", s.cfg.Name, "error", err)
+		return nil
+	}
+	return err
+}
+
+func init() {
+	for _, s := range runSteps {
+		RegisterStep(s.Name(), builtin(s))
+	}
+}
+
+// executeSteps runs steps in order, stopping at the first failure.
+// rc.State.Steps must already be synced to the step names.
+func executeSteps(ctx context.Context, rc *RunContext, steps []Step) error {
+	for _, s := range steps {
+		if err := runStep(ctx, rc, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep executes s, skipping it if already completed (restoring its artifacts via Resume).
+// It persists state transitions: pending → running → completed/failed.
+func runStep(ctx context.Context, rc *RunContext, s Step) error {
+	rs := rc.State
+	idx, ok := rs.StepIndex(s.Name())
+	if !ok {
+		rs.Status = state.RunFailed
+		_ = rs.Save()
+		return fmt.Errorf("step %q is not recorded in run state", s.Name())
+	}
+	step := &rs.Steps[idx]
+
+	if step.Status == state.StepCompleted {
+		rc.Logger.Info("skipping completed step", "step", step.Name)
+		if r, ok := s.(resumer); ok {
+			if err := r.Resume(ctx, rc); err != nil {
+				rs.Status = state.RunFailed
+				_ = rs.Save()
+				return fmt.Errorf("step %d (%s): %w", idx+1, step.Name, err)
+			}
+		}
+		return nil
+	}
+
+	ctx = withJournalStep(ctx, step.Name)
+	ctx = provider.WithRunInfo(ctx, provider.RunInfo{RunID: rs.ID, Step: step.Name})
+
+	policy := newRetryPolicy(rc.Config)
+	for attempt := 1; ; attempt++ {
+		err := runAttempt(ctx, rc, s, idx)
+		if err == nil {
+			_ = rs.Save()
+			return nil
+		}
+		if !policy.shouldRetry(ctx, s, err, attempt) {
+			rs.Status = state.RunFailed
+			_ = rs.Save()
+			return fmt.Errorf("step %d (%s): %w", idx+1, step.Name, err)
+		}
+
+		wait := policy.backoff(attempt)
+		rc.Logger.Warn("step failed with transient error, retrying",
+			"step", step.Name, "attempt", attempt, "max_attempts", policy.maxAttempts, "wait", wait.Round(time.Millisecond), "error", err)
+		record(ctx, state.EventStepRetrying, map[string]any{"attempt": attempt, "wait_ms": wait.Milliseconds(), "error": err.Error()})
+		if sleepErr := sleepCtx(ctx, wait); sleepErr != nil {
+			rs.Status = state.RunFailed
+			_ = rs.Save()
+			return fmt.Errorf("step %d (%s): %w", idx+1, step.Name, err)
+		}
+	}
+}
+
+// runAttempt runs one attempt of s, the step at idx, recording it in the run
+// state and journal.
+func runAttempt(ctx context.Context, rc *RunContext, s Step, idx int) error {
+	rs := rc.State
+	step := &rs.Steps[idx]
+
+	// Drop the previous attempt's step log (already kept in its attempt file),
+	// so a log present afterwards was written by this attempt.
+	_ = os.Remove(AgentLogPath(rs.ID, idx))
+
+	step.BeginAttempt(time.Now())
+	_ = rs.Save()
+	record(ctx, state.EventStepStarted, map[string]any{"attempt": len(step.Attempts)})
+
+	meter := &agentMeter{rs: rs, step: step}
+	if rc.Config != nil {
+		meter.budget = rc.Config.Agent.Budget
+	}
+	err := s.Run(withMeter(ctx, meter), rc)
+	step.EndAttempt(time.Now(), err, keepAttemptLog(rs.ID, idx, len(step.Attempts), rc.Logger))
+	finished := map[string]any{"status": string(step.Status), "duration_ms": step.Duration.Milliseconds()}
+	if err != nil {
+		finished["error"] = step.Error
+	}
+	if usage := meter.usage; !usage.IsZero() {
+		finished["agent_calls"] = usage.Calls
+		finished["tokens"] = usage.Tokens()
+		finished["cost_usd"] = usage.CostUSD
+	}
+	record(ctx, state.EventStepFinished, finished)
+	return err
+}
+
+// keepAttemptLog copies the step's log file, written by the attempt that just
+// ran, to attempt's own file so the next attempt can't overwrite it, and
+// returns that path; "" for steps that produce no log. The step's log file
+// keeps the latest attempt for forge logs --step.
+func keepAttemptLog(runID string, idx, attempt int, logger *slog.Logger) string {
+	path := AgentLogPath(runID, idx)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	dest := AttemptLogPath(runID, idx, attempt)
+	if err := copyFile(path, dest); err != nil {
+		logger.Warn("failed to keep attempt log", "path", dest, "error", err)
+		return path
+	}
+	return dest
+}
+
+// copyFile copies src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
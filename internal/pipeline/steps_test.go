@@ -0,0 +1,331 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/config"
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/shahar-caura/forge/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSteps_DefaultSequence(t *testing.T) {
+	steps, err := BuildSteps(testConfig())
+
+	require.NoError(t, err)
+	assert.Equal(t, state.StepNames, StepNames(steps))
+}
+
+func TestBuildSteps_CustomOrderAndRemoval(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read-plan", "generate branch", "create worktree", "run agent", "commit and push"}
+
+	steps, err := BuildSteps(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read plan", "generate branch", "create worktree", "run agent", "commit and push"}, StepNames(steps))
+}
+
+func TestBuildSteps_UnknownStep(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "generate branch", "create worktree", "deploy"}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown step "deploy"`)
+}
+
+func TestBuildSteps_DuplicateStep(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "generate branch", "create worktree", "Read Plan"}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate step")
+}
+
+func TestBuildSteps_MissingRequiredStep(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "run agent"}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "generate branch, create worktree")
+}
+
+func TestRegisterStep_CustomStepRuns(t *testing.T) {
+	var gotBranch string
+	RegisterStep("record branch", func(*config.Config) (Step, error) {
+		return stepFunc{name: "record branch", run: func(_ context.Context, rc *RunContext) error {
+			gotBranch = rc.Branch
+			return nil
+		}}, nil
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(stepRegistry, "record branch")
+		registryMu.Unlock()
+	})
+	assert.Contains(t, RegisteredSteps(), "record branch")
+
+	wt := &mockWorktree{createPath: "/tmp/wt"}
+	ag := &mockAgent{}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "generate branch", "create worktree", "record branch", "run agent", "commit and push"}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+
+	err := Run(context.Background(), cfg, defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, "forge/auth", gotBranch)
+	assert.Equal(t, state.RunCompleted, rs.Status)
+	require.Len(t, rs.Steps, 6)
+	assert.Equal(t, "record branch", rs.Steps[3].Name)
+	for _, step := range rs.Steps {
+		assert.Equal(t, state.StepCompleted, step.Status, "step %q should be completed", step.Name)
+	}
+	assert.Empty(t, vc.prBody, "create pr was removed from the pipeline")
+}
+
+func TestRun_ReorderedSteps_ResumeSkipsCompletedByName(t *testing.T) {
+	wt := &mockWorktree{createPath: t.TempDir()}
+	ag := &mockAgent{}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "generate branch", "create worktree", "run agent", "commit and push", "create pr"}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+	// Simulate a run on the default pipeline that completed through "run agent".
+	for i := 0; i <= 4; i++ {
+		rs.Steps[i].Status = state.StepCompleted
+	}
+	rs.Branch = "forge/auth"
+	rs.WorktreePath = wt.createPath
+
+	err := Run(context.Background(), cfg, defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+
+	require.NoError(t, err)
+	assert.False(t, ag.Called(), "completed run agent step should be skipped")
+	assert.True(t, vc.commitCalled)
+	assert.Equal(t, []string{"read plan", "generate branch", "create worktree", "run agent", "commit and push", "create pr"}, stepNamesOf(rs))
+}
+
+func stepNamesOf(rs *state.RunState) []string {
+	names := make([]string, len(rs.Steps))
+	for i, s := range rs.Steps {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestRunStep_UnrecordedStepSavesFailure(t *testing.T) {
+	chdirTemp(t)
+	rs := state.New("20260217-120000-test", "plans/auth.md")
+	require.NoError(t, rs.Save())
+	rc := &RunContext{State: rs, Config: testConfig(), Logger: testLogger()}
+
+	err := runStep(context.Background(), rc, shellStep{cfg: config.StepConfig{Name: "lint-sql", Run: "true"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step "lint-sql" is not recorded in run state`)
+	saved, err := state.Load(rs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.RunFailed, saved.Status)
+}
+
+// --- Custom steps ---
+
+func TestBuildSteps_CustomStepInsertedAfterAnchor(t *testing.T) {
+	cfg := testConfig()
+	cfg.Steps = []config.StepConfig{
+		{Name: "lint-sql", After: "run agent", Run: "true", OnFail: "fail"},
+		{Name: "gen-docs", After: "run agent", Run: "true", OnFail: "fail"},
+		{Name: "smoke", Run: "true", OnFail: "warn"},
+	}
+
+	steps, err := BuildSteps(cfg)
+
+	require.NoError(t, err)
+	names := StepNames(steps)
+	require.Len(t, names, 14)
+	assert.Equal(t, []string{"run agent", "lint-sql", "gen-docs", "commit and push"}, names[4:8])
+	assert.Equal(t, "smoke", names[13])
+}
+
+func TestBuildSteps_CustomStepPlacedByPipelineSteps(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "generate branch", "create worktree", "lint-sql", "run agent"}
+	cfg.Steps = []config.StepConfig{{Name: "lint-sql", After: "run agent", Run: "true", OnFail: "fail"}}
+
+	steps, err := BuildSteps(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read plan", "generate branch", "create worktree", "lint-sql", "run agent"}, StepNames(steps))
+}
+
+func TestBuildSteps_CustomStepUnknownAnchor(t *testing.T) {
+	cfg := testConfig()
+	cfg.Steps = []config.StepConfig{{Name: "lint-sql", After: "deploy", Run: "true", OnFail: "fail"}}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"lint-sql" runs after "deploy"`)
+}
+
+func TestBuildSteps_CustomStepBeforeWorktreeRejected(t *testing.T) {
+	for _, anchor := range []string{"read plan", "generate branch"} {
+		cfg := testConfig()
+		cfg.Steps = []config.StepConfig{{Name: "lint-sql", After: anchor, Run: "true", OnFail: "fail"}}
+
+		_, err := BuildSteps(cfg)
+
+		require.Error(t, err, anchor)
+		assert.Contains(t, err.Error(), `lint-sql must run after "create worktree"`)
+	}
+}
+
+func TestBuildSteps_CustomStepListedBeforeWorktreeRejected(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipeline.Steps = []string{"read plan", "lint-sql", "generate branch", "create worktree", "run agent"}
+	cfg.Steps = []config.StepConfig{{Name: "lint-sql", Run: "true", OnFail: "fail"}}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `lint-sql must run after "create worktree"`)
+}
+
+func TestShellStep_NoWorktreeFails(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	rc := &RunContext{State: state.New("run-1", "plan.md"), Config: testConfig(), Logger: testLogger()}
+
+	err := shellStep{cfg: config.StepConfig{Name: "touch", Run: "touch " + marker, OnFail: "warn"}}.Run(context.Background(), rc)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no worktree")
+	assert.NoFileExists(t, marker)
+}
+
+func TestBuildSteps_CustomStepConflictsWithBuiltin(t *testing.T) {
+	cfg := testConfig()
+	cfg.Steps = []config.StepConfig{{Name: "create-pr", Run: "true", OnFail: "fail"}}
+
+	_, err := BuildSteps(cfg)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicts with a built-in step")
+}
+
+func customStepRun(t *testing.T, sc config.StepConfig, ag *mockAgent) (*state.RunState, error) {
+	t.Helper()
+	wt := &mockWorktree{createPath: t.TempDir()}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+	cfg := testConfig()
+	cfg.Hooks.MaxHookRetries = 2
+	sc.After = "run agent"
+	cfg.Steps = []config.StepConfig{sc}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+	t.Cleanup(func() {
+		_ = os.Remove(AgentLogPath(rs.ID, 5))
+		_ = os.Remove(AttemptLogPath(rs.ID, 5, 1))
+	})
+
+	err := Run(context.Background(), cfg, defaultProviders(wt, ag, vc), planPath, rs, testLogger())
+	return rs, err
+}
+
+func TestRun_CustomStep_OutputLoggedAndCheckpointed(t *testing.T) {
+	rs, err := customStepRun(t, config.StepConfig{Name: "lint-sql", Run: "echo sql ok", OnFail: "fail"}, &mockAgent{})
+
+	require.NoError(t, err)
+	require.Len(t, rs.Steps, 12)
+	step := rs.Steps[5]
+	assert.Equal(t, "lint-sql", step.Name)
+	assert.Equal(t, state.StepCompleted, step.Status)
+	require.Len(t, step.Attempts, 1)
+	assert.Equal(t, AttemptLogPath(rs.ID, 5, 1), step.Attempts[0].LogPath)
+
+	data, err := os.ReadFile(AgentLogPath(rs.ID, 5))
+	require.NoError(t, err)
+	assert.Equal(t, "sql ok\n", string(data))
+}
+
+func TestRun_CustomStep_AttemptsKeepSeparateLogs(t *testing.T) {
+	chdirTemp(t)
+	counter := filepath.Join(t.TempDir(), "count")
+	cfg := testConfig()
+	cfg.Retry = config.RetryConfig{MaxAttempts: 2, InitialBackoff: config.Duration{Duration: time.Millisecond}, Multiplier: 1}
+	cfg.Steps = []config.StepConfig{{
+		Name:       "flaky",
+		After:      "run agent",
+		Run:        "echo x >> " + counter + "; n=$(wc -l < " + counter + "); echo attempt $n; [ $n -gt 1 ] || { echo connection reset; exit 1; }",
+		OnFail:     "fail",
+		Idempotent: true,
+	}}
+	vc := &mockVCS{pr: &provider.PR{URL: "https://github.com/owner/repo/pull/1", Number: 1}}
+
+	planPath := writePlan(t, "implement auth")
+	rs := newRunState(planPath)
+	require.NoError(t, Run(context.Background(), cfg, defaultProviders(&mockWorktree{createPath: t.TempDir()}, &mockAgent{}, vc), planPath, rs, testLogger()))
+
+	attempts := rs.Steps[5].Attempts
+	require.Len(t, attempts, 2)
+	require.NotEqual(t, attempts[0].LogPath, attempts[1].LogPath)
+	first, err := os.ReadFile(attempts[0].LogPath)
+	require.NoError(t, err)
+	assert.Equal(t, "attempt 1\nconnection reset\n", string(first))
+	second, err := os.ReadFile(attempts[1].LogPath)
+	require.NoError(t, err)
+	assert.Equal(t, "attempt 2\n", string(second))
+
+	latest, err := os.ReadFile(AgentLogPath(rs.ID, 5))
+	require.NoError(t, err)
+	assert.Equal(t, string(second), string(latest), "forge logs --step shows the latest attempt")
+}
+
+func TestRun_CustomStep_FailStopsPipeline(t *testing.T) {
+	rs, err := customStepRun(t, config.StepConfig{Name: "lint-sql", Run: "echo bad query; exit 1", OnFail: "fail"}, &mockAgent{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step 6 (lint-sql)")
+	assert.Contains(t, err.Error(), "bad query")
+	assert.Equal(t, state.StepFailed, rs.Steps[5].Status)
+	assert.Equal(t, state.StepPending, rs.Steps[6].Status)
+}
+
+func TestRun_CustomStep_WarnContinues(t *testing.T) {
+	rs, err := customStepRun(t, config.StepConfig{Name: "lint-sql", Run: "exit 1", OnFail: "warn"}, &mockAgent{})
+
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCompleted, rs.Status)
+	assert.Equal(t, state.StepCompleted, rs.Steps[5].Status)
+}
+
+func TestRun_CustomStep_AgentFix(t *testing.T) {
+	ag := &mockAgent{}
+	// Fails until the agent has been prompted to fix it (second agent call).
+	sc := config.StepConfig{Name: "lint-sql", Run: "test -f fixed || { touch fixed; exit 1; }", OnFail: "agent-fix"}
+
+	rs, err := customStepRun(t, sc, ag)
+
+	require.NoError(t, err)
+	assert.Equal(t, state.StepCompleted, rs.Steps[5].Status)
+	require.Len(t, ag.prompts, 2, "agent runs the plan, then fixes the custom step")
+	assert.Contains(t, ag.prompts[1], `step "lint-sql" failed`)
+}
@@ -3,14 +3,30 @@ package plan
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Plan holds parsed frontmatter and body from a plan file.
 type Plan struct {
-	Title string `yaml:"title"`
-	Body  string // everything after closing ---
+	Title     string   `yaml:"title"`
+	ID        string   `yaml:"id"`         // identifier other plans reference in depends_on
+	DependsOn []string `yaml:"depends_on"` // plan IDs that must complete first
+
+	// Agent overrides for this plan's run; empty fields keep forge.yaml's agent settings.
+	Agent        string            `yaml:"agent"`         // agent provider, e.g. codex
+	Model        string            `yaml:"model"`         // model passed to the agent CLI
+	Timeout      time.Duration     `yaml:"timeout"`       // e.g. 20m
+	AllowedTools string            `yaml:"allowed_tools"` // claude/ralph tool allowlist
+	Env          map[string]string `yaml:"env"`           // extra agent process environment
+
+	Body string // everything after closing ---
+}
+
+// HasAgentOverrides reports whether the frontmatter overrides any agent setting.
+func (p *Plan) HasAgentOverrides() bool {
+	return p.Agent != "" || p.Model != "" || p.Timeout != 0 || p.AllowedTools != "" || len(p.Env) > 0
 }
 
 // Parse extracts YAML frontmatter from a plan file.
@@ -2,6 +2,7 @@ package plan
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -69,3 +70,48 @@ func TestParse_MultilineBody(t *testing.T) {
 	assert.Equal(t, "Multi", p.Title)
 	assert.Equal(t, "Line 1\nLine 2\nLine 3", p.Body)
 }
+
+func TestParse_IDAndDependsOn(t *testing.T) {
+	content := "---\ntitle: API\nid: api\ndepends_on: [db, auth]\n---\nBuild the API"
+	p, err := Parse(content)
+	require.NoError(t, err)
+	assert.Equal(t, "api", p.ID)
+	assert.Equal(t, []string{"db", "auth"}, p.DependsOn)
+	assert.Equal(t, "Build the API", p.Body)
+}
+
+func TestParse_AgentOverrides(t *testing.T) {
+	content := `---
+title: Boilerplate
+agent: claude
+model: minimax/minimax-m2.5
+timeout: 20m
+allowed_tools: Edit,Read,Write
+env:
+  ANTHROPIC_BASE_URL: https://openrouter.ai/api
+  ANTHROPIC_API_KEY: ""
+---
+Generate CRUD handlers`
+	p, err := Parse(content)
+	require.NoError(t, err)
+
+	assert.True(t, p.HasAgentOverrides())
+	assert.Equal(t, "claude", p.Agent)
+	assert.Equal(t, "minimax/minimax-m2.5", p.Model)
+	assert.Equal(t, 20*time.Minute, p.Timeout)
+	assert.Equal(t, "Edit,Read,Write", p.AllowedTools)
+	assert.Equal(t, map[string]string{"ANTHROPIC_BASE_URL": "https://openrouter.ai/api", "ANTHROPIC_API_KEY": ""}, p.Env)
+	assert.Equal(t, "Generate CRUD handlers", p.Body)
+}
+
+func TestParse_NoAgentOverrides(t *testing.T) {
+	p, err := Parse("---\ntitle: Plain\n---\nBody")
+	require.NoError(t, err)
+	assert.False(t, p.HasAgentOverrides())
+}
+
+func TestParse_InvalidTimeout(t *testing.T) {
+	_, err := Parse("---\ntimeout: soon\n---\nBody")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parsing frontmatter")
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxAiderFiles caps how many prompt-mentioned files are added to aider's chat.
+const maxAiderFiles = 20
+
+// Aider implements provider.Agent using the aider CLI.
+type Aider struct {
+	Timeout time.Duration
+	Logger  *slog.Logger
+	Model   string   // --model; empty uses aider's configured default
+	Env     []string // extra KEY=VALUE entries for the agent process
+
+	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
+	LogWriter io.Writer
+
+	// commandContext is overridable for testing.
+	commandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+// NewAider creates a new Aider agent provider.
+func NewAider(timeout time.Duration, logger *slog.Logger) *Aider {
+	return &Aider{
+		Timeout:        timeout,
+		Logger:         logger,
+		commandContext: exec.CommandContext,
+	}
+}
+
+// SetLogWriter sets the writer that receives a real-time copy of agent output.
+func (a *Aider) SetLogWriter(w io.Writer) { a.LogWriter = w }
+
+// ClearLogWriter removes the streaming log writer.
+func (a *Aider) ClearLogWriter() { a.LogWriter = nil }
+
+func (a *Aider) PromptSuffix() string { return "" }
+
+// Run sends the prompt as a single aider message. Files the prompt mentions
+// are added to the chat up front; aider finds any others through its repo map,
+// and --yes-always lets it add them. Forge commits, so aider's own commits are
+// disabled.
+func (a *Aider) Run(ctx context.Context, dir, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+
+	files := aiderFiles(dir, prompt)
+	a.Logger.Info("running aider agent", "dir", dir, "files", len(files), "timeout", a.Timeout)
+
+	args := []string{
+		"--message", prompt,
+		"--yes-always",
+		"--no-auto-commits",
+		"--no-dirty-commits",
+		"--no-pretty",
+		"--no-check-update",
+		"--no-show-model-warnings",
+	}
+	if a.Model != "" {
+		args = append(args, "--model", a.Model)
+	}
+	args = append(args, files...)
+
+	cmd := a.commandContext(ctx, "aider", args...)
+	cmd.Dir = dir
+	cmd.Env = processEnv(a.Env)
+
+	out, err := runProcess(ctx, cmd, a.LogWriter, a.Timeout)
+	if err != nil {
+		return out, err
+	}
+
+	a.Logger.Info("aider agent completed")
+	return out, nil
+}
+
+// pathPattern matches path-like words with an extension, e.g. internal/auth.go.
+var pathPattern = regexp.MustCompile(`[\w.\-/]*\w\.\w+`)
+
+// aiderFiles returns the files mentioned in prompt that exist in dir, in
+// order of first mention, relative to dir.
+func aiderFiles(dir, prompt string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, m := range pathPattern.FindAllString(prompt, -1) {
+		rel := filepath.Clean(strings.TrimPrefix(m, "./"))
+		if seen[rel] || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		seen[rel] = true
+		if info, err := os.Stat(filepath.Join(dir, rel)); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, rel)
+		if len(files) == maxAiderFiles {
+			break
+		}
+	}
+	return files
+}
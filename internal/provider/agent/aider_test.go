@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAider_Run_Success(t *testing.T) {
+	a := NewAider(5*time.Minute, testLogger())
+	a.commandContext = stubCommand(0, "")
+
+	_, err := a.Run(context.Background(), t.TempDir(), "do something")
+	require.NoError(t, err)
+}
+
+func TestAider_Run_Timeout(t *testing.T) {
+	a := NewAider(50*time.Millisecond, testLogger())
+	a.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sleep", "60")
+	}
+
+	_, err := a.Run(context.Background(), t.TempDir(), "do something slow")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestAider_Run_NonZeroExit(t *testing.T) {
+	a := NewAider(5*time.Minute, testLogger())
+	a.commandContext = stubCommand(1, "something went wrong")
+
+	_, err := a.Run(context.Background(), t.TempDir(), "do something")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent failed")
+	assert.Contains(t, err.Error(), "something went wrong")
+}
+
+func TestAider_Run_StreamingToLogWriter(t *testing.T) {
+	var logBuf bytes.Buffer
+	a := NewAider(5*time.Minute, testLogger())
+	a.SetLogWriter(&logBuf)
+	a.commandContext = stubCommandWithOutput("hello from aider")
+
+	output, err := a.Run(context.Background(), t.TempDir(), "do something")
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "hello from aider")
+	assert.Contains(t, logBuf.String(), "hello from aider")
+
+	a.ClearLogWriter()
+	assert.Nil(t, a.LogWriter)
+}
+
+func TestAider_Run_VerifiesArgs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "internal", "auth"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "internal", "auth", "login.go"), []byte("package auth\n"), 0o644))
+
+	var capturedName string
+	var capturedArgs []string
+	a := NewAider(5*time.Minute, testLogger())
+	a.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedName, capturedArgs = name, args
+		return exec.CommandContext(ctx, "true")
+	}
+
+	prompt := "Fix the bug in `internal/auth/login.go` and add a test."
+	_, err := a.Run(context.Background(), dir, prompt)
+	require.NoError(t, err)
+
+	assert.Equal(t, "aider", capturedName)
+	assert.Equal(t, []string{"--message", prompt}, capturedArgs[:2])
+	assert.Contains(t, capturedArgs, "--yes-always")
+	assert.Contains(t, capturedArgs, "--no-auto-commits")
+	assert.Equal(t, "internal/auth/login.go", capturedArgs[len(capturedArgs)-1])
+}
+
+func TestAiderFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"main.go", "docs/README.md", "pkg/util.go"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0o644))
+	}
+
+	prompt := "Update pkg/util.go and ./main.go. See docs/README.md, then main.go again. " +
+		"Ignore missing.go, ../secret.txt, /etc/hosts and the pkg directory. Version 1.2 stays."
+	assert.Equal(t, []string{"pkg/util.go", "main.go", "docs/README.md"}, aiderFiles(dir, prompt))
+	assert.Empty(t, aiderFiles(dir, "no files here"))
+}
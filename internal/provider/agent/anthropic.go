@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+)
+
+// anthropicVersion is the Messages API version sent with every request.
+const anthropicVersion = "2023-06-01"
+
+// AnthropicOptions configures the Anthropic agent's endpoint and tool loop.
+type AnthropicOptions struct {
+	BaseURL         string   // endpoint root, e.g. https://api.anthropic.com or https://openrouter.ai/api
+	Model           string   // model slug sent as-is
+	APIKey          string   // sent as x-api-key and as a bearer token
+	MaxTokens       int      // max output tokens per response
+	MaxTurns        int      // max model round-trips per Run
+	AllowedCommands []string // run_command prefixes, e.g. "go test"
+}
+
+// Anthropic implements provider.Agent by calling an Anthropic
+// Messages-compatible HTTP endpoint directly and running the tool loop itself:
+// the model reads, writes and edits files in the worktree and runs
+// allow-listed commands there. No vendor CLI is needed.
+type Anthropic struct {
+	Timeout time.Duration
+	Logger  *slog.Logger
+	Options AnthropicOptions
+
+	// LogWriter, when non-nil, receives a real-time transcript of the run.
+	LogWriter io.Writer
+
+	client *http.Client
+}
+
+// NewAnthropic creates a new Anthropic agent provider.
+func NewAnthropic(timeout time.Duration, opts AnthropicOptions, logger *slog.Logger) *Anthropic {
+	return &Anthropic{
+		Timeout: timeout,
+		Logger:  logger,
+		Options: opts,
+		client:  &http.Client{},
+	}
+}
+
+// SetLogWriter sets the writer that receives a real-time transcript of the run.
+func (a *Anthropic) SetLogWriter(w io.Writer) { a.LogWriter = w }
+
+// ClearLogWriter removes the streaming log writer.
+func (a *Anthropic) ClearLogWriter() { a.LogWriter = nil }
+
+func (a *Anthropic) PromptSuffix() string { return "" }
+
+// messagesRequest is the JSON body for POST /v1/messages.
+type messagesRequest struct {
+	Model     string       `json:"model"`
+	MaxTokens int          `json:"max_tokens"`
+	System    string       `json:"system,omitempty"`
+	Messages  []apiMessage `json:"messages"`
+	Tools     []apiTool    `json:"tools,omitempty"`
+}
+
+// apiMessage is one conversation turn. Content holds raw blocks so the
+// assistant's replies are sent back exactly as received.
+type apiMessage struct {
+	Role    string            `json:"role"`
+	Content []json.RawMessage `json:"content"`
+}
+
+type apiTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// apiBlock is a content block: text, tool_use (from the model) or
+// tool_result (from us).
+type apiBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type messagesResponse struct {
+	Content    []json.RawMessage `json:"content"`
+	StopReason string            `json:"stop_reason"` // end_turn, tool_use, max_tokens, stop_sequence
+	Usage      struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicResult is what Run returns: a JSON summary of the whole loop,
+// parsed back by ParseResult.
+type anthropicResult struct {
+	Type         string              `json:"type"` // always "result"
+	StopReason   string              `json:"stop_reason"`
+	Result       string              `json:"result"`
+	Turns        int                 `json:"turns"`
+	ToolCalls    []provider.ToolCall `json:"tool_calls,omitempty"`
+	FilesTouched []string            `json:"files_touched,omitempty"`
+	Usage        provider.Usage      `json:"usage"`
+}
+
+func (a *Anthropic) Run(ctx context.Context, dir, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+
+	a.Logger.Info("running anthropic agent", "dir", dir, "model", a.Options.Model, "timeout", a.Timeout)
+
+	tools := &toolbox{dir: dir, allowed: a.Options.AllowedCommands, logWriter: a.LogWriter}
+	res := anthropicResult{Type: "result"}
+	userText, _ := json.Marshal(apiBlock{Type: "text", Text: prompt})
+	messages := []apiMessage{{Role: "user", Content: []json.RawMessage{userText}}}
+
+	for res.Turns < a.Options.MaxTurns {
+		res.Turns++
+		resp, err := a.send(ctx, messagesRequest{
+			Model:     a.Options.Model,
+			MaxTokens: a.Options.MaxTokens,
+			System:    tools.systemPrompt(),
+			Messages:  messages,
+			Tools:     toolDefinitions,
+		})
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("agent timed out after %s", a.Timeout)
+			}
+			return res.encode(tools), err
+		}
+		res.StopReason = resp.StopReason
+		res.Usage.InputTokens += resp.Usage.InputTokens
+		res.Usage.OutputTokens += resp.Usage.OutputTokens
+		res.Usage.CacheCreationTokens += resp.Usage.CacheCreationInputTokens
+		res.Usage.CacheReadTokens += resp.Usage.CacheReadInputTokens
+		messages = append(messages, apiMessage{Role: "assistant", Content: resp.Content})
+
+		var results []json.RawMessage
+		var text []string
+		for _, raw := range resp.Content {
+			var block apiBlock
+			if json.Unmarshal(raw, &block) != nil {
+				continue
+			}
+			switch block.Type {
+			case "text":
+				text = append(text, block.Text)
+				a.logf("%s\n", block.Text)
+			case "tool_use":
+				res.ToolCalls = append(res.ToolCalls, provider.ToolCall{Name: block.Name, Input: toolInputSummary(block.Input)})
+				out, isErr := tools.call(ctx, block.Name, block.Input)
+				result, _ := json.Marshal(apiBlock{Type: "tool_result", ToolUseID: block.ID, Content: out, IsError: isErr})
+				results = append(results, result)
+			}
+		}
+		if len(text) > 0 {
+			res.Result = strings.Join(text, "\n")
+		}
+
+		if len(results) == 0 {
+			a.Logger.Info("anthropic agent completed", "turns", res.Turns, "stop_reason", res.StopReason)
+			return res.encode(tools), nil
+		}
+		messages = append(messages, apiMessage{Role: "user", Content: results})
+	}
+
+	res.StopReason = "max_turns"
+	return res.encode(tools), fmt.Errorf("agent failed: no final answer after %d turns", a.Options.MaxTurns)
+}
+
+// send posts one Messages API request.
+func (a *Anthropic) send(ctx context.Context, body messagesRequest) (*messagesResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: marshaling request: %w", err)
+	}
+
+	url := strings.TrimRight(a.Options.BaseURL, "/") + "/v1/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if a.Options.APIKey != "" {
+		req.Header.Set("x-api-key", a.Options.APIKey)
+		req.Header.Set("Authorization", "Bearer "+a.Options.APIKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("anthropic: unexpected status %d: %s", resp.StatusCode, data)
+	}
+
+	var out messagesResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("anthropic: parsing response: %w", err)
+	}
+	return &out, nil
+}
+
+// logf writes to the streaming log, if any.
+func (a *Anthropic) logf(format string, args ...any) {
+	if a.LogWriter != nil {
+		_, _ = fmt.Fprintf(a.LogWriter, format, args...)
+	}
+}
+
+// encode finishes the summary with the files the tools changed.
+func (r anthropicResult) encode(tools *toolbox) string {
+	r.FilesTouched = tools.touched
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// ParseResult parses the JSON summary Run returns.
+func (a *Anthropic) ParseResult(output string) provider.AgentResult {
+	var env anthropicResult
+	if json.Unmarshal([]byte(output), &env) != nil || env.Type != "result" {
+		return provider.AgentResult{Text: output}
+	}
+	res := provider.AgentResult{
+		Text:         env.Result,
+		ToolCalls:    env.ToolCalls,
+		FilesTouched: env.FilesTouched,
+		Usage:        env.Usage,
+		ExitReason:   env.StopReason,
+	}
+	if res.Text == "" {
+		res.Text = output
+	}
+	return res
+}
@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ provider.ResultParser = (*Anthropic)(nil)
+
+// fakeMessagesAPI serves scripted Messages API responses in order and records
+// the requests it received.
+type fakeMessagesAPI struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []string
+	requests  []messagesRequest
+	headers   []http.Header
+}
+
+func (f *fakeMessagesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	assert.Equal(f.t, "/v1/messages", r.URL.Path)
+	var req messagesRequest
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	f.requests = append(f.requests, req)
+	f.headers = append(f.headers, r.Header.Clone())
+
+	if len(f.responses) == 0 {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"no more responses"}}`, http.StatusBadRequest)
+		return
+	}
+	_, _ = w.Write([]byte(f.responses[0]))
+	f.responses = f.responses[1:]
+}
+
+// toolResults returns the tool_result blocks of request i's last message.
+func (f *fakeMessagesAPI) toolResults(i int) []apiBlock {
+	msgs := f.requests[i].Messages
+	var blocks []apiBlock
+	for _, raw := range msgs[len(msgs)-1].Content {
+		var b apiBlock
+		require.NoError(f.t, json.Unmarshal(raw, &b))
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func newFakeAnthropic(t *testing.T, responses ...string) (*Anthropic, *fakeMessagesAPI) {
+	t.Helper()
+	fake := &fakeMessagesAPI{t: t, responses: responses}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	a := NewAnthropic(time.Minute, AnthropicOptions{
+		BaseURL:         srv.URL + "/",
+		Model:           "test-model",
+		APIKey:          "sk-test",
+		MaxTokens:       1024,
+		MaxTurns:        5,
+		AllowedCommands: []string{"echo"},
+	}, testLogger())
+	return a, fake
+}
+
+func TestAnthropic_Run_ToolLoop(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+
+	a, fake := newFakeAnthropic(t,
+		`{"content":[{"type":"text","text":"Looking."},{"type":"tool_use","id":"tu1","name":"read_file","input":{"path":"main.go"}},`+
+			`{"type":"tool_use","id":"tu2","name":"write_file","input":{"path":"pkg/util.go","content":"package pkg\n"}}],`+
+			`"stop_reason":"tool_use","usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":50}}`,
+		`{"content":[{"type":"tool_use","id":"tu3","name":"edit_file","input":{"path":"main.go","old_string":"func main() {}","new_string":"func main() { println(1) }"}},`+
+			`{"type":"tool_use","id":"tu4","name":"run_command","input":{"command":"echo built"}}],`+
+			`"stop_reason":"tool_use","usage":{"input_tokens":200,"output_tokens":30}}`,
+		`{"content":[{"type":"text","text":"Added util and a print."}],"stop_reason":"end_turn","usage":{"input_tokens":300,"output_tokens":10}}`,
+	)
+	var logBuf bytes.Buffer
+	a.SetLogWriter(&logBuf)
+
+	output, err := a.Run(context.Background(), dir, "improve main")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "println(1)")
+	data, err = os.ReadFile(filepath.Join(dir, "pkg", "util.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(data))
+
+	require.Len(t, fake.requests, 3)
+	first := fake.requests[0]
+	assert.Equal(t, "test-model", first.Model)
+	assert.Equal(t, 1024, first.MaxTokens)
+	assert.Len(t, first.Tools, 4)
+	assert.Contains(t, first.System, "echo")
+	assert.Equal(t, "sk-test", fake.headers[0].Get("x-api-key"))
+	assert.Equal(t, anthropicVersion, fake.headers[0].Get("anthropic-version"))
+
+	results := fake.toolResults(1)
+	require.Len(t, results, 2)
+	assert.Equal(t, "tu1", results[0].ToolUseID)
+	assert.Contains(t, results[0].Content, "func main() {}")
+	assert.False(t, results[0].IsError)
+	assert.Equal(t, "tu2", results[1].ToolUseID)
+
+	results = fake.toolResults(2)
+	require.Len(t, results, 2)
+	assert.False(t, results[0].IsError, results[0].Content)
+	assert.Equal(t, "built\n", results[1].Content)
+
+	assert.Equal(t, provider.AgentResult{
+		Text: "Added util and a print.",
+		ToolCalls: []provider.ToolCall{
+			{Name: "read_file", Input: "main.go"},
+			{Name: "write_file", Input: "pkg/util.go"},
+			{Name: "edit_file", Input: "main.go"},
+			{Name: "run_command", Input: "echo built"},
+		},
+		FilesTouched: []string{"pkg/util.go", "main.go"},
+		Usage:        provider.Usage{InputTokens: 600, OutputTokens: 60, CacheReadTokens: 50},
+		ExitReason:   "end_turn",
+	}, a.ParseResult(output))
+
+	assert.Contains(t, logBuf.String(), "→ run_command echo built")
+	assert.Contains(t, logBuf.String(), "Added util and a print.")
+}
+
+func TestAnthropic_Run_ToolErrorsGoBackToModel(t *testing.T) {
+	dir := t.TempDir()
+	a, fake := newFakeAnthropic(t,
+		`{"content":[{"type":"tool_use","id":"a","name":"read_file","input":{"path":"../outside.txt"}},`+
+			`{"type":"tool_use","id":"b","name":"run_command","input":{"command":"rm -rf ."}},`+
+			`{"type":"tool_use","id":"c","name":"edit_file","input":{"path":"missing.go","old_string":"x","new_string":"y"}},`+
+			`{"type":"tool_use","id":"d","name":"delete_everything","input":{}}],"stop_reason":"tool_use"}`,
+		`{"content":[{"type":"text","text":"Gave up."}],"stop_reason":"end_turn"}`,
+	)
+
+	_, err := a.Run(context.Background(), dir, "try things")
+	require.NoError(t, err)
+
+	results := fake.toolResults(1)
+	require.Len(t, results, 4)
+	for _, r := range results {
+		assert.True(t, r.IsError, "tool %s should fail", r.ToolUseID)
+	}
+	assert.Contains(t, results[0].Content, "outside the repository")
+	assert.Contains(t, results[1].Content, "not allowed")
+	assert.Contains(t, results[3].Content, "unknown tool")
+}
+
+func TestAnthropic_Run_CommandExitCode(t *testing.T) {
+	a, fake := newFakeAnthropic(t,
+		`{"content":[{"type":"tool_use","id":"a","name":"run_command","input":{"command":"false"}}],"stop_reason":"tool_use"}`,
+		`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`,
+	)
+	a.Options.AllowedCommands = []string{"false"}
+
+	_, err := a.Run(context.Background(), t.TempDir(), "fail")
+	require.NoError(t, err)
+
+	results := fake.toolResults(1)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].IsError)
+	assert.Contains(t, results[0].Content, "exit status 1")
+}
+
+func TestAnthropic_Run_HTTPError(t *testing.T) {
+	a, _ := newFakeAnthropic(t)
+
+	_, err := a.Run(context.Background(), t.TempDir(), "anything")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status 400")
+}
+
+func TestAnthropic_Run_MaxTurns(t *testing.T) {
+	loop := `{"content":[{"type":"tool_use","id":"a","name":"run_command","input":{"command":"echo again"}}],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":1}}`
+	a, fake := newFakeAnthropic(t, loop, loop, loop)
+	a.Options.MaxTurns = 2
+
+	output, err := a.Run(context.Background(), t.TempDir(), "loop forever")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 2 turns")
+	assert.Len(t, fake.requests, 2)
+
+	res := a.ParseResult(output)
+	assert.Equal(t, "max_turns", res.ExitReason)
+	assert.Equal(t, int64(2), res.Usage.InputTokens)
+}
+
+func TestToolbox_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "escape")))
+	tb := &toolbox{dir: dir}
+
+	for _, path := range []string{"a.go", "sub/dir/new.go", filepath.Join(dir, "abs.go")} {
+		_, err := tb.resolve(path)
+		assert.NoError(t, err, path)
+	}
+	for _, path := range []string{"", "../x", "sub/../../x", "/etc/passwd", "escape/x.go"} {
+		_, err := tb.resolve(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestToolbox_EditFile_Ambiguous(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x x"), 0o644))
+	tb := &toolbox{dir: dir}
+
+	_, err := tb.editFile("f.txt", "x", "y")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "occurs 2 times")
+	assert.Empty(t, tb.touched)
+}
+
+func TestToolbox_CommandAllowed(t *testing.T) {
+	tb := &toolbox{allowed: []string{"go test", "ls"}}
+
+	assert.True(t, tb.commandAllowed([]string{"go", "test", "./..."}))
+	assert.True(t, tb.commandAllowed([]string{"ls"}))
+	assert.False(t, tb.commandAllowed([]string{"go", "run", "main.go"}))
+	assert.False(t, tb.commandAllowed([]string{"go"}))
+	assert.False(t, tb.commandAllowed([]string{"lsof"}))
+}
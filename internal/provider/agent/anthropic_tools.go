@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// maxToolOutput caps what a tool returns to the model (file contents,
+// command output); the rest is cut with a note.
+const maxToolOutput = 100 << 10
+
+// toolDefinitions are the tools offered to the model on every request.
+var toolDefinitions = []apiTool{
+	{
+		Name:        "read_file",
+		Description: "Read a file in the repository. Paths are relative to the repository root.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`),
+	},
+	{
+		Name:        "write_file",
+		Description: "Create or overwrite a file with the given content. Parent directories are created as needed.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"},"content":{"type":"string"}},"required":["path","content"]}`),
+	},
+	{
+		Name:        "edit_file",
+		Description: "Replace old_string with new_string in a file. old_string must occur exactly once.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"},"old_string":{"type":"string"},"new_string":{"type":"string"}},"required":["path","old_string","new_string"]}`),
+	},
+	{
+		Name:        "run_command",
+		Description: "Run a command in the repository root and return its combined output. Runs without a shell: no pipes, redirects or globs. Only allow-listed commands are permitted.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"command":{"type":"string"}},"required":["command"]}`),
+	},
+}
+
+// toolInput is the union of the tools' input fields.
+type toolInput struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	OldString string `json:"old_string"`
+	NewString string `json:"new_string"`
+	Command   string `json:"command"`
+}
+
+// toolInputSummary returns the primary argument of a tool call for
+// AgentResult.ToolCalls: the path or the command.
+func toolInputSummary(raw json.RawMessage) string {
+	var in toolInput
+	_ = json.Unmarshal(raw, &in)
+	if in.Command != "" {
+		return in.Command
+	}
+	return in.Path
+}
+
+// toolbox executes tool calls inside a worktree.
+type toolbox struct {
+	dir       string
+	allowed   []string
+	logWriter io.Writer
+	touched   []string // files written or edited, in order, deduplicated
+}
+
+func (t *toolbox) systemPrompt() string {
+	cmds := "none"
+	if len(t.allowed) > 0 {
+		cmds = strings.Join(t.allowed, ", ")
+	}
+	return "You are a coding agent working in a git repository. Use the tools to inspect and change files; " +
+		"all paths are relative to the repository root and must stay inside it. " +
+		"Commands you may run (with any arguments): " + cmds + ". " +
+		"Do not commit or push; when the task is done, reply with a short summary of what you changed."
+}
+
+// call runs one tool and returns its result for the model, and whether it
+// failed. Tool failures are reported to the model rather than ending the run.
+func (t *toolbox) call(ctx context.Context, name string, raw json.RawMessage) (string, bool) {
+	var in toolInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return fmt.Sprintf("invalid input: %v", err), true
+	}
+	if t.logWriter != nil {
+		_, _ = fmt.Fprintf(t.logWriter, "→ %s %s\n", name, toolInputSummary(raw))
+	}
+
+	var out string
+	var err error
+	switch name {
+	case "read_file":
+		out, err = t.readFile(in.Path)
+	case "write_file":
+		out, err = t.writeFile(in.Path, in.Content)
+	case "edit_file":
+		out, err = t.editFile(in.Path, in.OldString, in.NewString)
+	case "run_command":
+		out, err = t.runCommand(ctx, in.Command)
+	default:
+		err = fmt.Errorf("unknown tool %q", name)
+	}
+	if err != nil {
+		return truncateOutput(out + err.Error()), true
+	}
+	return truncateOutput(out), false
+}
+
+// resolve maps a model-supplied path to an absolute path inside the worktree,
+// following symlinks so none can point outside it.
+func (t *toolbox) resolve(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	root, err := filepath.EvalSymlinks(t.dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving worktree: %w", err)
+	}
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if filepath.IsAbs(path) {
+		full = filepath.Clean(path)
+	}
+
+	// Resolve the deepest existing ancestor; the rest doesn't exist yet.
+	existing, rest := full, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("resolving %q: %w", path, err)
+	}
+	resolved = filepath.Join(resolved, rest)
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the repository", path)
+	}
+	return resolved, nil
+}
+
+func (t *toolbox) readFile(path string) (string, error) {
+	full, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+func (t *toolbox) writeFile(path, content string) (string, error) {
+	full, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return "", fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	t.touch(full)
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), path), nil
+}
+
+func (t *toolbox) editFile(path, oldString, newString string) (string, error) {
+	full, err := t.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	if oldString == "" {
+		return "", errors.New("old_string is required")
+	}
+	switch n := strings.Count(string(data), oldString); n {
+	case 0:
+		return "", fmt.Errorf("old_string not found in %s", path)
+	case 1:
+	default:
+		return "", fmt.Errorf("old_string occurs %d times in %s; include more context", n, path)
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", path, err)
+	}
+	updated := strings.Replace(string(data), oldString, newString, 1)
+	if err := os.WriteFile(full, []byte(updated), info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	t.touch(full)
+	return fmt.Sprintf("edited %s", path), nil
+}
+
+// runCommand runs an allow-listed command without a shell, so arguments can't
+// chain further commands. A non-zero exit is returned to the model as a failed
+// tool call along with the output.
+func (t *toolbox) runCommand(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("command is required")
+	}
+	if !t.commandAllowed(args) {
+		return "", fmt.Errorf("command %q is not allowed; allowed commands: %s", command, strings.Join(t.allowed, ", "))
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = t.dir
+	out, err := runProcess(ctx, cmd, t.logWriter, 0)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return out, fmt.Errorf("\n%s", exitErr)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// commandAllowed reports whether args start with one of the allow-listed
+// command prefixes, matched word by word.
+func (t *toolbox) commandAllowed(args []string) bool {
+	for _, entry := range t.allowed {
+		prefix := strings.Fields(entry)
+		if len(prefix) == 0 || len(prefix) > len(args) {
+			continue
+		}
+		match := true
+		for i, word := range prefix {
+			if args[i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// touch records a changed file, relative to the worktree.
+func (t *toolbox) touch(full string) {
+	rel := full
+	if root, err := filepath.EvalSymlinks(t.dir); err == nil {
+		if r, err := filepath.Rel(root, full); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+	}
+	for _, f := range t.touched {
+		if f == rel {
+			return
+		}
+	}
+	t.touched = append(t.touched, rel)
+}
+
+func truncateOutput(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return s[:maxToolOutput] + fmt.Sprintf("\n... [truncated %d bytes]", len(s)-maxToolOutput)
+}
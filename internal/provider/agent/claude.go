@@ -1,7 +1,6 @@
 package agent
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -10,14 +9,19 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 )
 
+// defaultClaudeTools is the --allowedTools list when none is configured.
+const defaultClaudeTools = "Edit,Read,Write,Bash"
+
 // Claude implements provider.Agent using the claude CLI.
 type Claude struct {
-	Timeout time.Duration
-	Logger  *slog.Logger
+	Timeout      time.Duration
+	Logger       *slog.Logger
+	Model        string   // --model; empty uses the CLI's default
+	AllowedTools string   // --allowedTools; empty uses defaultClaudeTools
+	Env          []string // extra KEY=VALUE entries for the agent process
 
 	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
 	LogWriter io.Writer
@@ -49,11 +53,18 @@ func (c *Claude) Run(ctx context.Context, dir, prompt string) (string, error) {
 
 	c.Logger.Info("running agent", "dir", dir, "timeout", c.Timeout)
 
+	tools := c.AllowedTools
+	if tools == "" {
+		tools = defaultClaudeTools
+	}
 	args := []string{
 		"-p", prompt,
-		"--allowedTools", "Edit,Read,Write,Bash",
+		"--allowedTools", tools,
 		"--output-format", "json",
 	}
+	if c.Model != "" {
+		args = append(args, "--model", c.Model)
+	}
 
 	// Inject .claude/*.md project docs into the system prompt so the headless
 	// agent has the same instruction set as an interactive session.
@@ -63,50 +74,11 @@ func (c *Claude) Run(ctx context.Context, dir, prompt string) (string, error) {
 
 	cmd := c.commandContext(ctx, "claude", args...)
 	cmd.Dir = dir
+	cmd.Env = processEnv(c.Env)
 
-	// When no LogWriter is set, use simple CombinedOutput (original behavior).
-	if c.LogWriter == nil {
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return string(out), fmt.Errorf("agent timed out after %s", c.Timeout)
-			}
-			return string(out), fmt.Errorf("agent failed: %w: %s", err, out)
-		}
-		c.Logger.Info("agent completed")
-		return string(out), nil
-	}
-
-	// Streaming: tee stdout+stderr to both a buffer and LogWriter.
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
+	out, err := runProcess(ctx, cmd, c.LogWriter, c.Timeout)
 	if err != nil {
-		return "", fmt.Errorf("agent stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("agent start: %w", err)
-	}
-
-	var buf bytes.Buffer
-	w := io.MultiWriter(&buf, c.LogWriter)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stdout) }()
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stderr) }()
-	wg.Wait()
-
-	err = cmd.Wait()
-	out := buf.String()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return out, fmt.Errorf("agent timed out after %s", c.Timeout)
-		}
-		return out, fmt.Errorf("agent failed: %w: %s", err, out)
+		return out, err
 	}
 
 	c.Logger.Info("agent completed")
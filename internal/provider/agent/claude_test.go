@@ -120,6 +120,37 @@ func TestRun_Streaming_NonZeroExit(t *testing.T) {
 	assert.Contains(t, logBuf.String(), "partial output")
 }
 
+func TestRun_ModelToolsAndEnv(t *testing.T) {
+	var capturedArgs []string
+	c := New(5*time.Minute, testLogger())
+	c.Model = "claude-sonnet-4-5"
+	c.AllowedTools = "Read,Edit"
+	c.Env = []string{"FORGE_TEST_BASE_URL=https://openrouter.ai/api"}
+	c.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedArgs = args
+		return exec.CommandContext(ctx, "sh", "-c", `printf %s "$FORGE_TEST_BASE_URL"`)
+	}
+
+	output, err := c.Run(context.Background(), t.TempDir(), "do something")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://openrouter.ai/api", output)
+	assert.Equal(t, []string{"-p", "do something", "--allowedTools", "Read,Edit", "--output-format", "json", "--model", "claude-sonnet-4-5"}, capturedArgs)
+}
+
+func TestRun_DefaultTools(t *testing.T) {
+	var capturedArgs []string
+	c := New(5*time.Minute, testLogger())
+	c.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedArgs = args
+		return exec.CommandContext(ctx, "true")
+	}
+
+	_, err := c.Run(context.Background(), t.TempDir(), "do something")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-p", "do something", "--allowedTools", "Edit,Read,Write,Bash", "--output-format", "json"}, capturedArgs)
+}
+
 func TestLoadProjectDocs_WithFiles(t *testing.T) {
 	dir := t.TempDir()
 	claudeDir := filepath.Join(dir, ".claude")
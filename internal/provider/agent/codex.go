@@ -1,13 +1,10 @@
 package agent
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"log/slog"
 	"os/exec"
-	"sync"
 	"time"
 )
 
@@ -15,6 +12,8 @@ import (
 type Codex struct {
 	Timeout time.Duration
 	Logger  *slog.Logger
+	Model   string   // --model; empty uses the CLI's default
+	Env     []string // extra KEY=VALUE entries for the agent process
 
 	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
 	LogWriter io.Writer
@@ -51,55 +50,19 @@ func (c *Codex) Run(ctx context.Context, dir, prompt string) (string, error) {
 		"--full-auto",
 		"--json",
 		"--cd", dir,
-		prompt,
 	}
+	if c.Model != "" {
+		args = append(args, "--model", c.Model)
+	}
+	args = append(args, prompt)
 
 	cmd := c.commandContext(ctx, "codex", args...)
 	cmd.Dir = dir
+	cmd.Env = processEnv(c.Env)
 
-	// When no LogWriter is set, use simple CombinedOutput.
-	if c.LogWriter == nil {
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return string(out), fmt.Errorf("agent timed out after %s", c.Timeout)
-			}
-			return string(out), fmt.Errorf("agent failed: %w: %s", err, out)
-		}
-		c.Logger.Info("codex agent completed")
-		return string(out), nil
-	}
-
-	// Streaming: tee stdout+stderr to both a buffer and LogWriter.
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("agent start: %w", err)
-	}
-
-	var buf bytes.Buffer
-	w := io.MultiWriter(&buf, c.LogWriter)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stdout) }()
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stderr) }()
-	wg.Wait()
-
-	err = cmd.Wait()
-	out := buf.String()
+	out, err := runProcess(ctx, cmd, c.LogWriter, c.Timeout)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return out, fmt.Errorf("agent timed out after %s", c.Timeout)
-		}
-		return out, fmt.Errorf("agent failed: %w: %s", err, out)
+		return out, err
 	}
 
 	c.Logger.Info("codex agent completed")
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/shahar-caura/forge/internal/provider"
+)
+
+// promptFile is the exec agent's PromptInput for writing the prompt to a temp
+// file (see execTemplateData.PromptFile) instead of the command's stdin.
+const promptFile = "file"
+
+// execTemplateData is what the exec agent's command template can reference.
+type execTemplateData struct {
+	Dir        string // worktree the agent runs in
+	PromptFile string // prompt file path; empty unless the prompt input is "file"
+	RunID      string
+	Step       string
+}
+
+// Exec implements provider.Agent by running a user-configured command, for
+// in-house agent wrappers. The command is a template rendered per call and
+// split on whitespace (no shell). It receives the prompt on stdin or in a
+// temp file, plus FORGE_RUN_ID, FORGE_STEP and FORGE_WORKTREE in its
+// environment. Its stdout is the output; stderr is only logged, and included
+// in the error when the command fails.
+type Exec struct {
+	Timeout     time.Duration
+	Logger      *slog.Logger
+	Command     string   // command template, e.g. "./bin/my-agent --prompt-file {{.PromptFile}}"
+	PromptInput string   // "stdin" (default) or "file"
+	Env         []string // extra KEY=VALUE entries for the command
+
+	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
+	LogWriter io.Writer
+
+	// commandContext is overridable for testing.
+	commandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+// NewExec creates a new exec agent provider.
+func NewExec(timeout time.Duration, command, promptInput string, logger *slog.Logger) *Exec {
+	return &Exec{
+		Timeout:        timeout,
+		Logger:         logger,
+		Command:        command,
+		PromptInput:    promptInput,
+		commandContext: exec.CommandContext,
+	}
+}
+
+// SetLogWriter sets the writer that receives a real-time copy of agent output.
+func (e *Exec) SetLogWriter(w io.Writer) { e.LogWriter = w }
+
+// ClearLogWriter removes the streaming log writer.
+func (e *Exec) ClearLogWriter() { e.LogWriter = nil }
+
+func (e *Exec) PromptSuffix() string { return "" }
+
+func (e *Exec) Run(ctx context.Context, dir, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+
+	info := provider.RunInfoFromContext(ctx)
+	data := execTemplateData{Dir: dir, RunID: info.RunID, Step: info.Step}
+
+	if e.PromptInput == promptFile {
+		f, err := os.CreateTemp("", "forge-prompt-*.md")
+		if err != nil {
+			return "", fmt.Errorf("exec agent: creating prompt file: %w", err)
+		}
+		defer func() { _ = os.Remove(f.Name()) }()
+		_, err = f.WriteString(prompt)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", fmt.Errorf("exec agent: writing prompt file: %w", err)
+		}
+		data.PromptFile = f.Name()
+	}
+
+	args, err := renderCommand(e.Command, data)
+	if err != nil {
+		return "", fmt.Errorf("exec agent: %w", err)
+	}
+
+	e.Logger.Info("running exec agent", "dir", dir, "command", args[0], "timeout", e.Timeout)
+
+	cmd := e.commandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"FORGE_RUN_ID="+data.RunID,
+		"FORGE_STEP="+data.Step,
+		"FORGE_WORKTREE="+dir,
+	)
+	cmd.Env = append(cmd.Env, e.Env...)
+	if data.PromptFile != "" {
+		cmd.Env = append(cmd.Env, "FORGE_PROMPT_FILE="+data.PromptFile)
+	} else {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = teeLog(&stdout, e.LogWriter)
+	cmd.Stderr = teeLog(&stderr, e.LogWriter)
+
+	wait, err := startProcess(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	err = wait()
+	out := stdout.String()
+	if err != nil {
+		detail := stderr.String()
+		if strings.TrimSpace(detail) == "" {
+			detail = out
+		}
+		return out, processError(ctx, err, e.Timeout, detail)
+	}
+
+	e.Logger.Info("exec agent completed")
+	return out, nil
+}
+
+// renderCommand splits the command template into arguments on whitespace
+// outside {{ }} actions, then renders each argument on its own so substituted
+// values (paths with spaces) stay one argument. Arguments that render empty
+// are dropped, and a leading ~ is expanded the way worktree commands do.
+func renderCommand(tmplStr string, data execTemplateData) ([]string, error) {
+	var fields []string
+	for _, word := range splitTemplate(tmplStr) {
+		tmpl, err := template.New("command").Parse(word)
+		if err != nil {
+			return nil, fmt.Errorf("parsing command template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("rendering command template: %w", err)
+		}
+		if buf.Len() > 0 {
+			fields = append(fields, buf.String())
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("command template produced empty command")
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		for i, f := range fields {
+			if f == "~" {
+				fields[i] = home
+			} else if strings.HasPrefix(f, "~/") {
+				fields[i] = filepath.Join(home, f[2:])
+			}
+		}
+	}
+	return fields, nil
+}
+
+// splitTemplate splits a command template on whitespace, keeping each
+// {{ ... }} action (which may contain spaces) inside its word.
+func splitTemplate(s string) []string {
+	var (
+		words    []string
+		word     strings.Builder
+		inAction bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case !inAction && strings.HasPrefix(s[i:], "{{"):
+			inAction = true
+			word.WriteString("{{")
+			i++
+		case inAction && strings.HasPrefix(s[i:], "}}"):
+			inAction = false
+			word.WriteString("}}")
+			i++
+		case !inAction && unicode.IsSpace(rune(s[i])):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteByte(s[i])
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes an executable shell script into a temp dir and returns its path.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755))
+	return path
+}
+
+func TestExec_Run_PromptOnStdin(t *testing.T) {
+	script := writeScript(t, `echo "prompt: $(cat)"
+echo "run=$FORGE_RUN_ID step=$FORGE_STEP wt=$FORGE_WORKTREE"
+echo "noise" >&2
+`)
+	dir := t.TempDir()
+	var logBuf bytes.Buffer
+	e := NewExec(time.Minute, script+" --dir {{.Dir}} --run {{.RunID}}", "stdin", testLogger())
+	e.SetLogWriter(&logBuf)
+
+	ctx := provider.WithRunInfo(context.Background(), provider.RunInfo{RunID: "20260101-abc", Step: "run agent"})
+	output, err := e.Run(ctx, dir, "implement auth")
+	require.NoError(t, err)
+
+	assert.Equal(t, "prompt: implement auth\nrun=20260101-abc step=run agent wt="+dir+"\n", output, "stdout only")
+	assert.Contains(t, logBuf.String(), "prompt: implement auth")
+	assert.Contains(t, logBuf.String(), "noise", "stderr is streamed to the log")
+}
+
+func TestExec_Run_PromptFile(t *testing.T) {
+	script := writeScript(t, `cat "$1"; echo; cat "$FORGE_PROMPT_FILE"`)
+	e := NewExec(time.Minute, script+" {{.PromptFile}}", "file", testLogger())
+
+	output, err := e.Run(context.Background(), t.TempDir(), "fix the bug")
+	require.NoError(t, err)
+	assert.Equal(t, "fix the bug\nfix the bug", output)
+}
+
+func TestExec_Run_RendersArgs(t *testing.T) {
+	var capturedName string
+	var capturedArgs []string
+	e := NewExec(time.Minute, "my-agent --model big --step {{.Step}}", "stdin", testLogger())
+	e.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedName, capturedArgs = name, args
+		return exec.CommandContext(ctx, "true")
+	}
+
+	ctx := provider.WithRunInfo(context.Background(), provider.RunInfo{RunID: "r1", Step: "fix-cr"})
+	_, err := e.Run(ctx, t.TempDir(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "my-agent", capturedName)
+	assert.Equal(t, []string{"--model", "big", "--step", "fix-cr"}, capturedArgs)
+}
+
+func TestExec_Run_DirWithSpaceStaysOneArg(t *testing.T) {
+	var capturedArgs []string
+	e := NewExec(time.Minute, "my-agent --dir {{ .Dir }} --prompt {{.PromptFile}} {{.Step}}", "file", testLogger())
+	e.commandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedArgs = args
+		return exec.CommandContext(ctx, "true")
+	}
+
+	dir := filepath.Join(t.TempDir(), "my worktree")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	_, err := e.Run(context.Background(), dir, "x")
+	require.NoError(t, err)
+	require.Len(t, capturedArgs, 4, "empty {{.Step}} is dropped, spaced dir is not split")
+	assert.Equal(t, []string{"--dir", dir, "--prompt"}, capturedArgs[:3])
+	assert.NotEmpty(t, capturedArgs[3])
+}
+
+func TestExec_Run_FailureIncludesStderr(t *testing.T) {
+	script := writeScript(t, `echo "partial"; echo "429 rate limit reached" >&2; exit 3`)
+	e := NewExec(time.Minute, script, "stdin", testLogger())
+
+	output, err := e.Run(context.Background(), t.TempDir(), "x")
+	require.Error(t, err)
+	assert.Equal(t, "partial\n", output)
+	assert.Contains(t, err.Error(), "agent failed")
+	assert.Contains(t, err.Error(), "rate limit", "stderr must reach the error so AgentPool can fall back")
+}
+
+func TestExec_Run_Timeout(t *testing.T) {
+	e := NewExec(50*time.Millisecond, "sleep 60", "stdin", testLogger())
+
+	_, err := e.Run(context.Background(), t.TempDir(), "x")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestExec_Run_BadTemplate(t *testing.T) {
+	for _, command := range []string{"agent {{.Nope}}", "agent {{", "{{.PromptFile}}"} {
+		e := NewExec(time.Minute, command, "stdin", testLogger())
+		_, err := e.Run(context.Background(), t.TempDir(), "x")
+		require.Error(t, err, command)
+		assert.True(t, strings.HasPrefix(err.Error(), "exec agent:"), err.Error())
+	}
+}
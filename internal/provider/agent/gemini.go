@@ -1,13 +1,10 @@
 package agent
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"log/slog"
 	"os/exec"
-	"sync"
 	"time"
 )
 
@@ -15,6 +12,8 @@ import (
 type Gemini struct {
 	Timeout time.Duration
 	Logger  *slog.Logger
+	Model   string   // --model; empty uses the CLI's default
+	Env     []string // extra KEY=VALUE entries for the agent process
 
 	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
 	LogWriter io.Writer
@@ -51,53 +50,17 @@ func (g *Gemini) Run(ctx context.Context, dir, prompt string) (string, error) {
 		"--yolo",
 		"--output-format", "json",
 	}
+	if g.Model != "" {
+		args = append(args, "--model", g.Model)
+	}
 
 	cmd := g.commandContext(ctx, "gemini", args...)
 	cmd.Dir = dir
+	cmd.Env = processEnv(g.Env)
 
-	// When no LogWriter is set, use simple CombinedOutput.
-	if g.LogWriter == nil {
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return string(out), fmt.Errorf("agent timed out after %s", g.Timeout)
-			}
-			return string(out), fmt.Errorf("agent failed: %w: %s", err, out)
-		}
-		g.Logger.Info("gemini agent completed")
-		return string(out), nil
-	}
-
-	// Streaming: tee stdout+stderr to both a buffer and LogWriter.
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("agent start: %w", err)
-	}
-
-	var buf bytes.Buffer
-	w := io.MultiWriter(&buf, g.LogWriter)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stdout) }()
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stderr) }()
-	wg.Wait()
-
-	err = cmd.Wait()
-	out := buf.String()
+	out, err := runProcess(ctx, cmd, g.LogWriter, g.Timeout)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return out, fmt.Errorf("agent timed out after %s", g.Timeout)
-		}
-		return out, fmt.Errorf("agent failed: %w: %s", err, out)
+		return out, err
 	}
 
 	g.Logger.Info("gemini agent completed")
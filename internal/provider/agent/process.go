@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+)
+
+// runProcess runs cmd in its own process group and returns its combined
+// stdout+stderr. When logWriter is non-nil it also receives a real-time copy
+// of the output. The PID is reported through the context's process hook so
+// forge cancel can kill the agent and everything it spawned; cancelling ctx
+// kills the whole group rather than just the direct child.
+func runProcess(ctx context.Context, cmd *exec.Cmd, logWriter io.Writer, timeout time.Duration) (string, error) {
+	var buf bytes.Buffer
+	cmd.Stdout = teeLog(&buf, logWriter)
+	cmd.Stderr = cmd.Stdout
+
+	wait, err := startProcess(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	err = wait()
+	out := buf.String()
+	if err != nil {
+		return out, processError(ctx, err, timeout, out)
+	}
+	return out, nil
+}
+
+// processEnv returns the environment for an agent process: forge's own plus
+// extra KEY=VALUE entries, which win over inherited ones. Nil (inherit) when
+// there are none.
+func processEnv(extra []string) []string {
+	if len(extra) == 0 {
+		return nil
+	}
+	return append(os.Environ(), extra...)
+}
+
+// teeLog returns w, also copying to logWriter when it is non-nil.
+func teeLog(w, logWriter io.Writer) io.Writer {
+	if logWriter == nil {
+		return w
+	}
+	return io.MultiWriter(w, logWriter)
+}
+
+// startProcess starts cmd in its own process group and reports its PID
+// through the context's process hook. The returned wait blocks until the
+// process exits.
+func startProcess(ctx context.Context, cmd *exec.Cmd) (wait func() error, err error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cmd.Cancel != nil {
+		cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("agent start: %w", err)
+	}
+	provider.ProcessStarted(ctx, cmd.Process.Pid)
+	return func() error {
+		defer provider.ProcessStarted(ctx, 0)
+		return cmd.Wait()
+	}, nil
+}
+
+// processError describes an agent process that exited with err; detail is
+// the output explaining the failure. AgentPool matches on the message to
+// decide whether another agent should retry, so detail must be kept.
+func processError(ctx context.Context, err error, timeout time.Duration, detail string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("agent timed out after %s", timeout)
+	}
+	return fmt.Errorf("agent failed: %w: %s", err, detail)
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunProcess_ReportsPID(t *testing.T) {
+	var pids []int
+	ctx := provider.WithProcessHook(context.Background(), func(pid int) { pids = append(pids, pid) })
+
+	out, err := runProcess(ctx, exec.CommandContext(ctx, "sh", "-c", "echo hi"), nil, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", out)
+
+	require.Len(t, pids, 2)
+	assert.Positive(t, pids[0])
+	assert.Equal(t, 0, pids[1])
+}
+
+func TestRunProcess_CancelKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = provider.WithProcessHook(ctx, func(pid int) {
+		if pid != 0 {
+			cancel()
+		}
+	})
+
+	// The grandchild sleep holds the output pipe open; only a group kill
+	// lets Wait return promptly.
+	start := time.Now()
+	_, err := runProcess(ctx, exec.CommandContext(ctx, "sh", "-c", "sleep 60 & wait"), nil, time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent failed")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
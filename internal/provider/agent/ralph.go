@@ -1,7 +1,6 @@
 package agent
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -9,7 +8,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sync"
 	"time"
 )
 
@@ -18,6 +16,8 @@ type Ralph struct {
 	Timeout      time.Duration
 	Logger       *slog.Logger
 	AllowedTools string
+	Model        string   // passed to claude as ANTHROPIC_MODEL; empty uses its default
+	Env          []string // extra KEY=VALUE entries for the agent process
 
 	// LogWriter, when non-nil, receives a real-time copy of agent stdout+stderr.
 	LogWriter io.Writer
@@ -90,53 +90,18 @@ func (r *Ralph) Run(ctx context.Context, dir, prompt string) (string, error) {
 	cmd.Dir = dir
 	// Pass allowed tools via env var so ralph forwards them as a single
 	// comma-separated string to claude --allowedTools (matching CLI format).
+	env := r.Env
 	if r.AllowedTools != "" {
-		cmd.Env = append(os.Environ(), "CLAUDE_ALLOWED_TOOLS="+r.AllowedTools)
+		env = append(env, "CLAUDE_ALLOWED_TOOLS="+r.AllowedTools)
 	}
-
-	// When no LogWriter is set, use simple CombinedOutput (original behavior).
-	if r.LogWriter == nil {
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return string(out), fmt.Errorf("agent timed out after %s", r.Timeout)
-			}
-			return string(out), fmt.Errorf("agent failed: %w: %s", err, out)
-		}
-		r.Logger.Info("ralph agent completed")
-		return string(out), nil
-	}
-
-	// Streaming: tee stdout+stderr to both a buffer and LogWriter.
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("agent stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("agent start: %w", err)
+	if r.Model != "" {
+		env = append(env, "ANTHROPIC_MODEL="+r.Model)
 	}
+	cmd.Env = processEnv(env)
 
-	var buf bytes.Buffer
-	w := io.MultiWriter(&buf, r.LogWriter)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stdout) }()
-	go func() { defer wg.Done(); _, _ = io.Copy(w, stderr) }()
-	wg.Wait()
-
-	err = cmd.Wait()
-	out := buf.String()
+	out, err := runProcess(ctx, cmd, r.LogWriter, r.Timeout)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return out, fmt.Errorf("agent timed out after %s", r.Timeout)
-		}
-		return out, fmt.Errorf("agent failed: %w: %s", err, out)
+		return out, err
 	}
 
 	r.Logger.Info("ralph agent completed")
@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// Replay modes.
+const (
+	ReplayRecord = "record" // run the wrapped agent and save each call to the cassette
+	ReplayPlay   = "replay" // serve calls from the cassette without running any agent
+)
+
+// interaction is one recorded Agent.Run call, stored as <seq>.yaml in the
+// cassette with its worktree changes in <seq>.patch.
+type interaction struct {
+	PromptHash string `yaml:"prompt_hash"`
+	Agent      string `yaml:"agent,omitempty"` // provider that produced the output
+	Prompt     string `yaml:"prompt"`
+	Output     string `yaml:"output"`
+	Error      string `yaml:"error,omitempty"`
+	Patch      string `yaml:"patch,omitempty"` // patch file name; empty when the call changed nothing
+
+	file string // yaml file name, set when loaded
+	used bool
+}
+
+// Replay implements provider.Agent by recording another agent's calls to a
+// cassette directory and replaying them later, for offline, deterministic
+// pipeline tests. Each call is keyed by its prompt's SHA-256; replay applies
+// the recorded worktree patch and returns the recorded output and error.
+// Identical prompts are served in recording order. A prompt that was never
+// recorded (e.g. a hook-fix prompt quoting test timings) gets the next unused
+// call in order, unless Strict is set.
+type Replay struct {
+	Mode     string         // ReplayRecord or ReplayPlay
+	Cassette string         // directory holding the recorded calls
+	Strict   bool           // replay: fail on prompts that weren't recorded
+	Inner    provider.Agent // agent recorded; in replay mode only its prompt suffix and output parser are used
+	Name     string         // Inner's provider name, stored with each call
+	Logger   *slog.Logger
+
+	// LogWriter, when non-nil, receives the replayed output (recording streams
+	// through Inner instead).
+	LogWriter io.Writer
+
+	mu     sync.Mutex
+	calls  []*interaction // replay mode: loaded lazily
+	loaded bool
+}
+
+// NewReplay creates a replay agent over inner, the agent named name.
+func NewReplay(mode, cassette string, strict bool, inner provider.Agent, name string, logger *slog.Logger) *Replay {
+	return &Replay{Mode: mode, Cassette: cassette, Strict: strict, Inner: inner, Name: name, Logger: logger}
+}
+
+// SetLogWriter sets the writer that receives agent output, passing it on to
+// the recorded agent.
+func (r *Replay) SetLogWriter(w io.Writer) {
+	r.LogWriter = w
+	if lw, ok := r.Inner.(interface{ SetLogWriter(io.Writer) }); ok {
+		lw.SetLogWriter(w)
+	}
+}
+
+// ClearLogWriter removes the streaming log writer.
+func (r *Replay) ClearLogWriter() {
+	r.LogWriter = nil
+	if lw, ok := r.Inner.(interface{ ClearLogWriter() }); ok {
+		lw.ClearLogWriter()
+	}
+}
+
+// PromptSuffix is the recorded agent's, so prompts hash the same in both modes.
+func (r *Replay) PromptSuffix() string { return r.Inner.PromptSuffix() }
+
+// ParseResult parses output with the recorded agent's parser.
+func (r *Replay) ParseResult(output string) provider.AgentResult {
+	if p, ok := r.Inner.(provider.ResultParser); ok {
+		return p.ParseResult(output)
+	}
+	return provider.AgentResult{Text: output}
+}
+
+func (r *Replay) Run(ctx context.Context, dir, prompt string) (string, error) {
+	if r.Mode == ReplayRecord {
+		return r.record(ctx, dir, prompt)
+	}
+	return r.replay(ctx, dir, prompt)
+}
+
+// record runs Inner and saves the call with the worktree changes it made.
+func (r *Replay) record(ctx context.Context, dir, prompt string) (string, error) {
+	before, err := snapshotTree(ctx, dir)
+	if err != nil {
+		return "", fmt.Errorf("replay: recording: %w", err)
+	}
+
+	out, runErr := r.Inner.Run(ctx, dir, prompt)
+
+	after, err := snapshotTree(ctx, dir)
+	if err != nil {
+		return out, fmt.Errorf("replay: recording: %w", err)
+	}
+	patch, err := gitOutput(ctx, dir, nil, "diff", "--binary", before, after)
+	if err != nil {
+		return out, fmt.Errorf("replay: recording: %w", err)
+	}
+
+	call := &interaction{PromptHash: promptHash(prompt), Agent: r.Name, Prompt: prompt, Output: out}
+	if runErr != nil {
+		call.Error = runErr.Error()
+	}
+	if err := r.save(call, patch); err != nil {
+		return out, fmt.Errorf("replay: recording: %w", err)
+	}
+	r.Logger.Info("recorded agent call", "cassette", r.Cassette, "file", call.file, "changed", patch != "")
+	return out, runErr
+}
+
+// save writes call and its patch under the next sequence number.
+func (r *Replay) save(call *interaction, patch string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.Cassette, 0o755); err != nil {
+		return fmt.Errorf("creating cassette: %w", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(r.Cassette, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("listing cassette: %w", err)
+	}
+	seq := fmt.Sprintf("%04d", len(existing)+1)
+
+	if patch != "" {
+		call.Patch = seq + ".patch"
+		if err := os.WriteFile(filepath.Join(r.Cassette, call.Patch), []byte(patch), 0o644); err != nil {
+			return fmt.Errorf("writing patch: %w", err)
+		}
+	}
+	data, err := yaml.Marshal(call)
+	if err != nil {
+		return fmt.Errorf("marshaling call: %w", err)
+	}
+	call.file = seq + ".yaml"
+	if err := os.WriteFile(filepath.Join(r.Cassette, call.file), data, 0o644); err != nil {
+		return fmt.Errorf("writing call: %w", err)
+	}
+	return nil
+}
+
+// replay serves the next unused recorded call for prompt.
+func (r *Replay) replay(ctx context.Context, dir, prompt string) (string, error) {
+	call, err := r.next(promptHash(prompt))
+	if err != nil {
+		return "", err
+	}
+
+	if call.Patch != "" {
+		patch := filepath.Join(r.Cassette, call.Patch)
+		abs, err := filepath.Abs(patch)
+		if err != nil {
+			return "", fmt.Errorf("replay: %w", err)
+		}
+		if _, err := gitOutput(ctx, dir, nil, "apply", "--binary", "--whitespace=nowarn", abs); err != nil {
+			return "", fmt.Errorf("replay: applying %s: %w", call.Patch, err)
+		}
+	}
+	if r.LogWriter != nil {
+		_, _ = io.WriteString(r.LogWriter, call.Output)
+	}
+
+	r.Logger.Info("replayed agent call", "cassette", r.Cassette, "file", call.file)
+	if call.Error != "" {
+		return call.Output, errors.New(call.Error)
+	}
+	return call.Output, nil
+}
+
+// next claims the first unused call recorded for hash, or failing that
+// (unless strict) the first unused call.
+func (r *Replay) next(hash string) (*interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.loaded {
+		calls, err := loadCassette(r.Cassette)
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		r.calls, r.loaded = calls, true
+	}
+	for _, call := range r.calls {
+		if !call.used && call.PromptHash == hash {
+			call.used = true
+			return call, nil
+		}
+	}
+	if !r.Strict {
+		for _, call := range r.calls {
+			if !call.used {
+				r.Logger.Warn("prompt not in cassette, replaying next call in order", "prompt_hash", hash[:12], "file", call.file)
+				call.used = true
+				return call, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("replay: no recorded call left for prompt %s in %s", hash[:12], r.Cassette)
+}
+
+// loadCassette reads all recorded calls in sequence order.
+func loadCassette(dir string) ([]*interaction, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing cassette: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("cassette %s is empty or missing", dir)
+	}
+	sort.Strings(files)
+
+	calls := make([]*interaction, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
+		var call interaction
+		if err := yaml.Unmarshal(data, &call); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", f, err)
+		}
+		call.file = filepath.Base(f)
+		calls = append(calls, &call)
+	}
+	return calls, nil
+}
+
+func promptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotTree writes the worktree's current files, tracked or not (minus
+// ignored ones), as a git tree and returns its hash. A throwaway index keeps
+// the worktree's own index untouched.
+func snapshotTree(ctx context.Context, dir string) (string, error) {
+	// git wants to create the index itself, so reserve a unique name and free it.
+	f, err := os.CreateTemp("", "forge-replay-index-*")
+	if err != nil {
+		return "", fmt.Errorf("snapshotting worktree: %w", err)
+	}
+	index := f.Name()
+	_ = f.Close()
+	_ = os.Remove(index)
+	defer func() { _ = os.Remove(index) }()
+	env := []string{"GIT_INDEX_FILE=" + index}
+
+	if _, err := gitOutput(ctx, dir, env, "add", "-A"); err != nil {
+		return "", fmt.Errorf("snapshotting worktree: %w", err)
+	}
+	tree, err := gitOutput(ctx, dir, env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("snapshotting worktree: %w", err)
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// gitOutput runs git in dir and returns its stdout.
+func gitOutput(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
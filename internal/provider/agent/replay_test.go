@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// editingAgent writes files into the worktree and returns a fixed output.
+type editingAgent struct {
+	files  map[string]string
+	remove []string
+	output string
+	err    error
+	calls  int
+}
+
+func (e *editingAgent) PromptSuffix() string { return "\nUse JSON." }
+
+func (e *editingAgent) Run(_ context.Context, dir, _ string) (string, error) {
+	e.calls++
+	for name, content := range e.files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range e.remove {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return "", err
+		}
+	}
+	return e.output, e.err
+}
+
+func (e *editingAgent) ParseResult(output string) provider.AgentResult {
+	return provider.AgentResult{Text: "parsed: " + output}
+}
+
+// initRepo creates a git repo with one committed file.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("obsolete\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	return dir
+}
+
+func TestReplay_RecordThenReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassette")
+	inner := &editingAgent{
+		files:  map[string]string{"main.go": "package main\n\nfunc main() {}\n", "pkg/new.go": "package pkg\n"},
+		remove: []string{"old.txt"},
+		output: `{"result":"done"}`,
+	}
+
+	recDir := initRepo(t)
+	rec := NewReplay(ReplayRecord, cassette, false, inner, "claude", testLogger())
+	out, err := rec.Run(context.Background(), recDir, "implement main")
+	require.NoError(t, err)
+	assert.Equal(t, `{"result":"done"}`, out)
+	assert.FileExists(t, filepath.Join(cassette, "0001.yaml"))
+	assert.FileExists(t, filepath.Join(cassette, "0001.patch"))
+
+	status, err := exec.Command("git", "-C", recDir, "diff", "--cached", "--name-only").Output()
+	require.NoError(t, err)
+	assert.Empty(t, string(status), "recording must not touch the worktree's index")
+
+	playDir := initRepo(t)
+	inner.calls = 0
+	var logBuf bytes.Buffer
+	play := NewReplay(ReplayPlay, cassette, true, inner, "claude", testLogger())
+	play.SetLogWriter(&logBuf)
+	out, err = play.Run(context.Background(), playDir, "implement main")
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"result":"done"}`, out)
+	assert.Zero(t, inner.calls, "replay must not run the recorded agent")
+	assert.Equal(t, `{"result":"done"}`, logBuf.String())
+	for name, content := range inner.files {
+		data, err := os.ReadFile(filepath.Join(playDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+	assert.NoFileExists(t, filepath.Join(playDir, "old.txt"))
+
+	assert.Equal(t, "\nUse JSON.", play.PromptSuffix())
+	assert.Equal(t, "parsed: x", play.ParseResult("x").Text)
+}
+
+func TestReplay_ReplaysErrorsAndNoChanges(t *testing.T) {
+	cassette := t.TempDir()
+	inner := &editingAgent{output: "partial", err: errors.New("agent failed: 429 rate limit")}
+
+	rec := NewReplay(ReplayRecord, cassette, false, inner, "claude", testLogger())
+	_, err := rec.Run(context.Background(), initRepo(t), "p")
+	require.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(cassette, "0001.patch"))
+
+	play := NewReplay(ReplayPlay, cassette, true, inner, "claude", testLogger())
+	out, err := play.Run(context.Background(), initRepo(t), "p")
+	assert.Equal(t, "partial", out)
+	require.EqualError(t, err, "agent failed: 429 rate limit")
+}
+
+func TestReplay_PromptMatching(t *testing.T) {
+	cassette := t.TempDir()
+	dir := initRepo(t)
+	inner := &editingAgent{}
+	rec := NewReplay(ReplayRecord, cassette, false, inner, "claude", testLogger())
+	for _, p := range []string{"first", "second", "first"} {
+		inner.output = "out-" + p
+		_, err := rec.Run(context.Background(), dir, p)
+		require.NoError(t, err)
+	}
+
+	play := NewReplay(ReplayPlay, cassette, false, inner, "claude", testLogger())
+	for _, tc := range []struct{ prompt, want string }{
+		{"first", "out-first"},
+		{"first", "out-first"},
+		{"changed prompt", "out-second"}, // not recorded: next unused call in order
+	} {
+		out, err := play.Run(context.Background(), dir, tc.prompt)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, out, tc.prompt)
+	}
+	_, err := play.Run(context.Background(), dir, "first")
+	require.Error(t, err, "cassette exhausted")
+
+	strict := NewReplay(ReplayPlay, cassette, true, inner, "claude", testLogger())
+	_, err = strict.Run(context.Background(), dir, "changed prompt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded call left")
+}
+
+func TestReplay_MissingCassette(t *testing.T) {
+	play := NewReplay(ReplayPlay, filepath.Join(t.TempDir(), "none"), false, &editingAgent{}, "claude", testLogger())
+	_, err := play.Run(context.Background(), t.TempDir(), "p")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty or missing")
+}
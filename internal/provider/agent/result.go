@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shahar-caura/forge/internal/provider"
+)
+
+// ParseResult parses claude's --output-format json result.
+func (c *Claude) ParseResult(output string) provider.AgentResult { return parseClaudeResult(output) }
+
+// ParseResult parses the claude result ralph prints with --output-format json.
+func (r *Ralph) ParseResult(output string) provider.AgentResult { return parseClaudeResult(output) }
+
+// ParseResult parses codex's exec --json event stream.
+func (c *Codex) ParseResult(output string) provider.AgentResult { return parseCodexResult(output) }
+
+// ParseResult parses gemini's --output-format json response.
+func (g *Gemini) ParseResult(output string) provider.AgentResult { return parseGeminiResult(output) }
+
+// ParseResult parses aider's plain-text transcript.
+func (a *Aider) ParseResult(output string) provider.AgentResult { return parseAiderResult(output) }
+
+// claudeResult is the envelope claude -p --output-format json prints when done.
+type claudeResult struct {
+	Type         string  `json:"type"`
+	Subtype      string  `json:"subtype"` // success, error_max_turns, error_during_execution
+	Result       string  `json:"result"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	CostUSD      float64 `json:"cost_usd"` // older claude releases
+	Usage        struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// parseClaudeResult reads the result envelope, which is the whole output or,
+// when stderr is mixed in, its last "type":"result" line. Claude's json
+// format doesn't list tool calls or files.
+func parseClaudeResult(output string) provider.AgentResult {
+	var env claudeResult
+	found := json.Unmarshal([]byte(output), &env) == nil
+	if !found {
+		for _, line := range jsonLines(output) {
+			var candidate claudeResult
+			if json.Unmarshal([]byte(line), &candidate) == nil && candidate.Type == "result" {
+				env, found = candidate, true
+			}
+		}
+	}
+
+	res := provider.AgentResult{Text: output}
+	if !found {
+		return res
+	}
+	if env.Result != "" {
+		res.Text = env.Result
+	}
+	res.ExitReason = env.Subtype
+	res.Usage = provider.Usage{
+		InputTokens:         env.Usage.InputTokens,
+		OutputTokens:        env.Usage.OutputTokens,
+		CacheCreationTokens: env.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     env.Usage.CacheReadInputTokens,
+		CostUSD:             env.TotalCostUSD,
+	}
+	if res.Usage.CostUSD == 0 {
+		res.Usage.CostUSD = env.CostUSD
+	}
+	return res
+}
+
+// codexEvent is one line of codex exec --json output.
+type codexEvent struct {
+	Type string `json:"type"` // thread.started, turn.completed, turn.failed, item.completed, error, ...
+	Item *struct {
+		Type    string `json:"type"` // agent_message, command_execution, file_change, mcp_tool_call, web_search, reasoning
+		Text    string `json:"text"`
+		Command string `json:"command"`
+		Tool    string `json:"tool"`
+		Query   string `json:"query"`
+		Changes []struct {
+			Path string `json:"path"`
+		} `json:"changes"`
+	} `json:"item"`
+	Usage *struct {
+		InputTokens       int64 `json:"input_tokens"`        // includes cached
+		CachedInputTokens int64 `json:"cached_input_tokens"` // served from cache
+		OutputTokens      int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// parseCodexResult folds codex's event stream: the last agent message is the
+// reply, commands and tools become tool calls, file changes the files touched,
+// and usage is summed over turns.
+func parseCodexResult(output string) provider.AgentResult {
+	res := provider.AgentResult{}
+	seen := make(map[string]bool)
+	parsed := false
+
+	for _, line := range jsonLines(output) {
+		var ev codexEvent
+		if json.Unmarshal([]byte(line), &ev) != nil || ev.Type == "" {
+			continue
+		}
+		parsed = true
+
+		switch ev.Type {
+		case "turn.completed":
+			res.ExitReason = "completed"
+		case "turn.failed":
+			res.ExitReason = "failed"
+		case "error":
+			res.ExitReason = "error"
+		}
+		if ev.Usage != nil {
+			res.Usage.InputTokens += ev.Usage.InputTokens - ev.Usage.CachedInputTokens
+			res.Usage.CacheReadTokens += ev.Usage.CachedInputTokens
+			res.Usage.OutputTokens += ev.Usage.OutputTokens
+		}
+		if ev.Type != "item.completed" || ev.Item == nil {
+			continue
+		}
+
+		switch it := ev.Item; it.Type {
+		case "agent_message":
+			res.Text = it.Text
+		case "command_execution":
+			res.ToolCalls = append(res.ToolCalls, provider.ToolCall{Name: it.Type, Input: it.Command})
+		case "mcp_tool_call":
+			res.ToolCalls = append(res.ToolCalls, provider.ToolCall{Name: it.Tool})
+		case "web_search":
+			res.ToolCalls = append(res.ToolCalls, provider.ToolCall{Name: it.Type, Input: it.Query})
+		case "file_change":
+			for _, ch := range it.Changes {
+				if !seen[ch.Path] {
+					seen[ch.Path] = true
+					res.FilesTouched = append(res.FilesTouched, ch.Path)
+				}
+			}
+		}
+	}
+
+	if !parsed || res.Text == "" {
+		res.Text = output
+	}
+	return res
+}
+
+// geminiResult is gemini -p --output-format json output.
+type geminiResult struct {
+	Response string `json:"response"`
+	Stats    struct {
+		Models map[string]struct {
+			Tokens struct {
+				Prompt     int64 `json:"prompt"` // includes cached
+				Candidates int64 `json:"candidates"`
+				Cached     int64 `json:"cached"`
+				Thoughts   int64 `json:"thoughts"`
+			} `json:"tokens"`
+		} `json:"models"`
+		Tools struct {
+			ByName map[string]struct {
+				Count int `json:"count"`
+			} `json:"byName"`
+		} `json:"tools"`
+	} `json:"stats"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// parseGeminiResult reads gemini's JSON document, skipping any log lines
+// printed before it. Gemini reports tool calls as per-tool counts, so each
+// call appears without input.
+func parseGeminiResult(output string) provider.AgentResult {
+	var env geminiResult
+	doc := output
+	if i := strings.Index(output, "\n{"); i >= 0 && !strings.HasPrefix(strings.TrimSpace(output), "{") {
+		doc = output[i+1:]
+	}
+	if json.Unmarshal([]byte(doc), &env) != nil {
+		return provider.AgentResult{Text: output}
+	}
+
+	res := provider.AgentResult{Text: env.Response, ExitReason: "success"}
+	if res.Text == "" {
+		res.Text = output
+	}
+	if env.Error != nil {
+		res.ExitReason = "error"
+		if env.Error.Type != "" {
+			res.ExitReason = env.Error.Type
+		}
+	}
+	for _, m := range env.Stats.Models {
+		res.Usage.InputTokens += m.Tokens.Prompt - m.Tokens.Cached
+		res.Usage.CacheReadTokens += m.Tokens.Cached
+		res.Usage.OutputTokens += m.Tokens.Candidates + m.Tokens.Thoughts
+	}
+
+	names := make([]string, 0, len(env.Stats.Tools.ByName))
+	for name := range env.Stats.Tools.ByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for range env.Stats.Tools.ByName[name].Count {
+			res.ToolCalls = append(res.ToolCalls, provider.ToolCall{Name: name})
+		}
+	}
+	return res
+}
+
+// parseAiderResult reads the usage and edit lines aider prints with
+// --no-pretty. Each model reply is followed by a report like
+//
+//	Tokens: 12k sent, 2.1k cache hit, 500 received. Cost: $0.04 message, $0.09 session.
+//
+// and every changed file by "Applied edit to <path>". Aider has no final
+// reply apart from its transcript, so the whole output is the text.
+func parseAiderResult(output string) provider.AgentResult {
+	res := provider.AgentResult{Text: output}
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if path, ok := strings.CutPrefix(line, "Applied edit to "); ok && !seen[path] {
+			seen[path] = true
+			res.FilesTouched = append(res.FilesTouched, path)
+			continue
+		}
+		report, ok := strings.CutPrefix(line, "Tokens: ")
+		if !ok {
+			continue
+		}
+		report, cost, _ := strings.Cut(report, "Cost: $")
+
+		var sent int64
+		for _, part := range strings.Split(strings.TrimSuffix(strings.TrimSpace(report), "."), ", ") {
+			num, label, _ := strings.Cut(part, " ")
+			n := parseTokenCount(num)
+			switch label {
+			case "sent":
+				sent = n
+			case "received":
+				res.Usage.OutputTokens += n
+			case "cache write":
+				res.Usage.CacheCreationTokens += n
+				sent -= n
+			case "cache hit":
+				res.Usage.CacheReadTokens += n
+				sent -= n
+			}
+		}
+		// "sent" includes the cached prompt tokens reported after it.
+		res.Usage.InputTokens += max(sent, 0)
+
+		if amount, _, ok := strings.Cut(cost, " message"); ok {
+			if usd, err := strconv.ParseFloat(amount, 64); err == nil {
+				res.Usage.CostUSD += usd
+			}
+		}
+	}
+	return res
+}
+
+// parseTokenCount parses aider's abbreviated counts: "512", "2.1k", "12k", "1.5M".
+func parseTokenCount(s string) int64 {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f*mult + 0.5)
+}
+
+// jsonLines returns the lines of output that look like JSON objects.
+func jsonLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "{") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
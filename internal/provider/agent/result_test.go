@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/shahar-caura/forge/internal/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// The agents parse their own output formats.
+var (
+	_ provider.ResultParser = (*Claude)(nil)
+	_ provider.ResultParser = (*Ralph)(nil)
+	_ provider.ResultParser = (*Codex)(nil)
+	_ provider.ResultParser = (*Gemini)(nil)
+	_ provider.ResultParser = (*Aider)(nil)
+)
+
+const claudeOutput = `{"type":"result","subtype":"success","is_error":false,"result":"Implemented auth.","num_turns":7,` +
+	`"total_cost_usd":0.42,"usage":{"input_tokens":1200,"output_tokens":800,"cache_creation_input_tokens":300,"cache_read_input_tokens":9000}}`
+
+func TestParseClaudeResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   provider.AgentResult
+	}{
+		{
+			name:   "result envelope",
+			output: claudeOutput,
+			want: provider.AgentResult{
+				Text:       "Implemented auth.",
+				ExitReason: "success",
+				Usage:      provider.Usage{InputTokens: 1200, OutputTokens: 800, CacheCreationTokens: 300, CacheReadTokens: 9000, CostUSD: 0.42},
+			},
+		},
+		{
+			name:   "legacy cost field",
+			output: `{"type":"result","subtype":"error_max_turns","result":"ran out","cost_usd":0.1,"usage":{"input_tokens":10,"output_tokens":5}}`,
+			want: provider.AgentResult{
+				Text:       "ran out",
+				ExitReason: "error_max_turns",
+				Usage:      provider.Usage{InputTokens: 10, OutputTokens: 5, CostUSD: 0.1},
+			},
+		},
+		{
+			name:   "stderr mixed in",
+			output: "warning: slow network\n" + claudeOutput + "\n",
+			want: provider.AgentResult{
+				Text:       "Implemented auth.",
+				ExitReason: "success",
+				Usage:      provider.Usage{InputTokens: 1200, OutputTokens: 800, CacheCreationTokens: 300, CacheReadTokens: 9000, CostUSD: 0.42},
+			},
+		},
+		{name: "empty result keeps raw output", output: `{"result": ""}`, want: provider.AgentResult{Text: `{"result": ""}`}},
+		{name: "other JSON", output: `{"other": "value"}`, want: provider.AgentResult{Text: `{"other": "value"}`}},
+		{name: "plain text", output: "just plain text", want: provider.AgentResult{Text: "just plain text"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&Claude{}).ParseResult(tt.output))
+		})
+	}
+}
+
+func TestParseCodexResult(t *testing.T) {
+	output := `{"type":"thread.started","thread_id":"t1"}
+{"type":"turn.started"}
+{"type":"item.completed","item":{"id":"i0","type":"reasoning","text":"thinking"}}
+{"type":"item.completed","item":{"id":"i1","type":"command_execution","command":"bash -lc 'go test ./...'","exit_code":0,"status":"completed"}}
+{"type":"item.completed","item":{"id":"i2","type":"file_change","changes":[{"path":"auth.go","kind":"update"},{"path":"auth_test.go","kind":"add"}],"status":"completed"}}
+{"type":"item.completed","item":{"id":"i3","type":"mcp_tool_call","server":"docs","tool":"search","status":"completed"}}
+{"type":"item.completed","item":{"id":"i4","type":"file_change","changes":[{"path":"auth.go","kind":"update"}],"status":"completed"}}
+{"type":"item.completed","item":{"id":"i5","type":"agent_message","text":"Added login."}}
+{"type":"turn.completed","usage":{"input_tokens":1000,"cached_input_tokens":600,"output_tokens":200}}
+{"type":"turn.completed","usage":{"input_tokens":500,"cached_input_tokens":0,"output_tokens":50}}`
+
+	assert.Equal(t, provider.AgentResult{
+		Text: "Added login.",
+		ToolCalls: []provider.ToolCall{
+			{Name: "command_execution", Input: "bash -lc 'go test ./...'"},
+			{Name: "search"},
+		},
+		FilesTouched: []string{"auth.go", "auth_test.go"},
+		Usage:        provider.Usage{InputTokens: 900, OutputTokens: 250, CacheReadTokens: 600},
+		ExitReason:   "completed",
+	}, (&Codex{}).ParseResult(output))
+}
+
+func TestParseCodexResult_Failed(t *testing.T) {
+	output := `{"type":"turn.started"}
+{"type":"turn.failed","error":{"message":"stream disconnected"}}`
+
+	res := (&Codex{}).ParseResult(output)
+	assert.Equal(t, "failed", res.ExitReason)
+	assert.Equal(t, output, res.Text, "no agent message: the raw stream is the text")
+}
+
+func TestParseCodexResult_PlainText(t *testing.T) {
+	assert.Equal(t, provider.AgentResult{Text: "done"}, (&Codex{}).ParseResult("done"))
+}
+
+func TestParseGeminiResult(t *testing.T) {
+	output := `Loaded cached credentials.
+{
+  "response": "Refactored the parser.",
+  "stats": {
+    "models": {"gemini-2.5-pro": {"tokens": {"prompt": 2000, "candidates": 300, "cached": 500, "thoughts": 100, "total": 2400}}},
+    "tools": {"totalCalls": 3, "byName": {"write_file": {"count": 1}, "read_file": {"count": 2}}},
+    "files": {"totalLinesAdded": 10, "totalLinesRemoved": 2}
+  }
+}`
+
+	assert.Equal(t, provider.AgentResult{
+		Text:       "Refactored the parser.",
+		ToolCalls:  []provider.ToolCall{{Name: "read_file"}, {Name: "read_file"}, {Name: "write_file"}},
+		Usage:      provider.Usage{InputTokens: 1500, OutputTokens: 400, CacheReadTokens: 500},
+		ExitReason: "success",
+	}, (&Gemini{}).ParseResult(output))
+}
+
+func TestParseGeminiResult_Error(t *testing.T) {
+	res := (&Gemini{}).ParseResult(`{"error": {"type": "FatalAuthenticationError", "message": "no credentials", "code": 41}}`)
+	assert.Equal(t, "FatalAuthenticationError", res.ExitReason)
+}
+
+func TestParseGeminiResult_PlainText(t *testing.T) {
+	assert.Equal(t, provider.AgentResult{Text: "oops"}, (&Gemini{}).ParseResult("oops"))
+}
+
+func TestParseAiderResult(t *testing.T) {
+	output := `Aider v0.86.1
+Main model: anthropic/claude-sonnet-4 with diff edit format
+Added internal/auth/login.go to the chat.
+
+I'll fix the nil check.
+
+internal/auth/login.go
+Applied edit to internal/auth/login.go
+Tokens: 12k sent, 2.1k cache hit, 500 received. Cost: $0.04 message, $0.04 session.
+Applied edit to internal/auth/login_test.go
+Applied edit to internal/auth/login.go
+Tokens: 1.5k sent, 1,200 received. Cost: $0.01 message, $0.05 session.`
+
+	res := (&Aider{}).ParseResult(output)
+	assert.Equal(t, output, res.Text)
+	assert.Equal(t, []string{"internal/auth/login.go", "internal/auth/login_test.go"}, res.FilesTouched)
+	assert.Equal(t, provider.Usage{InputTokens: 11400, OutputTokens: 1700, CacheReadTokens: 2100, CostUSD: 0.05}, roundCost(res.Usage))
+}
+
+func TestParseAiderResult_PlainText(t *testing.T) {
+	assert.Equal(t, provider.AgentResult{Text: "done"}, (&Aider{}).ParseResult("done"))
+}
+
+// roundCost rounds CostUSD to cents so summed floats compare equal.
+func roundCost(u provider.Usage) provider.Usage {
+	u.CostUSD = math.Round(u.CostUSD*100) / 100
+	return u
+}
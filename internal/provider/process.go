@@ -0,0 +1,24 @@
+package provider
+
+import "context"
+
+// ProcessHook is called with the PID of a child process when an agent starts
+// it, and with 0 once that process has exited. The pipeline uses it to record
+// the agent PID so forge cancel can kill the agent's process group.
+type ProcessHook func(pid int)
+
+type processHookKey struct{}
+
+// WithProcessHook returns a context that carries hook for providers to report
+// the child processes they spawn.
+func WithProcessHook(ctx context.Context, hook ProcessHook) context.Context {
+	return context.WithValue(ctx, processHookKey{}, hook)
+}
+
+// ProcessStarted reports pid to the hook carried by ctx, if any.
+// Pass 0 when the process has exited.
+func ProcessStarted(ctx context.Context, pid int) {
+	if hook, ok := ctx.Value(processHookKey{}).(ProcessHook); ok && hook != nil {
+		hook(pid)
+	}
+}
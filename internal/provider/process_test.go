@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessStarted_CallsHook(t *testing.T) {
+	var pids []int
+	ctx := WithProcessHook(context.Background(), func(pid int) { pids = append(pids, pid) })
+
+	ProcessStarted(ctx, 42)
+	ProcessStarted(ctx, 0)
+
+	assert.Equal(t, []int{42, 0}, pids)
+}
+
+func TestProcessStarted_NoHook(t *testing.T) {
+	assert.NotPanics(t, func() { ProcessStarted(context.Background(), 42) })
+}
+
+func TestRunInfoFromContext(t *testing.T) {
+	assert.Equal(t, RunInfo{}, RunInfoFromContext(context.Background()))
+
+	ctx := WithRunInfo(context.Background(), RunInfo{RunID: "r1", Step: "run agent"})
+	assert.Equal(t, RunInfo{RunID: "r1", Step: "run agent"}, RunInfoFromContext(ctx))
+}
@@ -0,0 +1,23 @@
+package provider
+
+import "context"
+
+// RunInfo identifies the forge run, and the step within it, that an agent
+// is invoked for. Agents that hand off to external programs pass it on.
+type RunInfo struct {
+	RunID string
+	Step  string
+}
+
+type runInfoKey struct{}
+
+// WithRunInfo returns a context carrying info.
+func WithRunInfo(ctx context.Context, info RunInfo) context.Context {
+	return context.WithValue(ctx, runInfoKey{}, info)
+}
+
+// RunInfoFromContext returns the RunInfo carried by ctx; zero outside a run.
+func RunInfoFromContext(ctx context.Context) RunInfo {
+	info, _ := ctx.Value(runInfoKey{}).(RunInfo)
+	return info
+}
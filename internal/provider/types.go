@@ -4,8 +4,10 @@ import "context"
 
 // PR represents a pull request created by the VCS provider.
 type PR struct {
-	URL    string
-	Number int
+	URL      string
+	Number   int
+	Base     string // branch the PR merges into
+	Existing bool   // CreatePR adopted a PR that was already open for the branch
 }
 
 // GitHubIssue represents a GitHub issue fetched by the VCS provider.
@@ -34,6 +36,8 @@ type Comment struct {
 type VCS interface {
 	CommitAndPush(ctx context.Context, dir, branch, message string) error
 	Push(ctx context.Context, dir, branch string) error
+	// CreatePR opens a PR for branch, or adopts the PR already open for it
+	// (updating its title, body and base), so resumes and re-runs are safe.
 	CreatePR(ctx context.Context, branch, baseBranch, title, body string) (*PR, error)
 	GetPRComments(ctx context.Context, prNumber int) ([]Comment, error)
 	PostPRComment(ctx context.Context, prNumber int, body string) error
@@ -41,6 +45,9 @@ type VCS interface {
 	AmendAndForcePushMsg(ctx context.Context, dir, branch, message string) error
 	HasChanges(ctx context.Context, dir string) (bool, error)
 	FetchAndRebase(ctx context.Context, dir, baseBranch string) error
+	FetchBase(ctx context.Context, dir, baseBranch string) error
+	RebaseOnto(ctx context.Context, dir, branch, oldBase, newBase string) error
+	UpdatePRBase(ctx context.Context, prNumber int, baseBranch string) error
 	GetIssue(ctx context.Context, number int) (*GitHubIssue, error)
 	ListIssues(ctx context.Context, state string, label string) ([]GitHubIssue, error)
 	GetPRState(ctx context.Context, prNumber int) (string, error)
@@ -55,7 +62,41 @@ type Agent interface {
 	PromptSuffix() string
 }
 
+// AgentResult is an agent invocation's output in structured form, parsed from
+// the provider's own output format. Fields a format doesn't report stay empty.
+type AgentResult struct {
+	Text         string     // final reply; the raw output when it can't be parsed
+	ToolCalls    []ToolCall // tool invocations, in order
+	FilesTouched []string   // files the agent changed
+	Usage        Usage
+	ExitReason   string // why the agent stopped, in the provider's terms (e.g. "success", "error_max_turns", "failed")
+}
+
+// ToolCall is one tool invocation reported by an agent.
+type ToolCall struct {
+	Name  string // tool name as the agent reports it (e.g. "command_execution", "read_file")
+	Input string // command or primary argument, when reported
+}
+
+// Usage is the token and cost accounting of an agent invocation.
+// InputTokens excludes cached prompt tokens. CostUSD is zero when the agent
+// doesn't report cost.
+type Usage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	CostUSD             float64
+}
+
+// ResultParser is implemented by agents that can parse their Run output into
+// an AgentResult. Output of agents without it is treated as plain text.
+type ResultParser interface {
+	ParseResult(output string) AgentResult
+}
+
 // Worktree manages isolated working directories for parallel development.
+// baseBranch may be any ref: a branch, a remote ref like origin/main, or another run's branch.
 type Worktree interface {
 	Create(ctx context.Context, branch, baseBranch string) (path string, err error)
 	Remove(ctx context.Context, path string) error
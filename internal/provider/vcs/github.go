@@ -74,7 +74,30 @@ func (g *GitHub) Push(ctx context.Context, dir, branch string) error {
 	return nil
 }
 
+// CreatePR opens a PR for branch. If a previous attempt already opened one
+// (gh failed after GitHub accepted the request, or the run is resumed from
+// "create pr"), that PR is updated with title and body, retargeted to
+// baseBranch if its base differs (e.g. a restacked branch), and returned.
 func (g *GitHub) CreatePR(ctx context.Context, branch, baseBranch, title, body string) (*provider.PR, error) {
+	existing, err := g.FindPR(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if err := g.editPR(ctx, existing.Number, title, body); err != nil {
+			return nil, err
+		}
+		if existing.Base != baseBranch {
+			if err := g.UpdatePRBase(ctx, existing.Number, baseBranch); err != nil {
+				return nil, err
+			}
+			existing.Base = baseBranch
+		}
+		existing.Existing = true
+		g.Logger.Info("adopted existing PR", "url", existing.URL, "number", existing.Number)
+		return existing, nil
+	}
+
 	g.Logger.Info("creating PR", "branch", branch, "base", baseBranch)
 
 	cmd := g.commandContext(ctx, "gh", "pr", "create",
@@ -92,7 +115,7 @@ func (g *GitHub) CreatePR(ctx context.Context, branch, baseBranch, title, body s
 
 	url := strings.TrimSpace(string(out))
 
-	pr := &provider.PR{URL: url}
+	pr := &provider.PR{URL: url, Base: baseBranch}
 
 	// Best-effort parse PR number from URL.
 	if parts := strings.Split(url, "/"); len(parts) > 0 {
@@ -105,6 +128,55 @@ func (g *GitHub) CreatePR(ctx context.Context, branch, baseBranch, title, body s
 	return pr, nil
 }
 
+// FindPR returns the open PR whose head is branch, or nil if there is none.
+func (g *GitHub) FindPR(ctx context.Context, branch string) (*provider.PR, error) {
+	g.Logger.Info("looking up PR", "branch", branch)
+
+	cmd := g.commandContext(ctx, "gh", "pr", "list",
+		"--repo", g.Repo,
+		"--head", branch,
+		"--state", "open",
+		"--json", "number,url,baseRefName",
+		"--limit", "1",
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("gh pr list: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var prs []struct {
+		Number      int    `json:"number"`
+		URL         string `json:"url"`
+		BaseRefName string `json:"baseRefName"`
+	}
+	if err := json.Unmarshal(out, &prs); err != nil {
+		return nil, fmt.Errorf("parsing PR list JSON: %w", err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &provider.PR{URL: prs[0].URL, Number: prs[0].Number, Base: prs[0].BaseRefName}, nil
+}
+
+// editPR replaces the title and body of PR prNumber.
+func (g *GitHub) editPR(ctx context.Context, prNumber int, title, body string) error {
+	g.Logger.Info("updating PR", "pr", prNumber)
+
+	cmd := g.commandContext(ctx, "gh", "pr", "edit",
+		strconv.Itoa(prNumber),
+		"--repo", g.Repo,
+		"--title", title,
+		"--body", body,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 func (g *GitHub) GetPRComments(ctx context.Context, prNumber int) ([]provider.Comment, error) {
 	g.Logger.Info("fetching PR comments", "pr", prNumber)
 
@@ -266,6 +338,88 @@ func (g *GitHub) FetchAndRebase(ctx context.Context, dir, baseBranch string) err
 	return nil
 }
 
+// FetchBase updates origin/<baseBranch> so new worktrees can branch from the latest base.
+func (g *GitHub) FetchBase(ctx context.Context, dir, baseBranch string) error {
+	g.Logger.Info("fetching latest base branch", "base", baseBranch)
+
+	cmd := g.commandContext(ctx, "git", "fetch", "origin", baseBranch)
+	cmd.Dir = dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git fetch: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RebaseOnto moves branch's own commits (those after oldBase) onto origin/<newBase>
+// and force-pushes. Used to restack a branch once the branch it was stacked on merges;
+// fetch newBase first (see FetchBase). dir is branch's worktree, or "" when it has
+// none (e.g. removed after the run), to rebase in a temporary worktree of the
+// repository in the current directory.
+func (g *GitHub) RebaseOnto(ctx context.Context, dir, branch, oldBase, newBase string) error {
+	g.Logger.Info("rebasing stacked branch", "branch", branch, "from", oldBase, "onto", newBase)
+
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "forge-restack-*")
+		if err != nil {
+			return fmt.Errorf("creating temporary worktree dir: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+
+		// Drop the registration of the removed worktree so branch can be checked out.
+		prune := g.commandContext(ctx, "git", "worktree", "prune")
+		_ = prune.Run()
+
+		add := g.commandContext(ctx, "git", "worktree", "add", tmp, branch)
+		if out, err := add.CombinedOutput(); err != nil {
+			return fmt.Errorf("git worktree add: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		defer func() {
+			remove := g.commandContext(context.WithoutCancel(ctx), "git", "worktree", "remove", "--force", tmp)
+			_ = remove.Run()
+		}()
+		dir = tmp
+	}
+
+	steps := []struct {
+		name string
+		args []string
+	}{
+		{"git rebase", []string{"git", "rebase", "--onto", "origin/" + newBase, oldBase, branch}},
+		{"git push", []string{"git", "push", "--force-with-lease", "origin", branch}},
+	}
+
+	for i, step := range steps {
+		cmd := g.commandContext(ctx, step.args[0], step.args[1:]...)
+		cmd.Dir = dir
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %w: %s", i+1, step.name, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
+
+// UpdatePRBase changes the branch a pull request targets.
+func (g *GitHub) UpdatePRBase(ctx context.Context, prNumber int, baseBranch string) error {
+	g.Logger.Info("retargeting PR", "pr", prNumber, "base", baseBranch)
+
+	cmd := g.commandContext(ctx, "gh", "pr", "edit",
+		strconv.Itoa(prNumber),
+		"--repo", g.Repo,
+		"--base", baseBranch,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 func (g *GitHub) amendAndForcePush(ctx context.Context, dir, branch, msgFlag, msgValue string) error {
 	g.Logger.Info("amending and force pushing", "branch", branch)
 
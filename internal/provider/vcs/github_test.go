@@ -17,7 +17,9 @@ func testLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 }
 
-// callTracker records which commands were invoked and returns scripted results.
+// callTracker records which commands were invoked and returns scripted results,
+// keyed by command name or, taking precedence, by name plus the first two
+// arguments (e.g. "gh pr list").
 type callTracker struct {
 	calls   []string
 	results map[string]stubResult
@@ -33,6 +35,11 @@ func (ct *callTracker) commandContext(ctx context.Context, name string, args ...
 	ct.calls = append(ct.calls, key)
 
 	r, ok := ct.results[name]
+	if len(args) >= 2 {
+		if sub, found := ct.results[name+" "+args[0]+" "+args[1]]; found {
+			r, ok = sub, true
+		}
+	}
 	if !ok {
 		// Default: succeed silently.
 		return exec.CommandContext(ctx, "true")
@@ -101,7 +108,8 @@ func TestCommitAndPush_RetryAfterPreCommitHook(t *testing.T) {
 
 func TestCreatePR_Success(t *testing.T) {
 	ct := &callTracker{results: map[string]stubResult{
-		"gh": {stdout: "https://github.com/owner/repo/pull/42", exitCode: 0},
+		"gh":         {stdout: "https://github.com/owner/repo/pull/42", exitCode: 0},
+		"gh pr list": {stdout: "[]", exitCode: 0},
 	}}
 	g := New("owner/repo", testLogger())
 	g.commandContext = ct.commandContext
@@ -110,11 +118,78 @@ func TestCreatePR_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "https://github.com/owner/repo/pull/42", pr.URL)
 	assert.Equal(t, 42, pr.Number)
+	assert.False(t, pr.Existing)
+	require.Len(t, ct.calls, 2)
+	assert.Contains(t, ct.calls[1], "gh pr create --repo owner/repo --head feat-branch --base main")
+}
+
+func TestCreatePR_AdoptsExistingPR(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh pr list": {stdout: `[{"number":42,"url":"https://github.com/owner/repo/pull/42","baseRefName":"main"}]`, exitCode: 0},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	pr, err := g.CreatePR(context.Background(), "feat-branch", "main", "Add feature", "new body")
+	require.NoError(t, err)
+	assert.Equal(t, 42, pr.Number)
+	assert.Equal(t, "https://github.com/owner/repo/pull/42", pr.URL)
+	assert.True(t, pr.Existing)
+
+	require.Len(t, ct.calls, 2)
+	assert.Equal(t, "gh pr edit 42 --repo owner/repo --title Add feature --body new body", ct.calls[1])
+	for _, c := range ct.calls {
+		assert.NotContains(t, c, "pr create")
+	}
+}
+
+func TestCreatePR_AdoptedPRRetargetedToBase(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh pr list": {stdout: `[{"number":42,"url":"https://github.com/owner/repo/pull/42","baseRefName":"main"}]`, exitCode: 0},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	// Restacked onto its parent's branch since the PR was opened.
+	pr, err := g.CreatePR(context.Background(), "feat-child", "feat-parent", "Add feature", "body")
+	require.NoError(t, err)
+	assert.True(t, pr.Existing)
+	assert.Equal(t, "feat-parent", pr.Base)
+
+	require.Len(t, ct.calls, 3)
+	assert.Equal(t, "gh pr edit 42 --repo owner/repo --base feat-parent", ct.calls[2])
+}
+
+func TestCreatePR_EditFails(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh pr list": {stdout: `[{"number":42,"url":"https://github.com/owner/repo/pull/42"}]`, exitCode: 0},
+		"gh pr edit": {stdout: "permission denied", exitCode: 1},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	_, err := g.CreatePR(context.Background(), "feat-branch", "main", "title", "body")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gh pr edit")
+}
+
+func TestCreatePR_LookupFails(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh": {stdout: "could not resolve host", exitCode: 1},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	_, err := g.CreatePR(context.Background(), "feat-branch", "main", "title", "body")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gh pr list")
+	assert.Len(t, ct.calls, 1, "no PR is created when the lookup fails")
 }
 
 func TestCreatePR_Failure(t *testing.T) {
 	ct := &callTracker{results: map[string]stubResult{
-		"gh": {stdout: "auth required", exitCode: 1},
+		"gh":         {stdout: "auth required", exitCode: 1},
+		"gh pr list": {stdout: "[]", exitCode: 0},
 	}}
 	g := New("owner/repo", testLogger())
 	g.commandContext = ct.commandContext
@@ -124,6 +199,33 @@ func TestCreatePR_Failure(t *testing.T) {
 	assert.Contains(t, err.Error(), "gh pr create")
 }
 
+func TestFindPR_Found(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh": {stdout: `[{"number":42,"url":"https://github.com/owner/repo/pull/42"}]`, exitCode: 0},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	pr, err := g.FindPR(context.Background(), "feat-branch")
+	require.NoError(t, err)
+	require.NotNil(t, pr)
+	assert.Equal(t, 42, pr.Number)
+	assert.Equal(t, "https://github.com/owner/repo/pull/42", pr.URL)
+	assert.Contains(t, ct.calls[0], "pr list --repo owner/repo --head feat-branch --state open")
+}
+
+func TestFindPR_None(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh": {stdout: "[]", exitCode: 0},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	pr, err := g.FindPR(context.Background(), "feat-branch")
+	require.NoError(t, err)
+	assert.Nil(t, pr)
+}
+
 // --- GetPRComments tests ---
 
 func TestGetPRComments_Success(t *testing.T) {
@@ -307,6 +409,97 @@ func TestFetchAndRebase_FetchFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "git fetch base")
 }
 
+// --- FetchBase tests ---
+
+func TestFetchBase_Success(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.FetchBase(context.Background(), t.TempDir(), "main")
+	require.NoError(t, err)
+	require.Len(t, ct.calls, 1)
+	assert.Contains(t, ct.calls[0], "git fetch origin main")
+}
+
+func TestFetchBase_Failure(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"git": {stdout: "could not resolve host", exitCode: 1},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.FetchBase(context.Background(), t.TempDir(), "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not resolve host")
+}
+
+// --- RebaseOnto tests ---
+
+func TestRebaseOnto_Success(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.RebaseOnto(context.Background(), t.TempDir(), "forge/child", "forge/parent", "main")
+	require.NoError(t, err)
+	require.Len(t, ct.calls, 2)
+	assert.Contains(t, ct.calls[0], "git rebase --onto origin/main forge/parent forge/child")
+	assert.Contains(t, ct.calls[1], "git push --force-with-lease origin forge/child")
+}
+
+func TestRebaseOnto_NoWorktreeUsesTemporaryOne(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.RebaseOnto(context.Background(), "", "forge/child", "forge/parent", "main")
+	require.NoError(t, err)
+	require.Len(t, ct.calls, 5)
+	assert.Equal(t, "git worktree prune", ct.calls[0])
+	assert.Regexp(t, `^git worktree add \S+forge-restack-\S+ forge/child$`, ct.calls[1])
+	assert.Contains(t, ct.calls[2], "git rebase --onto origin/main forge/parent forge/child")
+	assert.Contains(t, ct.calls[3], "git push --force-with-lease origin forge/child")
+	assert.Regexp(t, `^git worktree remove --force \S+forge-restack-`, ct.calls[4])
+}
+
+func TestRebaseOnto_RebaseFails(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"git": {stdout: "CONFLICT (content)", exitCode: 1},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.RebaseOnto(context.Background(), t.TempDir(), "forge/child", "forge/parent", "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CONFLICT")
+}
+
+// --- UpdatePRBase tests ---
+
+func TestUpdatePRBase_Success(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.UpdatePRBase(context.Background(), 42, "main")
+	require.NoError(t, err)
+	require.Len(t, ct.calls, 1)
+	assert.Contains(t, ct.calls[0], "gh pr edit 42 --repo owner/repo --base main")
+}
+
+func TestUpdatePRBase_Failure(t *testing.T) {
+	ct := &callTracker{results: map[string]stubResult{
+		"gh": {stdout: "no pull requests found", exitCode: 1},
+	}}
+	g := New("owner/repo", testLogger())
+	g.commandContext = ct.commandContext
+
+	err := g.UpdatePRBase(context.Background(), 42, "main")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gh pr edit")
+}
+
 // --- AmendAndForcePush tests ---
 
 func TestAmendAndForcePush_Success(t *testing.T) {
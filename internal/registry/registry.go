@@ -92,26 +92,11 @@ func ListRuns() ([]RepoRuns, error) {
 
 	var result []RepoRuns
 	for _, repo := range repos {
-		runsDir := filepath.Join(repo.Path, ".forge", "runs")
-		entries, err := filepath.Glob(filepath.Join(runsDir, "*.yaml"))
-		if err != nil || len(entries) == 0 {
+		runs, err := state.ForRepo(repo.Path).List(state.Query{})
+		if err != nil || len(runs) == 0 {
 			continue
 		}
-
-		var runs []*state.RunState
-		for _, path := range entries {
-			rs, err := state.LoadFile(path)
-			if err != nil {
-				continue
-			}
-			runs = append(runs, rs)
-		}
-		if len(runs) > 0 {
-			sort.Slice(runs, func(i, j int) bool {
-				return runs[i].CreatedAt.After(runs[j].CreatedAt)
-			})
-			result = append(result, RepoRuns{Repo: repo, Runs: runs})
-		}
+		result = append(result, RepoRuns{Repo: repo, Runs: runs})
 	}
 	return result, nil
 }
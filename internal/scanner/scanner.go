@@ -8,15 +8,10 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"sync"
 
 	"github.com/shahar-caura/forge/internal/state"
 )
 
-const defaultRunsDir = ".forge/runs"
-
-var stateLoadMu sync.Mutex
-
 // RepoRuns contains all run states discovered for a repository.
 type RepoRuns struct {
 	RepoPath string
@@ -143,13 +138,7 @@ func loadRuns(runsDir string) ([]state.RunState, error) {
 }
 
 func loadRun(runsDir, id string) (*state.RunState, error) {
-	stateLoadMu.Lock()
-	defer stateLoadMu.Unlock()
-
-	state.SetRunsDir(runsDir)
-	defer state.SetRunsDir(defaultRunsDir)
-
-	return state.Load(id)
+	return state.NewYAMLStore(runsDir).Load(id)
 }
 
 func resolveRoot(root string) (string, error) {
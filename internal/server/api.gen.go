@@ -26,6 +26,7 @@ import (
 // Defines values for RunStatus.
 const (
 	RunStatusActive    RunStatus = "active"
+	RunStatusCancelled RunStatus = "cancelled"
 	RunStatusCompleted RunStatus = "completed"
 	RunStatusFailed    RunStatus = "failed"
 )
@@ -60,6 +61,7 @@ type Run struct {
 	Id           string      `json:"id"`
 	IssueKey     *string     `json:"issue_key,omitempty"`
 	IssueUrl     *string     `json:"issue_url,omitempty"`
+	Lock         *RunLock    `json:"lock,omitempty"`
 	Mode         *string     `json:"mode,omitempty"`
 	PlanPath     string      `json:"plan_path"`
 	PlanTitle    *string     `json:"plan_title,omitempty"`
@@ -78,14 +80,42 @@ type RunList struct {
 	Total int   `json:"total"`
 }
 
+// RunLock Process currently executing the run (absent when unlocked or stale)
+type RunLock struct {
+	AcquiredAt time.Time `json:"acquired_at"`
+	Heartbeat  time.Time `json:"heartbeat"`
+	Host       string    `json:"host"`
+	Pid        int       `json:"pid"`
+}
+
 // RunStatus defines model for RunStatus.
 type RunStatus string
 
+// StepAttempt defines model for StepAttempt.
+type StepAttempt struct {
+	Error *string `json:"error,omitempty"`
+
+	// FinishedAt Absent when the attempt was interrupted
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	LogPath    *string    `json:"log_path,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+}
+
 // StepState defines model for StepState.
 type StepState struct {
-	Error  *string    `json:"error,omitempty"`
-	Name   string     `json:"name"`
-	Status StepStatus `json:"status"`
+	Attempts *[]StepAttempt `json:"attempts,omitempty"`
+
+	// DurationMs Duration of the latest attempt in milliseconds
+	DurationMs *int64  `json:"duration_ms,omitempty"`
+	Error      *string `json:"error,omitempty"`
+
+	// FinishedAt End of the latest attempt
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Name       string     `json:"name"`
+
+	// StartedAt Start of the latest attempt
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Status    StepStatus `json:"status"`
 }
 
 // StepStatus defines model for StepStatus.
@@ -109,6 +139,9 @@ type ServerInterface interface {
 	// Get run detail
 	// (GET /runs/{id})
 	GetRun(w http.ResponseWriter, r *http.Request, id string)
+	// Cancel an active run
+	// (POST /runs/{id}/cancel)
+	CancelRun(w http.ResponseWriter, r *http.Request, id string)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -202,6 +235,31 @@ func (siw *ServerInterfaceWrapper) GetRun(w http.ResponseWriter, r *http.Request
 	handler.ServeHTTP(w, r)
 }
 
+// CancelRun operation middleware
+func (siw *ServerInterfaceWrapper) CancelRun(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CancelRun(w, r, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -325,6 +383,7 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 	m.HandleFunc("GET "+options.BaseURL+"/health", wrapper.GetHealth)
 	m.HandleFunc("GET "+options.BaseURL+"/runs", wrapper.ListRuns)
 	m.HandleFunc("GET "+options.BaseURL+"/runs/{id}", wrapper.GetRun)
+	m.HandleFunc("POST "+options.BaseURL+"/runs/{id}/cancel", wrapper.CancelRun)
 
 	return m
 }
@@ -388,6 +447,41 @@ func (response GetRun404JSONResponse) VisitGetRunResponse(w http.ResponseWriter)
 	return json.NewEncoder(w).Encode(response)
 }
 
+type CancelRunRequestObject struct {
+	Id string `json:"id"`
+}
+
+type CancelRunResponseObject interface {
+	VisitCancelRunResponse(w http.ResponseWriter) error
+}
+
+type CancelRun200JSONResponse Run
+
+func (response CancelRun200JSONResponse) VisitCancelRunResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type CancelRun404JSONResponse ErrorResponse
+
+func (response CancelRun404JSONResponse) VisitCancelRunResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type CancelRun409JSONResponse ErrorResponse
+
+func (response CancelRun409JSONResponse) VisitCancelRunResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(409)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 // StrictServerInterface represents all server handlers.
 type StrictServerInterface interface {
 	// Health check
@@ -399,6 +493,9 @@ type StrictServerInterface interface {
 	// Get run detail
 	// (GET /runs/{id})
 	GetRun(ctx context.Context, request GetRunRequestObject) (GetRunResponseObject, error)
+	// Cancel an active run
+	// (POST /runs/{id}/cancel)
+	CancelRun(ctx context.Context, request CancelRunRequestObject) (CancelRunResponseObject, error)
 }
 
 type StrictHandlerFunc = strictnethttp.StrictHTTPHandlerFunc
@@ -506,24 +603,54 @@ func (sh *strictHandler) GetRun(w http.ResponseWriter, r *http.Request, id strin
 	}
 }
 
+// CancelRun operation middleware
+func (sh *strictHandler) CancelRun(w http.ResponseWriter, r *http.Request, id string) {
+	var request CancelRunRequestObject
+
+	request.Id = id
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.CancelRun(ctx, request.(CancelRunRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "CancelRun")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(CancelRunResponseObject); ok {
+		if err := validResponse.VisitCancelRunResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
-
-	"H4sIAAAAAAAC/7RWTY/cNgz9K4bao7t2Pk5zK5I0XaCHYra3YjHQWPRYWesjFLXNYDH/vaBsrz0zcnYL",
-	"NDfbFCnyvUfST6JxxjsLloLYPInQdGBkevyE6HALwTsbgD94dB6QNCRz41T6SkcPYiO0JTgAilMpDIQg",
-	"D0tjINT2IE6nUiB8jRpBic3fQ4j5/H05nXf7L9AQx/odZE/dehaBJMWQuaoU0ZM2sAvQOKtCPtVHwKCd",
-	"fTnV8Z7Z4yp+LvtttNcp71Hapsum3OCuBVB72Tys2vW3XYjGSDyuHAFJoHaS2Nw6NPwklCT4hfMV5bWP",
-	"VtlQOoQIuwc4fscasc9azbk6ZoPvpd15Sd26lTT1K864s9HsAfNselzNJ7iIDexS0nnnWUk/I7RiI36q",
-	"5taoxr6ottHeDQeTC/jkoQnMi653BJ59gV3H+yWiPA5iVf+Ztn8cPhACrMF5IWGtxBL+ctb0QjNnmUwV",
-	"rkj7Dx3oWt4Y7esx4QbJoEGOZJ+j6aKkdNd0fCXLu2dewUbDXrIh/ciIckI9EDAwrdQ9qEWQGeeZuKti",
-	"gWdkVnBWmryGX6ez6U4W2kXRKfJznFzVC+dF2R6s4hxKhs0OT69CgBPQtnUcTEFoUHtKQ1NsP939Vfz6",
-	"523ROiyog+I3hwcoPtxWHz4WSoZu7yRy7LGlxWD/OFnYdzFTN6K+eXNTcwnOg5Vei414d1PfvGPlSupS",
-	"PRU8jtvqVIqqSwuCvx8gqZHpkZzfrRIb8RloWCFc9rhFUpS3dT1sMUtgk6P0vtdNcq2+hGEpDHS8RNbF",
-	"kkqAnQN1B/gIWOhQDPkeE6vPc3xcc0XTQfPAcMlDSFvnGAiMuOfD1dRY2Tq5F7dDN3iJ0gABcognofn+",
-	"rxHwKCZVzp3/uvoWQ+9U5iP22mg6C6iglbEnsXlbl8LIb9qwDN/U/Kbt+FZmOjx/gWvbACs3LEPWmZD3",
-	"P5D7aQ5mSOfvhWuLRNw538nktYdeWyimMTaynl5nzqsnrU7fEzgP0Tzt46AfIUwLYJ4khBGWcF52/Q9G",
-	"LYfYNtpCAUnd8wx4X7//3y48/5ldudo6KloXrbpg6zMQczSlds0Un04dPmCffkJExfPrdH/6NwAA//+M",
-	"8EzuYwsAAA==",
+	"H4sIAAAAAAAC/9xXX2/jNgz/KoI2YBvgNbk/GLC8FW13K3YPh3RvQxEoNp3oaks6imobFPnuA2U7TmK5",
+	"TbfdBuxNMcV/vx8pMk8yt7WzBgx5OXuSPl9DreLxCtHiHLyzxgN/cGgdIGmI4twW8SttHMiZ1IZgBSi3",
+	"mazBe7XaF3pCbVZyu80kwpegEQo5+6Mx0d+/zbr7dvkZcmJbv4KqaD0ehSdFwSdcZTI40jUsPOTWFD4d",
+	"6j2g19a8HGrrp9cY2E9FPw9mGPISlcnXyZBzXJQAxVLld6Ny/bjwoa4VbkaugCIoFopYXFqs+SQLRfAj",
+	"xyuzoY4ukqa09wEWd7B5RhqwSkor26TwLUIpZ/KbSV9mk7bGJvNgPvI1LpnDYurtuEqZhVO0HpeSpmpE",
+	"GRcm1EvANPkOR8P3NmAOi5hjWrkvvBdSvGkuRhVwUUMT1C+q3hA41gVWbf0rRLVpart4NcsPFu8IAcbg",
+	"PKp4Xch9+LO+BfZK7CCSLsORTvioPQ27AYM5HRPupwQaZElVKZqOUoq+uutjUbaFW4DPUTuKr4P8hDYH",
+	"70UeEMFQtRHwCHkgbVaC1iAwGPG9WnowJB7WYEQw3AFQCIvCk6rgB5kdZa7yJrJXkbgGhbSEV6nYBveB",
+	"wOniBNBcLIRoJDuIeT+YETBvdk0CJtRsTeWk7zlMZrcCAjZeKl3FQ65MDhWfbxOJcEecE0HtEnUEPK2S",
+	"aZbaaL/e4XxI7PkeZ0ykauyLB+UFI4IYXBvkSWBXdjX+XHlS+LquHU6hzkAK8f7JGMDT5vW656cDO9Fy",
+	"RUDFEC5qPwT1shUKW0ZQK0XgaYetNqLWVaW7ybmHrTb00/sehr339i/ye2WKdBQnM2pUDSeweej2hmV/",
+	"0/FpE6bjPPhBtcTId3aeK5jDHnVgCo4hkxiMaU6Jdh22KAegTWmHeMyvbn4X55+uRWkxYvKLxRWIi+vJ",
+	"xaUolF8vrUK23Q5z2cgvOwnr7i1fMzk9e3M25RSsA6OcljP57mx69k5mktsv5jOB+3at3WZyso6bJH9f",
+	"QWSM2yOW6XUhZ/IDULNrctrtuhmtvJ1Om3XXEJioqJyrdB5VJ599sz02dLxE1tE2GwE7KhzAe0ChvWji",
+	"3URWdwtfuw+LfA35HcOlVj4+DBtPUMtbvjzpRmoyT57C82YOOoWqBgJkE09Ss/8vAXAju6rvZ/5p+e2t",
+	"O9ssbbHStaYDgwWUKlQkZ2+nmazVo665DN9M+Zc27a8sMabSDmxZehjxsG9ymjB5+xW57zagBOn8nR+L",
+	"SNwh31HktINKGxDdAtOyHn/2nE+edLF9rsB5fUrT3q54LYRx4vcvCWGAfTiPu/4ro5ZCbB6MKICUruQ2",
+	"k++n7/8xh4f/ekdcG0uitMEUR2x9ABLYh/Y8U5Nm24mzul3RDj39pqvKd+vld16oFa8qrl1FV2iDy4TX",
+	"K6Paa2V8NLsL/YaqKRPKFKJWeLczKHbL1pnMjmrlIor+P+Vy0aXKif/XFcPef/53vWsfA2iX78OabbAR",
+	"yrTiCNGwclknzqamDOIfZznhybu93f45AOGXaqlGEgAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
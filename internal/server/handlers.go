@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"sort"
 	"time"
 
+	"github.com/shahar-caura/forge/internal/pipeline"
 	"github.com/shahar-caura/forge/internal/registry"
 	"github.com/shahar-caura/forge/internal/state"
 )
@@ -27,43 +29,21 @@ func (h *Handlers) GetHealth(_ context.Context, _ GetHealthRequestObject) (GetHe
 }
 
 func (h *Handlers) ListRuns(_ context.Context, request ListRunsRequestObject) (ListRunsResponseObject, error) {
-	runs, err := h.listAllRuns()
-	if err != nil {
-		return nil, err
-	}
-
-	// Filter by status if provided.
+	q := state.Query{Limit: 20}
 	if request.Params.Status != nil {
-		filtered := make([]*state.RunState, 0, len(runs))
-		for _, r := range runs {
-			if string(r.Status) == string(*request.Params.Status) {
-				filtered = append(filtered, r)
-			}
-		}
-		runs = filtered
+		q.Status = state.RunStatus(*request.Params.Status)
 	}
-
-	total := len(runs)
-
-	// Apply offset.
-	offset := 0
 	if request.Params.Offset != nil {
-		offset = *request.Params.Offset
+		q.Offset = *request.Params.Offset
 	}
-	if offset > len(runs) {
-		offset = len(runs)
-	}
-	runs = runs[offset:]
-
-	// Apply limit.
-	limit := 20
 	if request.Params.Limit != nil {
-		limit = *request.Params.Limit
+		q.Limit = *request.Params.Limit
 	}
-	if limit > len(runs) {
-		limit = len(runs)
+
+	runs, total, err := h.listRuns(q)
+	if err != nil {
+		return nil, err
 	}
-	runs = runs[:limit]
 
 	apiRuns := make([]Run, len(runs))
 	for i, r := range runs {
@@ -88,6 +68,29 @@ func (h *Handlers) GetRun(_ context.Context, request GetRunRequestObject) (GetRu
 	return GetRun200JSONResponse(stateToRun(rs)), nil
 }
 
+// CancelRun cancels an active run of the local repo (the one forge serve runs in).
+func (h *Handlers) CancelRun(_ context.Context, request CancelRunRequestObject) (CancelRunResponseObject, error) {
+	if _, err := state.Load(request.Id); err != nil {
+		return CancelRun404JSONResponse{
+			Code:    404,
+			Message: "run not found",
+		}, nil
+	}
+
+	rs, err := pipeline.CancelRun(request.Id, h.Logger)
+	if errors.Is(err, pipeline.ErrRunNotActive) {
+		return CancelRun409JSONResponse{
+			Code:    409,
+			Message: err.Error(),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return CancelRun200JSONResponse(stateToRun(rs)), nil
+}
+
 // findRun looks up a run by ID, searching registered repos when in multi-repo mode.
 func (h *Handlers) findRun(id string) (*state.RunState, error) {
 	// Try local first (fast path).
@@ -99,44 +102,72 @@ func (h *Handlers) findRun(id string) (*state.RunState, error) {
 		return nil, err
 	}
 	// Search registered repos.
-	repoRuns, regErr := registry.ListRuns()
+	repos, regErr := registry.List()
 	if regErr != nil {
 		return nil, err
 	}
-	for _, rr := range repoRuns {
-		for _, r := range rr.Runs {
-			if r.ID == id {
-				return r, nil
-			}
+	for _, repo := range repos {
+		if r, repoErr := state.ForRepo(repo.Path).Load(id); repoErr == nil {
+			return r, nil
 		}
 	}
 	return nil, err
 }
 
-// listAllRuns returns runs from the local directory or aggregated from all registered repos.
-func (h *Handlers) listAllRuns() ([]*state.RunState, error) {
-	if !h.MultiRepo {
-		return state.List()
-	}
-
-	repoRuns, err := registry.ListRuns()
+// listRuns returns one page of runs matching q and the total number of matches.
+// Local runs are filtered and paginated by the store; in multi-repo mode runs
+// of all registered repos are merged first.
+func (h *Handlers) listRuns(q state.Query) ([]*state.RunState, int, error) {
+	repoRuns, err := h.registeredRuns()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-
-	// Flatten all runs from all repos, fall back to local if registry is empty.
-	if len(repoRuns) == 0 {
-		return state.List()
+	if repoRuns == nil {
+		total, err := state.Current().Count(q)
+		if err != nil || q.Limit <= 0 {
+			// Query treats a zero limit as unlimited; the API returns an empty page.
+			return nil, total, err
+		}
+		runs, err := state.ListQuery(q)
+		if err != nil {
+			return nil, 0, err
+		}
+		return runs, total, nil
 	}
 
 	var all []*state.RunState
 	for _, rr := range repoRuns {
-		all = append(all, rr.Runs...)
+		for _, r := range rr.Runs {
+			if q.Status == "" || r.Status == q.Status {
+				all = append(all, r)
+			}
+		}
 	}
 	sort.Slice(all, func(i, j int) bool {
 		return all[i].CreatedAt.After(all[j].CreatedAt)
 	})
-	return all, nil
+
+	total := len(all)
+	offset := min(q.Offset, len(all))
+	all = all[offset:]
+	return all[:min(max(q.Limit, 0), len(all))], total, nil
+}
+
+// registeredRuns returns the runs of all registered repos in multi-repo mode,
+// or nil when runs come from the local store only (single-repo mode, or an
+// empty registry).
+func (h *Handlers) registeredRuns() ([]registry.RepoRuns, error) {
+	if !h.MultiRepo {
+		return nil, nil
+	}
+	repoRuns, err := registry.ListRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(repoRuns) == 0 {
+		return nil, nil
+	}
+	return repoRuns, nil
 }
 
 // stateToRun converts a state.RunState to the API Run type.
@@ -183,6 +214,14 @@ func stateToRun(rs *state.RunState) Run {
 	if rs.SourceIssue != 0 {
 		r.SourceIssue = &rs.SourceIssue
 	}
+	if owner := rs.LockOwner(); owner != nil {
+		r.Lock = &RunLock{
+			Pid:        owner.PID,
+			Host:       owner.Host,
+			AcquiredAt: owner.AcquiredAt,
+			Heartbeat:  owner.Heartbeat,
+		}
+	}
 
 	for i, step := range rs.Steps {
 		s := StepState{
@@ -192,6 +231,30 @@ func stateToRun(rs *state.RunState) Run {
 		if step.Error != "" {
 			s.Error = &step.Error
 		}
+		if !step.StartedAt.IsZero() {
+			s.StartedAt = &step.StartedAt
+		}
+		if !step.FinishedAt.IsZero() {
+			s.FinishedAt = &step.FinishedAt
+			ms := step.Duration.Milliseconds()
+			s.DurationMs = &ms
+		}
+		if len(step.Attempts) > 0 {
+			attempts := make([]StepAttempt, len(step.Attempts))
+			for j, a := range step.Attempts {
+				attempts[j] = StepAttempt{StartedAt: a.StartedAt}
+				if !a.FinishedAt.IsZero() {
+					attempts[j].FinishedAt = &a.FinishedAt
+				}
+				if a.Error != "" {
+					attempts[j].Error = &a.Error
+				}
+				if a.LogPath != "" {
+					attempts[j].LogPath = &a.LogPath
+				}
+			}
+			s.Attempts = &attempts
+		}
 		r.Steps[i] = s
 	}
 
@@ -125,8 +125,7 @@ func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
 
 // #2: isRunActive properly loads state instead of fragile string matching.
 func (s *Server) isRunActive(runID string) bool {
-	statePath := filepath.Join(s.runsDir, runID+".yaml")
-	rs, err := state.LoadFile(statePath)
+	rs, err := state.Load(runID)
 	if err != nil {
 		return false
 	}
@@ -2,6 +2,7 @@ package server_test
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -158,6 +159,67 @@ func TestGetRun(t *testing.T) {
 	assert.Len(t, resp.Steps, 2)
 }
 
+func TestGetRunLockOwner(t *testing.T) {
+	setupFixtures(t)
+	handler := newTestHandler(t)
+
+	lock, err := state.AcquireLock("run-001")
+	require.NoError(t, err)
+	defer lock.Release()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/runs/run-001", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp server.Run
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.NotNil(t, resp.Lock)
+	assert.Equal(t, os.Getpid(), resp.Lock.Pid)
+
+	lock.Release()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/runs/run-001", nil))
+	resp = server.Run{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Lock)
+}
+
+func TestGetRunStepTiming(t *testing.T) {
+	setupFixtures(t)
+	handler := newTestHandler(t)
+
+	t0 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	rs := state.New("run-timed", "plans/timed.md")
+	rs.Steps[4].BeginAttempt(t0)
+	rs.Steps[4].EndAttempt(t0.Add(time.Minute), errors.New("agent crashed"), "")
+	rs.Steps[4].BeginAttempt(t0.Add(time.Hour))
+	rs.Steps[4].EndAttempt(t0.Add(time.Hour+90*time.Second), nil, ".forge/runs/run-timed-agent-step4.log")
+	require.NoError(t, rs.Save())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/runs/run-timed", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp server.Run
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+	step := resp.Steps[4]
+	require.NotNil(t, step.DurationMs)
+	assert.Equal(t, int64(90_000), *step.DurationMs)
+	require.NotNil(t, step.StartedAt)
+	assert.True(t, t0.Add(time.Hour).Equal(*step.StartedAt))
+	require.NotNil(t, step.Attempts)
+	require.Len(t, *step.Attempts, 2)
+	assert.Equal(t, "agent crashed", *(*step.Attempts)[0].Error)
+	assert.Equal(t, ".forge/runs/run-timed-agent-step4.log", *(*step.Attempts)[1].LogPath)
+
+	assert.Nil(t, resp.Steps[0].StartedAt, "steps that never ran have no timing")
+	assert.Nil(t, resp.Steps[0].Attempts)
+}
+
 func TestGetRunNotFound(t *testing.T) {
 	dir := t.TempDir()
 	state.SetRunsDir(dir)
@@ -202,6 +264,55 @@ func TestGetRunStepError(t *testing.T) {
 	assert.Equal(t, "timeout", *failedStep.Error)
 }
 
+func TestCancelRun(t *testing.T) {
+	setupFixtures(t)
+	active := state.New("run-003", "plans/wip.md")
+	require.NoError(t, active.Save())
+
+	handler := newTestMux(t, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/runs/run-003/cancel", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp server.Run
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, server.RunStatusCancelled, resp.Status)
+
+	onDisk, err := state.Load("run-003")
+	require.NoError(t, err)
+	assert.Equal(t, state.RunCancelled, onDisk.Status)
+}
+
+func TestCancelRunNotActive(t *testing.T) {
+	setupFixtures(t)
+	handler := newTestMux(t, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/runs/run-001/cancel", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var resp server.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, 409, resp.Code)
+	assert.Contains(t, resp.Message, "completed")
+}
+
+func TestCancelRunNotFound(t *testing.T) {
+	setupFixtures(t)
+	handler := newTestMux(t, t.TempDir())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/runs/nonexistent/cancel", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
 func newTestMux(t *testing.T, runsDir string) http.Handler {
 	t.Helper()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -16,26 +18,52 @@ import (
 	"github.com/shahar-caura/forge/internal/state"
 )
 
-// SSEHub fans out run-state change events to connected SSE clients.
+// SSEHub fans out run-state changes and run journal events to connected SSE
+// clients. Run snapshots are sent as unnamed messages; journal entries as
+// "run-event" events, one per line appended to a .forge/runs/<id>.events.jsonl.
 type SSEHub struct {
 	runsDir string
 	logger  *slog.Logger
 
+	// offsets tracks how much of each journal has been forwarded.
+	// Only touched by the Start goroutine.
+	offsets map[string]int64
+
 	mu      sync.Mutex
-	clients map[chan []byte]struct{}
+	clients map[chan sseMessage]struct{}
+}
+
+// sseMessage is one SSE frame; Event is empty for unnamed messages.
+type sseMessage struct {
+	Event string
+	Data  []byte
 }
 
+// journalEvent is the SSE event name of run journal entries.
+const journalEvent = "run-event"
+
 // NewSSEHub creates an SSEHub watching the given runs directory.
 func NewSSEHub(runsDir string, logger *slog.Logger) *SSEHub {
 	return &SSEHub{
 		runsDir: runsDir,
 		logger:  logger,
-		clients: make(map[chan []byte]struct{}),
+		offsets: make(map[string]int64),
+		clients: make(map[chan sseMessage]struct{}),
 	}
 }
 
+// ssePollInterval is how often a database-backed store is polled for changes.
+var ssePollInterval = time.Second
+
 // Start watches runsDir for file changes and broadcasts events. Blocks until ctx is cancelled.
+// When run states live in a database rather than files, it polls the store for
+// run changes; journals are files either way.
 func (h *SSEHub) Start(ctx context.Context) {
+	fileBacked := state.FileBacked()
+	if !fileBacked {
+		go h.poll(ctx)
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		h.logger.Error("sse: failed to create watcher", "err", err)
@@ -54,6 +82,14 @@ func (h *SSEHub) Start(ctx context.Context) {
 		return
 	}
 
+	// Only forward journal lines appended from now on.
+	journals, _ := filepath.Glob(filepath.Join(h.runsDir, "*.events.jsonl"))
+	for _, path := range journals {
+		if info, err := os.Stat(path); err == nil {
+			h.offsets[path] = info.Size()
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -65,7 +101,11 @@ func (h *SSEHub) Start(ctx context.Context) {
 			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
 				continue
 			}
-			if !strings.HasSuffix(event.Name, ".yaml") || strings.HasSuffix(event.Name, ".tmp") {
+			if strings.HasSuffix(event.Name, ".events.jsonl") {
+				h.forwardJournal(event.Name)
+				continue
+			}
+			if !fileBacked || !strings.HasSuffix(event.Name, ".yaml") || strings.HasSuffix(event.Name, ".tmp") {
 				continue
 			}
 
@@ -79,7 +119,7 @@ func (h *SSEHub) Start(ctx context.Context) {
 			if err != nil {
 				continue
 			}
-			h.broadcast(data)
+			h.broadcast(sseMessage{Data: data})
 
 		case err, ok := <-watcher.Errors:
 			if !ok {
@@ -90,25 +130,88 @@ func (h *SSEHub) Start(ctx context.Context) {
 	}
 }
 
-func (h *SSEHub) broadcast(data []byte) {
+// poll broadcasts every run saved since the previous tick.
+func (h *SSEHub) poll(ctx context.Context) {
+	ticker := time.NewTicker(ssePollInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runs, err := state.ListQuery(state.Query{UpdatedAfter: last})
+			if err != nil {
+				h.logger.Error("sse: polling run states", "err", err)
+				continue
+			}
+			for _, rs := range runs {
+				if rs.UpdatedAt.After(last) {
+					last = rs.UpdatedAt
+				}
+				data, err := json.Marshal(stateToRun(rs))
+				if err != nil {
+					continue
+				}
+				h.broadcast(sseMessage{Data: data})
+			}
+		}
+	}
+}
+
+// forwardJournal broadcasts the complete lines appended to journal path since
+// the last call. A trailing partial line waits for the next write.
+func (h *SSEHub) forwardJournal(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	offset := h.offsets[path]
+	if info, err := f.Stat(); err == nil && info.Size() < offset {
+		offset = 0 // journal was recreated
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return
+	}
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		if len(line) > 0 {
+			h.broadcast(sseMessage{Event: journalEvent, Data: line})
+		}
+	}
+	h.offsets[path] = offset + int64(end) + 1
+}
+
+func (h *SSEHub) broadcast(msg sseMessage) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	for ch := range h.clients {
 		select {
-		case ch <- data:
+		case ch <- msg:
 		default:
 			// Slow client; drop this event.
 		}
 	}
 }
 
-func (h *SSEHub) addClient(ch chan []byte) {
+func (h *SSEHub) addClient(ch chan sseMessage) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.clients[ch] = struct{}{}
 }
 
-func (h *SSEHub) removeClient(ch chan []byte) {
+func (h *SSEHub) removeClient(ch chan sseMessage) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	delete(h.clients, ch)
@@ -127,7 +230,7 @@ func (h *SSEHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	ch := make(chan []byte, 32)
+	ch := make(chan sseMessage, 32)
 	h.addClient(ch)
 	defer h.removeClient(ch)
 
@@ -142,8 +245,11 @@ func (h *SSEHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case <-keepalive.C:
 			_, _ = fmt.Fprint(w, ": keepalive\n\n")
 			flusher.Flush()
-		case data := <-ch:
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		case msg := <-ch:
+			if msg.Event != "" {
+				_, _ = fmt.Fprintf(w, "event: %s\n", msg.Event)
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", msg.Data)
 			flusher.Flush()
 		}
 	}
@@ -76,6 +76,81 @@ func TestSSEHubBroadcast(t *testing.T) {
 	}
 }
 
+// dbStore hides the YAML store's type so the hub treats it as a database.
+type dbStore struct{ state.Store }
+
+func TestSSEHubPollsNonFileStore(t *testing.T) {
+	state.SetStore(dbStore{state.NewYAMLStore(t.TempDir())})
+	t.Cleanup(func() { state.SetStore(nil) })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	hub := server.NewSSEHub(t.TempDir(), logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Start(ctx)
+
+	req := httptest.NewRequest("GET", "/api/events", nil)
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	done := make(chan struct{})
+	go func() {
+		hub.ServeHTTP(rec, req.WithContext(ctx))
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, state.New("sse-poll-001", "plans/test.md").Save())
+
+	// One poll interval plus slack.
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Contains(t, rec.Body.String(), "sse-poll-001")
+}
+
+func TestSSEHubStreamsJournal(t *testing.T) {
+	dir := t.TempDir()
+	state.SetRunsDir(dir)
+	t.Cleanup(func() { state.SetRunsDir(".forge/runs") })
+
+	// Entries written before the hub starts are history, not news.
+	require.NoError(t, state.AppendEvent(state.Event{RunID: "sse-journal-001", Type: state.EventStepStarted, Step: "read plan"}))
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	hub := server.NewSSEHub(dir, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Start(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/api/events", nil)
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	done := make(chan struct{})
+	go func() {
+		hub.ServeHTTP(rec, req.WithContext(ctx))
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, state.AppendEvent(state.Event{
+		RunID: "sse-journal-001",
+		Type:  state.EventPRCreated,
+		Step:  "create pr",
+		Data:  map[string]any{"number": 7},
+	}))
+
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "event: run-event\n")
+	assert.Contains(t, body, `"type":"pr_created"`)
+	assert.NotContains(t, body, `"type":"step_started"`)
+}
+
 // flushRecorder wraps httptest.ResponseRecorder to implement http.Flusher.
 type flushRecorder struct {
 	*httptest.ResponseRecorder
@@ -0,0 +1,140 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BatchIssueStatus represents the state of one issue within a batch.
+type BatchIssueStatus string
+
+const (
+	IssuePending   BatchIssueStatus = "pending"
+	IssueRunning   BatchIssueStatus = "running"
+	IssueCompleted BatchIssueStatus = "completed"
+	IssueFailed    BatchIssueStatus = "failed"
+	IssueBlocked   BatchIssueStatus = "blocked"
+)
+
+// BatchIssue tracks one issue of a batch and the run executing it.
+// Title and body are stored so a resumed batch does not re-fetch issues.
+type BatchIssue struct {
+	Number int              `yaml:"number"`
+	Title  string           `yaml:"title"`
+	Body   string           `yaml:"body,omitempty"`
+	RunID  string           `yaml:"run_id,omitempty"`
+	Status BatchIssueStatus `yaml:"status"`
+	Error  string           `yaml:"error,omitempty"`
+}
+
+// BatchState is the persistent record of a forge run --all-issues batch.
+// Stored at .forge/runs/batches/<id>.yaml, next to the run state files.
+type BatchState struct {
+	ID        string       `yaml:"id"`
+	Label     string       `yaml:"label,omitempty"`
+	KeepGoing bool         `yaml:"keep_going,omitempty"`
+	Status    RunStatus    `yaml:"status"`
+	CreatedAt time.Time    `yaml:"created_at"`
+	UpdatedAt time.Time    `yaml:"updated_at"`
+	Levels    [][]int      `yaml:"levels"`
+	Issues    []BatchIssue `yaml:"issues"`
+}
+
+func batchesDir() string { return filepath.Join(runsDir, "batches") }
+
+// NewBatch creates an active BatchState with no issues.
+func NewBatch(id string) *BatchState {
+	now := time.Now()
+	return &BatchState{
+		ID:        id,
+		Status:    RunActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// LoadBatch reads a BatchState from .forge/runs/batches/<id>.yaml.
+func LoadBatch(id string) (*BatchState, error) {
+	path := filepath.Join(batchesDir(), id+".yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading batch state %q: %w", path, err)
+	}
+
+	var bs BatchState
+	if err := yaml.Unmarshal(data, &bs); err != nil {
+		return nil, fmt.Errorf("parsing batch state %q: %w", path, err)
+	}
+	return &bs, nil
+}
+
+// Save writes the BatchState atomically to .forge/runs/batches/<id>.yaml.
+func (b *BatchState) Save() error {
+	dir := batchesDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating batches dir: %w", err)
+	}
+
+	b.UpdatedAt = time.Now()
+
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("marshaling batch state: %w", err)
+	}
+
+	dest := filepath.Join(dir, b.ID+".yaml")
+	tmp := dest + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing temp batch file: %w", err)
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp) // best-effort cleanup
+		return fmt.Errorf("renaming batch file: %w", err)
+	}
+
+	return nil
+}
+
+// ListBatches returns all batch states sorted by created_at descending.
+func ListBatches() ([]*BatchState, error) {
+	entries, err := filepath.Glob(filepath.Join(batchesDir(), "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing batch states: %w", err)
+	}
+
+	var batches []*BatchState
+	for _, path := range entries {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // skip unreadable files
+		}
+		var bs BatchState
+		if err := yaml.Unmarshal(data, &bs); err != nil {
+			continue // skip corrupt files
+		}
+		batches = append(batches, &bs)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+
+	return batches, nil
+}
+
+// Issue returns the record for issue number, or nil if it is not in the batch.
+func (b *BatchState) Issue(number int) *BatchIssue {
+	for i := range b.Issues {
+		if b.Issues[i].Number == number {
+			return &b.Issues[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchSaveAndLoad_RoundTrip(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	bs := NewBatch("20260217-120000-batch")
+	bs.Label = "forge"
+	bs.KeepGoing = true
+	bs.Levels = [][]int{{1, 4}, {2}}
+	bs.Issues = []BatchIssue{
+		{Number: 1, Title: "Schema", Body: "No deps.", RunID: "20260217-120001-schema", Status: IssueCompleted},
+		{Number: 4, Title: "Docs", Status: IssueFailed, Error: "boom"},
+		{Number: 2, Title: "API", Body: "Depends on #1", Status: IssuePending},
+	}
+	require.NoError(t, bs.Save())
+
+	loaded, err := LoadBatch(bs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bs.Label, loaded.Label)
+	assert.True(t, loaded.KeepGoing)
+	assert.Equal(t, RunActive, loaded.Status)
+	assert.Equal(t, bs.Levels, loaded.Levels)
+	assert.Equal(t, bs.Issues, loaded.Issues)
+}
+
+func TestBatch_NotListedAsRun(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, New("run-1", "plan.md").Save())
+	require.NoError(t, NewBatch("batch-1").Save())
+
+	_, err := os.Stat(filepath.Join(runsDir, "batches", "batch-1.yaml"))
+	require.NoError(t, err, "batch file lives next to run files")
+
+	runs, err := List()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "run-1", runs[0].ID)
+}
+
+func TestListBatches_SortedByCreatedAtDesc(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	older := NewBatch("older")
+	older.CreatedAt = time.Now().Add(-time.Hour)
+	require.NoError(t, older.Save())
+	require.NoError(t, NewBatch("newer").Save())
+
+	batches, err := ListBatches()
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "newer", batches[0].ID)
+	assert.Equal(t, "older", batches[1].ID)
+}
+
+func TestLoadBatch_Nonexistent(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	_, err := LoadBatch("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "loading batch state")
+}
+
+func TestBatchIssue_Lookup(t *testing.T) {
+	bs := NewBatch("b")
+	bs.Issues = []BatchIssue{{Number: 3}, {Number: 7}}
+
+	bi := bs.Issue(7)
+	require.NotNil(t, bi)
+	bi.Status = IssueRunning
+	assert.Equal(t, IssueRunning, bs.Issues[1].Status, "returns a pointer into the batch")
+	assert.Nil(t, bs.Issue(9))
+}
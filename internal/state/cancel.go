@@ -0,0 +1,35 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// A cancel marker (.forge/runs/<id>.cancel) asks the process executing a run
+// to stop it. Unlike the status field it survives the runner's own saves, so a
+// run sharing its forge process with others (a batch) still sees the request.
+
+func cancelPath(id string) string { return filepath.Join(runsDir, id+".cancel") }
+
+// RequestCancel writes the cancel marker for run id.
+func RequestCancel(id string) error {
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		return fmt.Errorf("creating runs dir: %w", err)
+	}
+	if err := os.WriteFile(cancelPath(id), nil, 0o644); err != nil {
+		return fmt.Errorf("writing cancel marker: %w", err)
+	}
+	return nil
+}
+
+// CancelRequested reports whether run id has a cancel marker.
+func CancelRequested(id string) bool {
+	_, err := os.Stat(cancelPath(id))
+	return err == nil
+}
+
+// ClearCancel removes the cancel marker for run id, if any.
+func ClearCancel(id string) {
+	_ = os.Remove(cancelPath(id))
+}
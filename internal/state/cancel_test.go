@@ -0,0 +1,34 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelMarker_RoundTrip(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	assert.False(t, CancelRequested("run-1"))
+
+	require.NoError(t, RequestCancel("run-1"))
+	assert.True(t, CancelRequested("run-1"))
+	assert.False(t, CancelRequested("run-2"))
+
+	ClearCancel("run-1")
+	assert.False(t, CancelRequested("run-1"))
+}
+
+func TestCancelMarker_NotListedAsRun(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, New("run-1", "plan.md").Save())
+	require.NoError(t, RequestCancel("run-1"))
+
+	runs, err := List()
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
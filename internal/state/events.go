@@ -0,0 +1,103 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EventType names an entry of a run's event journal.
+type EventType string
+
+const (
+	EventStepStarted      EventType = "step_started"
+	EventStepFinished     EventType = "step_finished"
+	EventStepRetrying     EventType = "step_retrying"
+	EventAgentInvoked     EventType = "agent_invoked"
+	EventAgentFinished    EventType = "agent_finished"
+	EventHookFailed       EventType = "hook_failed"
+	EventPRCreated        EventType = "pr_created"
+	EventCRCommentMatched EventType = "cr_comment_matched"
+	EventNotificationSent EventType = "notification_sent"
+	EventBudgetExceeded   EventType = "budget_exceeded"
+)
+
+// Event is one entry of a run's append-only journal. Unlike the run state,
+// which only holds the latest status, the journal keeps everything that
+// happened, in order.
+type Event struct {
+	Time  time.Time      `json:"time"`
+	RunID string         `json:"run_id"`
+	Type  EventType      `json:"type"`
+	Step  string         `json:"step,omitempty"`
+	Data  map[string]any `json:"data,omitempty"`
+}
+
+// eventsMu serializes appends from runs sharing this process (batches).
+var eventsMu sync.Mutex
+
+// EventsPath returns the journal of run id: .forge/runs/<id>.events.jsonl.
+func EventsPath(id string) string { return filepath.Join(runsDir, id+".events.jsonl") }
+
+// AppendEvent adds ev to its run's journal as one JSON line, stamping Time
+// when unset.
+func AppendEvent(ev Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+	line = append(line, '\n')
+
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		return fmt.Errorf("creating runs dir: %w", err)
+	}
+	f, err := os.OpenFile(EventsPath(ev.RunID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening event journal: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return f.Close()
+}
+
+// ReadEvents returns the journal of run id in order. A run without a journal
+// (e.g. from before journaling) has no events. Lines that fail to parse, such
+// as a partial line from a crash mid-write, are skipped.
+func ReadEvents(id string) ([]Event, error) {
+	f, err := os.Open(EventsPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening event journal: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading event journal: %w", err)
+	}
+	return events, nil
+}
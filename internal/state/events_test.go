@@ -0,0 +1,72 @@
+package state
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendEvent_ReadEventsRoundTrip(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, AppendEvent(Event{Time: t0, RunID: "run-1", Type: EventStepStarted, Step: "run agent"}))
+	require.NoError(t, AppendEvent(Event{RunID: "run-1", Type: EventPRCreated, Step: "create pr", Data: map[string]any{"number": 7}}))
+	require.NoError(t, AppendEvent(Event{RunID: "run-2", Type: EventStepStarted}))
+
+	events, err := ReadEvents("run-1")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.True(t, t0.Equal(events[0].Time))
+	assert.Equal(t, "run agent", events[0].Step)
+	assert.Equal(t, EventPRCreated, events[1].Type)
+	assert.False(t, events[1].Time.IsZero(), "time is stamped when unset")
+	assert.Equal(t, float64(7), events[1].Data["number"])
+}
+
+func TestReadEvents_MissingJournal(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	events, err := ReadEvents("nope")
+	require.NoError(t, err)
+	assert.Nil(t, events)
+}
+
+func TestReadEvents_SkipsPartialLine(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, AppendEvent(Event{RunID: "run-1", Type: EventStepStarted}))
+	f, err := os.OpenFile(EventsPath("run-1"), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2026-03-01T12:00:00Z","run_id":"run-1","ty`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	events, err := ReadEvents("run-1")
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestCleanup_RemovesJournal(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	rs := New("old-run", "plan.md")
+	rs.Status = RunCompleted
+	require.NoError(t, rs.Save())
+	backdateRun(t, rs, time.Now().Add(-48*time.Hour))
+	require.NoError(t, AppendEvent(Event{RunID: rs.ID, Type: EventStepStarted}))
+
+	deleted, err := Cleanup(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = os.Stat(EventsPath(rs.ID))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AgentHealth is an agent's track record, used by the agent pool to route
+// work to the healthiest agent. Persisted across forge invocations in
+// .forge/agent-health.yaml, keyed by agent name.
+type AgentHealth struct {
+	Successes     int           `yaml:"successes"`
+	Failures      int           `yaml:"failures"`
+	RateLimits    int           `yaml:"rate_limits"`              // consecutive rate-limit hits; reset by a success
+	CooldownUntil time.Time     `yaml:"cooldown_until,omitempty"` // skip the agent until then
+	AvgLatency    time.Duration `yaml:"avg_latency,omitempty"`    // moving average of successful calls
+	UpdatedAt     time.Time     `yaml:"updated_at"`
+}
+
+// SuccessRate returns the share of successful calls, smoothed toward 50% so a
+// single early result doesn't dominate.
+func (h AgentHealth) SuccessRate() float64 {
+	return float64(h.Successes+1) / float64(h.Successes+h.Failures+2)
+}
+
+// CoolingDown reports whether the agent is backing off from rate limits at now.
+func (h AgentHealth) CoolingDown(now time.Time) bool {
+	return now.Before(h.CooldownUntil)
+}
+
+// healthMu serializes read-modify-write cycles on the health file within
+// this process; a flock on healthLockPath serializes them across processes.
+var healthMu sync.Mutex
+
+func healthPath() string { return filepath.Join(filepath.Dir(runsDir), "agent-health.yaml") }
+
+func healthLockPath() string { return healthPath() + ".lock" }
+
+// LoadAgentHealth reads the recorded agent health. A missing file yields an
+// empty map.
+func LoadAgentHealth() (map[string]*AgentHealth, error) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	return loadAgentHealth()
+}
+
+// UpdateAgentHealth applies fn to the named agent's record and saves it. The
+// file is re-read and rewritten under a file lock, so updates from concurrent
+// forge processes aren't lost. Returns every agent's record as saved.
+func UpdateAgentHealth(name string, fn func(*AgentHealth)) (map[string]*AgentHealth, error) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(healthPath()), 0o755); err != nil {
+		return nil, fmt.Errorf("creating forge dir: %w", err)
+	}
+	unlock, err := flockFile(healthLockPath())
+	if err != nil {
+		return nil, fmt.Errorf("locking agent health: %w", err)
+	}
+	defer unlock()
+
+	health, err := loadAgentHealth()
+	if err != nil {
+		return nil, err
+	}
+	h := health[name]
+	if h == nil {
+		h = &AgentHealth{}
+		health[name] = h
+	}
+	fn(h)
+	h.UpdatedAt = time.Now()
+
+	if err := saveAgentHealth(health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+func loadAgentHealth() (map[string]*AgentHealth, error) {
+	path := healthPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]*AgentHealth), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading agent health %q: %w", path, err)
+	}
+
+	health := make(map[string]*AgentHealth)
+	if err := yaml.Unmarshal(data, &health); err != nil {
+		return nil, fmt.Errorf("parsing agent health %q: %w", path, err)
+	}
+	return health, nil
+}
+
+// saveAgentHealth writes the health file atomically.
+func saveAgentHealth(health map[string]*AgentHealth) error {
+	dest := healthPath()
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("creating forge dir: %w", err)
+	}
+
+	data, err := yaml.Marshal(health)
+	if err != nil {
+		return fmt.Errorf("marshaling agent health: %w", err)
+	}
+
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing temp agent health file: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp) // best-effort cleanup
+		return fmt.Errorf("renaming agent health file: %w", err)
+	}
+	return nil
+}
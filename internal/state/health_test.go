@@ -0,0 +1,105 @@
+package state
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAgentHealth_Missing(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	health, err := LoadAgentHealth()
+	require.NoError(t, err)
+	assert.Empty(t, health)
+}
+
+func TestUpdateAgentHealth_RoundTrip(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	until := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	_, err := UpdateAgentHealth("claude", func(h *AgentHealth) {
+		h.Failures++
+		h.RateLimits++
+		h.CooldownUntil = until
+	})
+	require.NoError(t, err)
+	all, err := UpdateAgentHealth("codex", func(h *AgentHealth) {
+		h.Successes++
+		h.AvgLatency = 90 * time.Second
+	})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	loaded, err := LoadAgentHealth()
+	require.NoError(t, err)
+	require.Contains(t, loaded, "claude")
+	assert.Equal(t, 1, loaded["claude"].RateLimits)
+	assert.True(t, loaded["claude"].CooldownUntil.Equal(until))
+	assert.Equal(t, 90*time.Second, loaded["codex"].AvgLatency)
+	assert.False(t, loaded["codex"].UpdatedAt.IsZero())
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(runsDir), "agent-health.yaml"))
+	assert.NoError(t, err, "health lives next to runs/, not in it")
+}
+
+// TestHealthHelperProcess records HEALTH_HELPER_UPDATES successes for
+// "claude" in HEALTH_HELPER_RUNS_DIR when run as a subprocess.
+func TestHealthHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	runsDir = os.Getenv("HEALTH_HELPER_RUNS_DIR")
+	n, _ := strconv.Atoi(os.Getenv("HEALTH_HELPER_UPDATES"))
+	for range n {
+		if _, err := UpdateAgentHealth("claude", func(h *AgentHealth) { h.Successes++ }); err != nil {
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestUpdateAgentHealth_ConcurrentProcesses(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	const procs, updates = 4, 25
+	cmds := make([]*exec.Cmd, procs)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=TestHealthHelperProcess")
+		cmd.Env = append(os.Environ(),
+			"GO_WANT_HELPER_PROCESS=1",
+			"HEALTH_HELPER_RUNS_DIR="+runsDir,
+			"HEALTH_HELPER_UPDATES="+strconv.Itoa(updates),
+		)
+		require.NoError(t, cmd.Start())
+		cmds[i] = cmd
+	}
+	for _, cmd := range cmds {
+		require.NoError(t, cmd.Wait())
+	}
+
+	health, err := LoadAgentHealth()
+	require.NoError(t, err)
+	require.Contains(t, health, "claude")
+	assert.Equal(t, procs*updates, health["claude"].Successes, "no update is lost between processes")
+}
+
+func TestAgentHealth_SuccessRateAndCooldown(t *testing.T) {
+	assert.InDelta(t, 0.5, AgentHealth{}.SuccessRate(), 0.001)
+	assert.InDelta(t, 0.75, AgentHealth{Successes: 2}.SuccessRate(), 0.001)
+	assert.InDelta(t, 0.25, AgentHealth{Failures: 2}.SuccessRate(), 0.001)
+
+	now := time.Now()
+	assert.True(t, AgentHealth{CooldownUntil: now.Add(time.Minute)}.CoolingDown(now))
+	assert.False(t, AgentHealth{CooldownUntil: now.Add(-time.Minute)}.CoolingDown(now))
+	assert.False(t, AgentHealth{}.CoolingDown(now))
+}
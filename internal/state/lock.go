@@ -0,0 +1,323 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lock timing. The owner rewrites the heartbeat every LockHeartbeatInterval;
+// a lock whose heartbeat is older than LockStaleAfter is considered abandoned.
+var (
+	LockHeartbeatInterval = 10 * time.Second
+	LockStaleAfter        = time.Minute
+)
+
+// LockInfo identifies the process holding a run's advisory lock
+// (.forge/runs/<id>.lock).
+type LockInfo struct {
+	PID        int       `yaml:"pid"`
+	Host       string    `yaml:"host"`
+	AcquiredAt time.Time `yaml:"acquired_at"`
+	Heartbeat  time.Time `yaml:"heartbeat"`
+}
+
+// Stale reports whether the lock owner is gone: its heartbeat expired, or it
+// ran on this host and the process no longer exists.
+func (l *LockInfo) Stale() bool {
+	if time.Since(l.Heartbeat) > LockStaleAfter {
+		return true
+	}
+	if host, err := os.Hostname(); err == nil && host == l.Host {
+		return !processAlive(l.PID)
+	}
+	return false
+}
+
+// LockedError is returned by AcquireLock when another live process holds the lock.
+type LockedError struct {
+	ID    string
+	Owner LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("run %q is locked by pid %d on %s (heartbeat %s ago)",
+		e.ID, e.Owner.PID, e.Owner.Host, time.Since(e.Owner.Heartbeat).Truncate(time.Second))
+}
+
+// ErrLockLost is returned by a heartbeat that finds another process has
+// replaced the lock, e.g. after taking it over while this process was stalled.
+var ErrLockLost = errors.New("run lock was taken over by another process")
+
+// RunLock is a held run lock. Release it when done with the run.
+type RunLock struct {
+	id   string
+	info LockInfo
+
+	stop chan struct{}
+	lost chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func lockPath(id string) string { return filepath.Join(runsDir, id+".lock") }
+
+// takeoverPath is flocked while a stale lock is replaced, so only one process
+// at a time decides a lock is abandoned and swaps in its own.
+func takeoverPath(id string) string { return lockPath(id) + ".takeover" }
+
+// AcquireLock takes the advisory lock for run id and keeps its heartbeat fresh
+// until Release. A stale lock is taken over; a live one yields *LockedError.
+func AcquireLock(id string) (*RunLock, error) {
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating runs dir: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	l := &RunLock{
+		id:   id,
+		info: LockInfo{PID: os.Getpid(), Host: host, AcquiredAt: now, Heartbeat: now},
+		stop: make(chan struct{}),
+		lost: make(chan struct{}),
+	}
+	data, err := yaml.Marshal(l.info)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling lock: %w", err)
+	}
+
+	created, err := createLock(id, data)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		if err := takeOverLock(id, data); err != nil {
+			return nil, err
+		}
+	}
+
+	l.wg.Add(1)
+	go l.heartbeat()
+	return l, nil
+}
+
+// createLock writes the lock file for run id unless one already exists.
+func createLock(id string, data []byte) (bool, error) {
+	path := lockPath(id)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("creating lock file: %w", err)
+	}
+	_, werr := f.Write(data)
+	cerr := f.Close()
+	if werr != nil || cerr != nil {
+		_ = os.Remove(path)
+		return false, fmt.Errorf("writing lock file: %w", errors.Join(werr, cerr))
+	}
+	return true, nil
+}
+
+// takeOverLock replaces run id's existing lock with data if it is stale.
+// Processes taking over hold an exclusive flock on the takeover file and
+// re-read the lock under it, so once one of them has swapped in its lock the
+// others see it live and get *LockedError instead of removing it.
+func takeOverLock(id string, data []byte) error {
+	unlock, err := flockFile(takeoverPath(id))
+	if err != nil {
+		return fmt.Errorf("locking lock takeover file: %w", err)
+	}
+	defer unlock()
+
+	owner, err := ReadLock(id)
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		if !owner.Stale() {
+			return &LockedError{ID: id, Owner: *owner}
+		}
+		if err := os.Remove(lockPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing stale lock: %w", err)
+		}
+	}
+
+	// A process that never saw the stale lock may create one in between.
+	created, err := createLock(id, data)
+	if err != nil {
+		return err
+	}
+	if !created {
+		if owner, err := ReadLock(id); err == nil && owner != nil {
+			return &LockedError{ID: id, Owner: *owner}
+		}
+		return fmt.Errorf("lock for run %q is contended", id)
+	}
+	return nil
+}
+
+func (l *RunLock) heartbeat() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(LockHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			l.info.Heartbeat = now
+			if errors.Is(l.write(), ErrLockLost) {
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+// Lost is closed when the heartbeat finds the lock held by another process.
+// The heartbeat stops and Release leaves the new owner's lock in place.
+func (l *RunLock) Lost() <-chan struct{} { return l.lost }
+
+// owns reports whether info identifies this lock's holder.
+func (l *RunLock) owns(info *LockInfo) bool {
+	return info != nil && info.PID == l.info.PID && info.Host == l.info.Host
+}
+
+// write atomically replaces the lock file with the current lock info. It holds
+// the takeover flock so a concurrent takeover cannot land between checking
+// ownership and the rename; if the lock no longer names this process it is
+// left alone and ErrLockLost is returned.
+func (l *RunLock) write() error {
+	data, err := yaml.Marshal(l.info)
+	if err != nil {
+		return fmt.Errorf("marshaling lock: %w", err)
+	}
+
+	unlock, err := flockFile(takeoverPath(l.id))
+	if err != nil {
+		return fmt.Errorf("locking lock takeover file: %w", err)
+	}
+	defer unlock()
+
+	owner, err := ReadLock(l.id)
+	if err != nil {
+		return err
+	}
+	if !l.owns(owner) {
+		return ErrLockLost
+	}
+
+	dest := lockPath(l.id)
+	tmp, err := os.CreateTemp(runsDir, l.id+".lock.*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp lock file: %w", err)
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr == nil && cerr == nil {
+		cerr = os.Chmod(tmp.Name(), 0o644)
+	}
+	if werr != nil || cerr != nil {
+		_ = os.Remove(tmp.Name()) // best-effort cleanup
+		return fmt.Errorf("writing temp lock file: %w", errors.Join(werr, cerr))
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		_ = os.Remove(tmp.Name()) // best-effort cleanup
+		return fmt.Errorf("renaming lock file: %w", err)
+	}
+	return nil
+}
+
+// Release stops the heartbeat and removes the lock file, along with its
+// takeover file, if this process still owns it. Safe to call more than once.
+func (l *RunLock) Release() {
+	l.once.Do(func() {
+		close(l.stop)
+		l.wg.Wait()
+
+		unlock, err := flockFile(takeoverPath(l.id))
+		if err != nil {
+			return
+		}
+		defer unlock()
+		if owner, err := ReadLock(l.id); err == nil && l.owns(owner) {
+			_ = os.Remove(lockPath(l.id))
+			// Still under the flock: waiters on the removed file notice and
+			// retry on a fresh one (see flockFile).
+			_ = os.Remove(takeoverPath(l.id))
+		}
+	})
+}
+
+// ReadLock returns the lock holder of run id, or nil if the run is not locked.
+func ReadLock(id string) (*LockInfo, error) {
+	data, err := os.ReadFile(lockPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+	var info LockInfo
+	if err := yaml.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("parsing lock file: %w", err)
+	}
+	return &info, nil
+}
+
+// LockOwner returns the live holder of this run's lock, or nil when the run is
+// unlocked or its lock is stale.
+func (s *RunState) LockOwner() *LockInfo {
+	owner, err := ReadLock(s.ID)
+	if err != nil || owner == nil || owner.Stale() {
+		return nil
+	}
+	return owner
+}
+
+// flockFile blocks until it holds an exclusive flock(2) on path, creating the
+// file if needed. The lock is shared by all forge processes on the host and
+// released by calling the returned func (or when the process exits). If path
+// was removed or replaced while waiting, the flock is retaken on the current
+// file so holders of the old one cannot overlap with holders of the new.
+func flockFile(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		held, herr := f.Stat()
+		cur, cerr := os.Stat(path)
+		if herr == nil && cerr == nil && os.SameFile(held, cur) {
+			return func() { _ = f.Close() }, nil // closing releases the flock
+		}
+		_ = f.Close()
+		if herr != nil {
+			return nil, herr
+		}
+		if cerr != nil && !errors.Is(cerr, os.ErrNotExist) {
+			return nil, cerr
+		}
+	}
+}
+
+// processAlive reports whether a process with pid exists on this host.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
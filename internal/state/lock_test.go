@@ -0,0 +1,246 @@
+package state
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func writeLockFile(t *testing.T, id string, info LockInfo) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(runsDir, 0o755))
+	data, err := yaml.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockPath(id), data, 0o644))
+}
+
+func TestAcquireLock_ExclusiveUntilRelease(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+
+	owner, err := ReadLock("run-1")
+	require.NoError(t, err)
+	require.NotNil(t, owner)
+	assert.Equal(t, os.Getpid(), owner.PID)
+
+	_, err = AcquireLock("run-1")
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, os.Getpid(), locked.Owner.PID)
+	assert.Contains(t, err.Error(), "locked by pid")
+
+	lock.Release()
+	lock.Release() // idempotent
+
+	owner, err = ReadLock("run-1")
+	require.NoError(t, err)
+	assert.Nil(t, owner)
+
+	again, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	again.Release()
+}
+
+func TestAcquireLock_TakesOverExpiredHeartbeat(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	old := time.Now().Add(-2 * LockStaleAfter)
+	writeLockFile(t, "run-1", LockInfo{PID: os.Getpid(), Host: "elsewhere", AcquiredAt: old, Heartbeat: old})
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	defer lock.Release()
+
+	owner, err := ReadLock("run-1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), owner.Heartbeat, 5*time.Second)
+}
+
+func TestAcquireLock_ConcurrentTakeoverHasOneWinner(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	old := time.Now().Add(-2 * LockStaleAfter)
+	writeLockFile(t, "run-1", LockInfo{PID: 1, Host: "elsewhere", AcquiredAt: old, Heartbeat: old})
+
+	const n = 16
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		held  []*RunLock
+		start = make(chan struct{})
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			lock, err := AcquireLock("run-1")
+			if err != nil {
+				var locked *LockedError
+				assert.ErrorAs(t, err, &locked)
+				return
+			}
+			mu.Lock()
+			held = append(held, lock)
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	require.Len(t, held, 1, "exactly one process takes over a stale lock")
+	held[0].Release()
+}
+
+func TestTakeOverLock_LoserBacksOff(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	// Another process saw the stale lock too, but took it over first.
+	winner, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	defer winner.Release()
+
+	err = takeOverLock("run-1", []byte("pid: 1\n"))
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+
+	owner, err := ReadLock("run-1")
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), owner.PID, "the winner's lock is left in place")
+}
+
+func TestAcquireLock_TakesOverDeadLocalProcess(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	// PIDs are bounded well below this on Linux and macOS.
+	writeLockFile(t, "run-1", LockInfo{PID: 1 << 30, Host: host, AcquiredAt: time.Now(), Heartbeat: time.Now()})
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	lock.Release()
+}
+
+func TestAcquireLock_RemoteLiveLockRefused(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	writeLockFile(t, "run-1", LockInfo{PID: 1 << 30, Host: "other-host", AcquiredAt: time.Now(), Heartbeat: time.Now()})
+
+	_, err := AcquireLock("run-1")
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, "other-host", locked.Owner.Host)
+}
+
+func TestAcquireLock_HeartbeatRefreshes(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	orig := LockHeartbeatInterval
+	LockHeartbeatInterval = 10 * time.Millisecond
+	defer func() { LockHeartbeatInterval = orig }()
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	defer lock.Release()
+
+	first, err := ReadLock("run-1")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		cur, err := ReadLock("run-1")
+		return err == nil && cur != nil && cur.Heartbeat.After(first.Heartbeat)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestRunLock_HeartbeatStopsWhenLockLost(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	orig := LockHeartbeatInterval
+	LockHeartbeatInterval = 10 * time.Millisecond
+	defer func() { LockHeartbeatInterval = orig }()
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	defer lock.Release()
+
+	// Another process took the lock over while this one was stalled.
+	now := time.Now()
+	writeLockFile(t, "run-1", LockInfo{PID: 1, Host: "elsewhere", AcquiredAt: now, Heartbeat: now})
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(2 * time.Second):
+		t.Fatal("heartbeat did not report the lost lock")
+	}
+
+	lock.Release()
+	owner, err := ReadLock("run-1")
+	require.NoError(t, err)
+	require.NotNil(t, owner)
+	assert.Equal(t, 1, owner.PID, "new owner's lock must not be clobbered or removed")
+	assert.Equal(t, "elsewhere", owner.Host)
+}
+
+func TestRunLock_ReleaseRemovesLockFiles(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	orig := LockHeartbeatInterval
+	LockHeartbeatInterval = 10 * time.Millisecond
+	defer func() { LockHeartbeatInterval = orig }()
+
+	old := time.Now().Add(-2 * LockStaleAfter)
+	writeLockFile(t, "run-1", LockInfo{PID: 1, Host: "elsewhere", AcquiredAt: old, Heartbeat: old})
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	first, err := ReadLock("run-1")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		cur, err := ReadLock("run-1")
+		return err == nil && cur != nil && cur.Heartbeat.After(first.Heartbeat)
+	}, 2*time.Second, 10*time.Millisecond)
+	lock.Release()
+
+	entries, err := os.ReadDir(runsDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), "run-1.lock", "leftover lock file %s", e.Name())
+	}
+}
+
+func TestLockOwner(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	rs := New("run-1", "plan.md")
+	assert.Nil(t, rs.LockOwner())
+
+	lock, err := AcquireLock("run-1")
+	require.NoError(t, err)
+	owner := rs.LockOwner()
+	require.NotNil(t, owner)
+	assert.Equal(t, os.Getpid(), owner.PID)
+
+	lock.Release()
+	assert.Nil(t, rs.LockOwner())
+
+	old := time.Now().Add(-2 * LockStaleAfter)
+	writeLockFile(t, "run-1", LockInfo{PID: 1, Host: "elsewhere", AcquiredAt: old, Heartbeat: old})
+	assert.Nil(t, rs.LockOwner(), "stale locks have no owner")
+}
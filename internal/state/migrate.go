@@ -0,0 +1,100 @@
+package state
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the RunState schema version written by this forge.
+// Bump it together with a new entry in migrations whenever a change would
+// make older state files decode wrongly (renamed steps or fields, changed
+// types or meanings).
+const SchemaVersion = 1
+
+// migration upgrades a decoded run state document by one version in place.
+type migration func(doc map[string]any) error
+
+// migrations[v] upgrades a document from version v to v+1.
+var migrations = []migration{
+	migrateV0,
+}
+
+// decodeRunState parses a run state document of any known version,
+// migrating it to SchemaVersion before decoding into RunState.
+func decodeRunState(data []byte) (*RunState, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("empty run state")
+	}
+
+	if err := migrate(doc, migrations); err != nil {
+		return nil, err
+	}
+
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var rs RunState
+	if err := yaml.Unmarshal(migrated, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// migrate applies chain from doc's version to len(chain) and records the
+// new version in doc. Documents newer than chain are rejected rather than
+// misread.
+func migrate(doc map[string]any, chain []migration) error {
+	version := 0
+	if v, ok := doc["version"]; ok {
+		n, ok := v.(int)
+		if !ok || n < 0 {
+			return fmt.Errorf("invalid version %v", v)
+		}
+		version = n
+	}
+	if version > len(chain) {
+		return fmt.Errorf("version %d is newer than this forge supports (%d); upgrade forge", version, len(chain))
+	}
+
+	for v := version; v < len(chain); v++ {
+		if err := chain[v](doc); err != nil {
+			return fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	doc["version"] = len(chain)
+	return nil
+}
+
+// migrateV0 upgrades unversioned files. Default step names are rewritten to
+// their canonical spelling, since older releases and hand edits accepted
+// "Commit-And-Push" and friends; custom step names are kept as written.
+// Steps without a status are pending.
+func migrateV0(doc map[string]any) error {
+	canonical := make(map[string]string, len(StepNames))
+	for _, name := range StepNames {
+		canonical[NormalizeStepName(name)] = name
+	}
+
+	steps, _ := doc["steps"].([]any)
+	for i, s := range steps {
+		step, ok := s.(map[string]any)
+		if !ok {
+			return fmt.Errorf("steps[%d] is not a mapping", i)
+		}
+		if name, ok := step["name"].(string); ok {
+			if c, ok := canonical[NormalizeStepName(name)]; ok {
+				step["name"] = c
+			}
+		}
+		if status, _ := step["status"].(string); status == "" {
+			step["status"] = string(StepPending)
+		}
+	}
+	return nil
+}
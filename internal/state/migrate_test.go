@@ -0,0 +1,118 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyRun is a run state written before RunState was versioned.
+const legacyRun = `id: 20250101-120000-auth
+plan_path: plans/auth.md
+status: failed
+created_at: 2025-01-01T12:00:00Z
+updated_at: 2025-01-01T12:30:00Z
+branch: forge/auth
+steps:
+  - name: Read-Plan
+    status: completed
+  - name: run agent
+    status: failed
+    error: agent crashed
+  - name: lint-sql
+  - name: commit-and-push
+    status: pending
+`
+
+func TestLoadFile_MigratesUnversionedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(legacyRun), 0o644))
+
+	rs, err := LoadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, SchemaVersion, rs.Version)
+	assert.Equal(t, "forge/auth", rs.Branch)
+	require.Len(t, rs.Steps, 4)
+	assert.Equal(t, StepState{Name: "read plan", Status: StepCompleted}, rs.Steps[0])
+	assert.Equal(t, StepState{Name: "run agent", Status: StepFailed, Error: "agent crashed"}, rs.Steps[1])
+	assert.Equal(t, StepState{Name: "lint-sql", Status: StepPending}, rs.Steps[2], "custom step names are kept")
+	assert.Equal(t, "commit and push", rs.Steps[3].Name)
+}
+
+func TestLoadFile_MigratedRunResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(legacyRun), 0o644))
+
+	rs, err := LoadFile(path)
+	require.NoError(t, err)
+
+	idx, ok := rs.StepIndex("run agent")
+	require.True(t, ok)
+	rs.ResetFrom(idx)
+	rs.SyncSteps(StepNames)
+
+	assert.Equal(t, StepCompleted, rs.Steps[0].Status, "read plan keeps its status across the rename")
+	assert.Equal(t, StepPending, rs.Steps[4].Status)
+}
+
+func TestLoadFile_RejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "future.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 99\nid: x\nstatus: active\n"), 0o644))
+
+	_, err := LoadFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "version 99 is newer than this forge supports")
+}
+
+func TestList_IncludesLegacyFiles(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	require.NoError(t, os.MkdirAll(runsDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(runsDir, "20250101-120000-auth.yaml"), []byte(legacyRun), 0o644))
+
+	runs, err := List()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "read plan", runs[0].Steps[0].Name)
+}
+
+func TestSave_StampsSchemaVersion(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	rs := &RunState{ID: "old", Status: RunActive}
+	require.NoError(t, rs.Save())
+
+	data, err := os.ReadFile(filepath.Join(runsDir, "old.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "version: 1\n")
+}
+
+func TestMigrate_AppliesChainInOrder(t *testing.T) {
+	var applied []int
+	chain := []migration{
+		func(doc map[string]any) error { applied = append(applied, 0); return nil },
+		func(doc map[string]any) error {
+			applied = append(applied, 1)
+			doc["plan_title"] = "renamed"
+			return nil
+		},
+		func(doc map[string]any) error { applied = append(applied, 2); return nil },
+	}
+
+	doc := map[string]any{"version": 1}
+	require.NoError(t, migrate(doc, chain))
+
+	assert.Equal(t, []int{1, 2}, applied, "migrations below the document's version are skipped")
+	assert.Equal(t, 3, doc["version"])
+	assert.Equal(t, "renamed", doc["plan_title"])
+}
+
+func TestMigrations_MatchSchemaVersion(t *testing.T) {
+	assert.Len(t, migrations, SchemaVersion, "every schema version needs a migration from the previous one")
+}
@@ -0,0 +1,224 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	_ "modernc.org/sqlite" // pure-Go driver, no cgo
+)
+
+// sqliteDriver is the database/sql driver name registered by the pure-Go
+// SQLite driver (modernc.org/sqlite).
+const sqliteDriver = "sqlite"
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	repo         TEXT    NOT NULL,
+	id           TEXT    NOT NULL,
+	status       TEXT    NOT NULL,
+	source_issue INTEGER NOT NULL DEFAULT 0,
+	created_at   INTEGER NOT NULL,
+	updated_at   INTEGER NOT NULL,
+	data         BLOB    NOT NULL,
+	PRIMARY KEY (repo, id)
+);
+CREATE INDEX IF NOT EXISTS runs_repo_created ON runs (repo, created_at DESC);
+CREATE INDEX IF NOT EXISTS runs_status ON runs (repo, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS runs_source_issue ON runs (repo, source_issue);
+CREATE INDEX IF NOT EXISTS runs_created ON runs (created_at DESC);
+`
+
+// SQLiteStore keeps run states of all repos in one SQLite database, indexed by
+// repo, status, created_at and source issue. Each row stores the run as YAML,
+// exactly as YAMLStore writes it, next to the indexed columns.
+type SQLiteStore struct {
+	db     *sql.DB
+	repo   string
+	shared bool // scoped copy from ForRepo; Close leaves db open
+}
+
+// OpenSQLite opens (creating if needed) the database at path, scoped to the
+// repo rooted at repo.
+func OpenSQLite(path, repo string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating state db dir: %w", err)
+	}
+
+	// Several forge processes may share the database: wait on locks instead of
+	// failing, and let readers proceed during writes.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open(sqliteDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening state db %q: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("creating state db schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db, repo: repo}, nil
+}
+
+// ForRepo returns a store sharing the database, scoped to another repo.
+func (s *SQLiteStore) ForRepo(repo string) Store {
+	return &SQLiteStore{db: s.db, repo: repo, shared: true}
+}
+
+// Load reads run id of the store's repo.
+func (s *SQLiteStore) Load(id string) (*RunState, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM runs WHERE repo = ? AND id = ?`, s.repo, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("loading run state %q: %w", id, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading run state %q: %w", id, err)
+	}
+	return decodeRun(id, data)
+}
+
+// Save stamps UpdatedAt and upserts rs.
+func (s *SQLiteStore) Save(rs *RunState) error {
+	rs.stamp()
+	return s.put(s.db, rs)
+}
+
+// Import upserts runs in one transaction, keeping their UpdatedAt.
+// Used by forge state migrate.
+func (s *SQLiteStore) Import(runs []*RunState) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting import: %w", err)
+	}
+	for _, rs := range runs {
+		if err := s.put(tx, rs); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing import: %w", err)
+	}
+	return nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (s *SQLiteStore) put(db execer, rs *RunState) error {
+	data, err := yaml.Marshal(rs)
+	if err != nil {
+		return fmt.Errorf("marshaling run state: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO runs (repo, id, status, source_issue, created_at, updated_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo, id) DO UPDATE SET
+			status = excluded.status,
+			source_issue = excluded.source_issue,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			data = excluded.data`,
+		s.repo, rs.ID, string(rs.Status), rs.SourceIssue,
+		rs.CreatedAt.UnixNano(), rs.UpdatedAt.UnixNano(), data)
+	if err != nil {
+		return fmt.Errorf("saving run state %q: %w", rs.ID, err)
+	}
+	return nil
+}
+
+// List returns the runs of the store's repo matching q.
+func (s *SQLiteStore) List(q Query) ([]*RunState, error) {
+	where, args := s.where(q)
+	limit := -1 // SQLite: no limit
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	args = append(args, limit, q.Offset)
+
+	rows, err := s.db.Query(`SELECT id, data FROM runs WHERE `+where+` ORDER BY created_at DESC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing run states: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var runs []*RunState
+	for rows.Next() {
+		var (
+			id   string
+			data []byte
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("listing run states: %w", err)
+		}
+		rs, err := decodeRun(id, data)
+		if err != nil {
+			continue // skip corrupt rows, as YAMLStore skips corrupt files
+		}
+		runs = append(runs, rs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing run states: %w", err)
+	}
+	return runs, nil
+}
+
+// Count returns how many runs of the store's repo match q.
+func (s *SQLiteStore) Count(q Query) (int, error) {
+	where, args := s.where(q)
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM runs WHERE `+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting run states: %w", err)
+	}
+	return n, nil
+}
+
+func (s *SQLiteStore) where(q Query) (string, []any) {
+	conds := []string{"repo = ?"}
+	args := []any{s.repo}
+	if q.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, string(q.Status))
+	}
+	if q.SourceIssue != 0 {
+		conds = append(conds, "source_issue = ?")
+		args = append(args, q.SourceIssue)
+	}
+	if !q.UpdatedAfter.IsZero() {
+		conds = append(conds, "updated_at > ?")
+		args = append(args, q.UpdatedAfter.UnixNano())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// Delete removes run id of the store's repo.
+func (s *SQLiteStore) Delete(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM runs WHERE repo = ? AND id = ?`, s.repo, id); err != nil {
+		return fmt.Errorf("deleting run state %q: %w", id, err)
+	}
+	return nil
+}
+
+// Close closes the database unless this store is a ForRepo view of it.
+func (s *SQLiteStore) Close() error {
+	if s.shared {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func decodeRun(id string, data []byte) (*RunState, error) {
+	rs, err := decodeRunState(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing run state %q: %w", id, err)
+	}
+	return rs, nil
+}
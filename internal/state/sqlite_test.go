@@ -0,0 +1,53 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestSQLite(t *testing.T, repo string) *SQLiteStore {
+	t.Helper()
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "state.db"), repo)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, openTestSQLite(t, "/repo"))
+}
+
+func TestSQLiteStore_ScopedByRepo(t *testing.T) {
+	a := openTestSQLite(t, "/repo-a")
+	b := a.ForRepo("/repo-b")
+
+	require.NoError(t, a.Save(New("run-a", "a.md")))
+	require.NoError(t, b.Save(New("run-b", "b.md")))
+
+	runs, err := a.List(Query{})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "run-a", runs[0].ID)
+
+	_, err = b.Load("run-a")
+	assert.Error(t, err)
+	require.NoError(t, b.Close(), "closing a scoped view keeps the db open")
+	_, err = a.Load("run-a")
+	assert.NoError(t, err)
+}
+
+func TestSQLiteStore_ImportKeepsTimestamps(t *testing.T) {
+	s := openTestSQLite(t, "/repo")
+
+	rs := New("run-1", "plan.md")
+	rs.UpdatedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.Import([]*RunState{rs}))
+
+	got, err := s.Load("run-1")
+	require.NoError(t, err)
+	assert.True(t, rs.UpdatedAt.Equal(got.UpdatedAt))
+}
@@ -3,12 +3,8 @@ package state
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
 	"strings"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
 var runsDir = ".forge/runs"
@@ -33,9 +29,11 @@ const (
 	RunActive    RunStatus = "active"
 	RunCompleted RunStatus = "completed"
 	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
 )
 
-// StepNames defines the ordered pipeline steps.
+// StepNames defines the default ordered pipeline steps.
+// A run's actual step list may differ when pipeline.steps is configured.
 var StepNames = []string{
 	"read plan",
 	"create issue",
@@ -50,15 +48,71 @@ var StepNames = []string{
 	"notify",
 }
 
-// StepState tracks status and error for a single pipeline step.
+// StepState tracks status, error, timing and agent usage for a single
+// pipeline step. Timing fields describe the latest attempt; Attempts keeps
+// every attempt across resumes and Usage sums the agent calls of all of them.
 type StepState struct {
-	Name   string     `yaml:"name"`
-	Status StepStatus `yaml:"status"`
-	Error  string     `yaml:"error,omitempty"`
+	Name       string        `yaml:"name"`
+	Status     StepStatus    `yaml:"status"`
+	Error      string        `yaml:"error,omitempty"`
+	StartedAt  time.Time     `yaml:"started_at,omitempty"`
+	FinishedAt time.Time     `yaml:"finished_at,omitempty"`
+	Duration   time.Duration `yaml:"duration,omitempty"`
+	Attempts   []StepAttempt `yaml:"attempts,omitempty"`
+	Usage      Usage         `yaml:"usage,omitempty"`
+}
+
+// StepAttempt is one execution of a step. An attempt with a zero FinishedAt
+// was interrupted (e.g. forge was killed mid-step).
+type StepAttempt struct {
+	StartedAt  time.Time `yaml:"started_at"`
+	FinishedAt time.Time `yaml:"finished_at,omitempty"`
+	Error      string    `yaml:"error,omitempty"`
+	LogPath    string    `yaml:"log_path,omitempty"` // agent/command output of this attempt, if any
+}
+
+// BeginAttempt marks the step running and opens a new attempt at now.
+func (s *StepState) BeginAttempt(now time.Time) {
+	s.Status = StepRunning
+	s.Error = ""
+	s.StartedAt = now
+	s.FinishedAt = time.Time{}
+	s.Duration = 0
+	s.Attempts = append(s.Attempts, StepAttempt{StartedAt: now})
+}
+
+// EndAttempt closes the open attempt at now: completed when err is nil,
+// failed otherwise.
+func (s *StepState) EndAttempt(now time.Time, err error, logPath string) {
+	s.Status = StepCompleted
+	s.Error = ""
+	if err != nil {
+		s.Status = StepFailed
+		s.Error = err.Error()
+	}
+	s.FinishedAt = now
+	s.Duration = now.Sub(s.StartedAt)
+
+	if n := len(s.Attempts); n > 0 {
+		a := &s.Attempts[n-1]
+		a.FinishedAt = now
+		a.Error = s.Error
+		a.LogPath = logPath
+	}
+}
+
+// Elapsed returns how long the latest attempt took, or has taken so far if the
+// step is still running. Zero for steps that never ran.
+func (s StepState) Elapsed(now time.Time) time.Duration {
+	if s.Status == StepRunning && !s.StartedAt.IsZero() {
+		return now.Sub(s.StartedAt)
+	}
+	return s.Duration
 }
 
 // RunState is the persistent state for a single pipeline run.
 type RunState struct {
+	Version   int       `yaml:"version"` // schema version, see SchemaVersion
 	ID        string    `yaml:"id"`
 	PlanPath  string    `yaml:"plan_path"`
 	Mode      string    `yaml:"mode,omitempty"` // "" = "run", "push" = push
@@ -78,6 +132,12 @@ type RunState struct {
 	CRRetryCount int    `yaml:"cr_retry_count,omitempty"`
 	PlanTitle    string `yaml:"plan_title,omitempty"`
 	SourceIssue  int    `yaml:"source_issue,omitempty"`
+	BaseRef      string `yaml:"base_ref,omitempty"` // worktree start point when not vcs.base_branch (e.g. origin/main)
+	PRBase       string `yaml:"pr_base,omitempty"`  // PR target when stacked on a parent run's branch
+
+	// Processes of an active run, recorded so forge cancel can signal them.
+	PID      int `yaml:"pid,omitempty"`       // forge process executing the run
+	AgentPID int `yaml:"agent_pid,omitempty"` // agent child process (group leader), 0 when none is running
 
 	Steps []StepState `yaml:"steps"`
 }
@@ -90,6 +150,7 @@ func New(id, planPath string) *RunState {
 		steps[i] = StepState{Name: name, Status: StepPending}
 	}
 	return &RunState{
+		Version:   SchemaVersion,
 		ID:        id,
 		PlanPath:  planPath,
 		Status:    RunActive,
@@ -99,93 +160,84 @@ func New(id, planPath string) *RunState {
 	}
 }
 
-// Load reads a RunState from .forge/runs/<id>.yaml.
+// Load reads a RunState from the current store (.forge/runs/<id>.yaml by default).
 func Load(id string) (*RunState, error) {
-	path := filepath.Join(runsDir, id+".yaml")
-	return LoadFile(path)
+	return Current().Load(id)
 }
 
-// LoadFile reads a RunState from an arbitrary file path.
+// LoadFile reads a RunState from an arbitrary file path, migrating files
+// written by older forge versions to the current schema.
 func LoadFile(path string) (*RunState, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("loading run state %q: %w", path, err)
 	}
 
-	var rs RunState
-	if err := yaml.Unmarshal(data, &rs); err != nil {
+	rs, err := decodeRunState(data)
+	if err != nil {
 		return nil, fmt.Errorf("parsing run state %q: %w", path, err)
 	}
-	return &rs, nil
+	return rs, nil
 }
 
-// Save writes the RunState atomically to .forge/runs/<id>.yaml.
-func (s *RunState) Save() error {
-	if err := os.MkdirAll(runsDir, 0o755); err != nil {
-		return fmt.Errorf("creating runs dir: %w", err)
-	}
-
+// stamp marks s as written now by this forge version.
+func (s *RunState) stamp() {
 	s.UpdatedAt = time.Now()
+	s.Version = SchemaVersion
+}
 
-	data, err := yaml.Marshal(s)
-	if err != nil {
-		return fmt.Errorf("marshaling run state: %w", err)
-	}
-
-	dest := filepath.Join(runsDir, s.ID+".yaml")
-	tmp := dest + ".tmp"
-
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		return fmt.Errorf("writing temp state file: %w", err)
-	}
-
-	if err := os.Rename(tmp, dest); err != nil {
-		_ = os.Remove(tmp) // best-effort cleanup
-		return fmt.Errorf("renaming state file: %w", err)
-	}
-
-	return nil
+// Save writes the RunState to the current store (atomically to .forge/runs/<id>.yaml by default).
+func (s *RunState) Save() error {
+	return Current().Save(s)
 }
 
 // List returns all run states sorted by created_at descending.
 func List() ([]*RunState, error) {
-	entries, err := filepath.Glob(filepath.Join(runsDir, "*.yaml"))
-	if err != nil {
-		return nil, fmt.Errorf("listing run states: %w", err)
-	}
-
-	var runs []*RunState
-	for _, path := range entries {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue // skip unreadable files
-		}
-		var rs RunState
-		if err := yaml.Unmarshal(data, &rs); err != nil {
-			continue // skip corrupt files
-		}
-		runs = append(runs, &rs)
-	}
-
-	sort.Slice(runs, func(i, j int) bool {
-		return runs[i].CreatedAt.After(runs[j].CreatedAt)
-	})
+	return Current().List(Query{})
+}
 
-	return runs, nil
+// ListQuery returns the run states matching q, sorted by created_at descending.
+func ListQuery(q Query) ([]*RunState, error) {
+	return Current().List(q)
 }
 
-// StepIndex returns the index of the named step, or -1 and false if not found.
+// StepIndex returns the index of the named step in this run, or -1 and false if not found.
 // Accepts exact names ("commit and push") or hyphenated ("commit-and-push").
-func StepIndex(name string) (int, bool) {
-	normalized := strings.ReplaceAll(strings.ToLower(name), "-", " ")
-	for i, s := range StepNames {
-		if strings.ToLower(s) == normalized {
+func (s *RunState) StepIndex(name string) (int, bool) {
+	normalized := NormalizeStepName(name)
+	for i, step := range s.Steps {
+		if NormalizeStepName(step.Name) == normalized {
 			return i, true
 		}
 	}
 	return -1, false
 }
 
+// NormalizeStepName lowercases a step name and converts hyphens to spaces.
+func NormalizeStepName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", " ")
+}
+
+// SyncSteps reorders Steps to match names, keeping the status and error of
+// steps already recorded by name. Unknown names are added as pending; recorded
+// steps missing from names are dropped.
+func (s *RunState) SyncSteps(names []string) {
+	existing := make(map[string]StepState, len(s.Steps))
+	for _, step := range s.Steps {
+		existing[NormalizeStepName(step.Name)] = step
+	}
+	steps := make([]StepState, len(names))
+	for i, name := range names {
+		if step, ok := existing[NormalizeStepName(name)]; ok {
+			step.Name = name
+			steps[i] = step
+			continue
+		}
+		steps[i] = StepState{Name: name, Status: StepPending}
+	}
+	s.Steps = steps
+}
+
 // ResetFrom marks all steps before idx as completed and idx onward as pending.
 // Sets run status to active.
 func (s *RunState) ResetFrom(idx int) {
@@ -201,10 +253,22 @@ func (s *RunState) ResetFrom(idx int) {
 	s.Status = RunActive
 }
 
-// Cleanup deletes completed run state files older than the given retention duration.
-// Returns the number of files deleted.
+// ResetFailed marks failed steps pending and sets run status to active,
+// so a re-run picks up where the previous attempt stopped.
+func (s *RunState) ResetFailed() {
+	s.Status = RunActive
+	for i := range s.Steps {
+		if s.Steps[i].Status == StepFailed {
+			s.Steps[i].Status = StepPending
+			s.Steps[i].Error = ""
+		}
+	}
+}
+
+// Cleanup deletes completed run states older than the given retention duration.
+// Returns the number of runs deleted.
 func Cleanup(retention time.Duration) (int, error) {
-	entries, err := filepath.Glob(filepath.Join(runsDir, "*.yaml"))
+	runs, err := ListQuery(Query{Status: RunCompleted})
 	if err != nil {
 		return 0, fmt.Errorf("listing run states for cleanup: %w", err)
 	}
@@ -212,24 +276,13 @@ func Cleanup(retention time.Duration) (int, error) {
 	cutoff := time.Now().Add(-retention)
 	deleted := 0
 
-	for _, path := range entries {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
-		var rs RunState
-		if err := yaml.Unmarshal(data, &rs); err != nil {
-			continue
-		}
-
-		if rs.Status != RunCompleted {
-			continue // only clean up completed runs
-		}
+	for _, rs := range runs {
 		if rs.UpdatedAt.After(cutoff) {
 			continue // not old enough
 		}
-
-		if err := os.Remove(path); err == nil {
+		if err := Current().Delete(rs.ID); err == nil {
+			_ = os.Remove(EventsPath(rs.ID))
+			_ = os.Remove(takeoverPath(rs.ID))
 			deleted++
 		}
 	}
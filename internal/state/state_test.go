@@ -1,6 +1,7 @@
 package state
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -59,6 +60,8 @@ func TestSaveAndLoad_RoundTrip(t *testing.T) {
 	rs.PRUrl = "https://github.com/owner/repo/pull/42"
 	rs.PRNumber = 42
 	rs.CRRetryCount = 3
+	rs.PID = 1234
+	rs.AgentPID = 5678
 	rs.Steps[0].Status = StepCompleted
 	rs.Steps[1].Status = StepFailed
 	rs.Steps[1].Error = "branch conflict"
@@ -76,6 +79,8 @@ func TestSaveAndLoad_RoundTrip(t *testing.T) {
 	assert.Equal(t, rs.PRUrl, loaded.PRUrl)
 	assert.Equal(t, rs.PRNumber, loaded.PRNumber)
 	assert.Equal(t, 3, loaded.CRRetryCount)
+	assert.Equal(t, 1234, loaded.PID)
+	assert.Equal(t, 5678, loaded.AgentPID)
 	require.Len(t, loaded.Steps, 11)
 	assert.Equal(t, StepCompleted, loaded.Steps[0].Status)
 	assert.Equal(t, StepFailed, loaded.Steps[1].Status)
@@ -188,28 +193,67 @@ func TestCleanup_DeletesOnlyCompletedOlderThanRetention(t *testing.T) {
 }
 
 func TestStepIndex_ExactMatch(t *testing.T) {
-	idx, ok := StepIndex("commit and push")
+	idx, ok := New("test", "plan.md").StepIndex("commit and push")
 	require.True(t, ok)
 	assert.Equal(t, 5, idx)
 }
 
 func TestStepIndex_Hyphenated(t *testing.T) {
-	idx, ok := StepIndex("commit-and-push")
+	idx, ok := New("test", "plan.md").StepIndex("commit-and-push")
 	require.True(t, ok)
 	assert.Equal(t, 5, idx)
 }
 
 func TestStepIndex_CaseInsensitive(t *testing.T) {
-	idx, ok := StepIndex("Create PR")
+	idx, ok := New("test", "plan.md").StepIndex("Create PR")
 	require.True(t, ok)
 	assert.Equal(t, 6, idx)
 }
 
 func TestStepIndex_NotFound(t *testing.T) {
-	_, ok := StepIndex("nonexistent")
+	_, ok := New("test", "plan.md").StepIndex("nonexistent")
 	assert.False(t, ok)
 }
 
+func TestStepIndex_CustomOrder(t *testing.T) {
+	rs := New("test", "plan.md")
+	rs.SyncSteps([]string{"read plan", "run tests", "commit and push"})
+
+	idx, ok := rs.StepIndex("commit-and-push")
+	require.True(t, ok)
+	assert.Equal(t, 2, idx)
+
+	_, ok = rs.StepIndex("create pr")
+	assert.False(t, ok, "steps dropped from the run are not found")
+}
+
+func TestSyncSteps_PreservesStatusByName(t *testing.T) {
+	rs := New("test", "plan.md")
+	rs.Steps[0].Status = StepCompleted
+	rs.Steps[4].Status = StepFailed
+	rs.Steps[4].Error = "agent crashed"
+
+	rs.SyncSteps([]string{"run agent", "security scan", "read plan"})
+
+	require.Len(t, rs.Steps, 3)
+	assert.Equal(t, StepState{Name: "run agent", Status: StepFailed, Error: "agent crashed"}, rs.Steps[0])
+	assert.Equal(t, StepState{Name: "security scan", Status: StepPending}, rs.Steps[1])
+	assert.Equal(t, StepState{Name: "read plan", Status: StepCompleted}, rs.Steps[2])
+}
+
+func TestSyncSteps_MatchesHyphenatedNames(t *testing.T) {
+	rs := New("test", "plan.md")
+	rs.SyncSteps([]string{"read plan", "lint-sql"})
+	rs.Steps[1].Status = StepCompleted
+
+	rs.SyncSteps([]string{"read plan", "lint-sql", "notify"})
+
+	assert.Equal(t, StepState{Name: "lint-sql", Status: StepCompleted}, rs.Steps[1])
+	idx, ok := rs.StepIndex("lint-sql")
+	require.True(t, ok)
+	assert.Equal(t, 1, idx)
+}
+
 func TestResetFrom_MiddleStep(t *testing.T) {
 	rs := New("test", "plan.md")
 	// Mark all steps completed first.
@@ -254,6 +298,68 @@ func TestResetFrom_ClearsErrors(t *testing.T) {
 	assert.Empty(t, rs.Steps[5].Error)
 }
 
+func TestResetFailed_OnlyResetsFailedSteps(t *testing.T) {
+	rs := New("test", "plan.md")
+	rs.Status = RunFailed
+	rs.Steps[0].Status = StepCompleted
+	rs.Steps[4].Status = StepFailed
+	rs.Steps[4].Error = "agent exploded"
+
+	rs.ResetFailed()
+
+	assert.Equal(t, RunActive, rs.Status)
+	assert.Equal(t, StepCompleted, rs.Steps[0].Status)
+	assert.Equal(t, StepPending, rs.Steps[4].Status)
+	assert.Empty(t, rs.Steps[4].Error)
+}
+
+func TestStepAttempts_BeginAndEnd(t *testing.T) {
+	var step StepState
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	step.BeginAttempt(t0)
+	assert.Equal(t, StepRunning, step.Status)
+	assert.Equal(t, 5*time.Second, step.Elapsed(t0.Add(5*time.Second)), "running step reports time so far")
+
+	step.EndAttempt(t0.Add(time.Minute), errors.New("boom"), "")
+	assert.Equal(t, StepFailed, step.Status)
+	assert.Equal(t, "boom", step.Error)
+	assert.Equal(t, time.Minute, step.Duration)
+
+	t1 := t0.Add(time.Hour)
+	step.BeginAttempt(t1)
+	assert.Empty(t, step.Error)
+	assert.True(t, step.FinishedAt.IsZero())
+	step.EndAttempt(t1.Add(2*time.Minute), nil, "runs/x-agent-step4.log")
+
+	assert.Equal(t, StepCompleted, step.Status)
+	assert.Equal(t, 2*time.Minute, step.Elapsed(t1.Add(time.Hour)))
+	require.Len(t, step.Attempts, 2)
+	assert.Equal(t, StepAttempt{StartedAt: t0, FinishedAt: t0.Add(time.Minute), Error: "boom"}, step.Attempts[0])
+	assert.Equal(t, "runs/x-agent-step4.log", step.Attempts[1].LogPath)
+}
+
+func TestStepTiming_RoundTrip(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rs := New("timing", "plan.md")
+	rs.Steps[0].BeginAttempt(t0)
+	rs.Steps[0].EndAttempt(t0.Add(90*time.Second), nil, "")
+	require.NoError(t, rs.Save())
+
+	data, err := os.ReadFile(filepath.Join(runsDir, "timing.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "duration: 1m30s")
+
+	loaded, err := Load("timing")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, loaded.Steps[0].Duration)
+	assert.True(t, t0.Equal(loaded.Steps[0].Attempts[0].StartedAt))
+	assert.True(t, loaded.Steps[1].StartedAt.IsZero())
+}
+
 // backdateRun re-writes a run state file with a specific UpdatedAt timestamp.
 func backdateRun(t *testing.T, rs *RunState, updatedAt time.Time) {
 	t.Helper()
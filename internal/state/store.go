@@ -0,0 +1,95 @@
+package state
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Store persists run states. YAMLStore (the default) keeps one file per run
+// under .forge/runs; SQLiteStore keeps the runs of every repo in one indexed
+// database. A store is scoped to a single repo.
+type Store interface {
+	Load(id string) (*RunState, error)
+	// Save writes rs, stamping UpdatedAt and Version.
+	Save(rs *RunState) error
+	// List returns the runs matching q, sorted by created_at descending.
+	List(q Query) ([]*RunState, error)
+	// Count returns how many runs match q, ignoring Limit and Offset.
+	Count(q Query) (int, error)
+	Delete(id string) error
+	Close() error
+}
+
+// Query filters Store.List. Zero-valued fields match every run.
+type Query struct {
+	Status       RunStatus
+	SourceIssue  int
+	UpdatedAfter time.Time
+	Limit        int // 0 = no limit
+	Offset       int
+}
+
+// repoScoper is implemented by stores that hold runs of several repos.
+type repoScoper interface {
+	ForRepo(repo string) Store
+}
+
+// store is the process-wide store; nil means YAML files under runsDir.
+var store Store
+
+// SetStore replaces the store used by Load, List, RunState.Save and friends.
+// Pass nil to go back to YAML files under the runs directory.
+func SetStore(s Store) { store = s }
+
+// Current returns the store in use.
+func Current() Store {
+	if store != nil {
+		return store
+	}
+	return NewYAMLStore(runsDir)
+}
+
+// ForRepo returns a store holding the runs of the repo rooted at repo: the
+// current store scoped to it when that store spans repos, otherwise the YAML
+// files under repo/.forge/runs.
+func ForRepo(repo string) Store {
+	if s, ok := Current().(repoScoper); ok {
+		return s.ForRepo(repo)
+	}
+	return NewYAMLStore(filepath.Join(repo, ".forge", "runs"))
+}
+
+// FileBacked reports whether run states are plain files in the runs directory,
+// so file watchers see every save.
+func FileBacked() bool {
+	_, ok := Current().(*YAMLStore)
+	return ok
+}
+
+// matches reports whether rs satisfies the filters of q.
+func (q Query) matches(rs *RunState) bool {
+	if q.Status != "" && rs.Status != q.Status {
+		return false
+	}
+	if q.SourceIssue != 0 && rs.SourceIssue != q.SourceIssue {
+		return false
+	}
+	if !q.UpdatedAfter.IsZero() && !rs.UpdatedAt.After(q.UpdatedAfter) {
+		return false
+	}
+	return true
+}
+
+// page applies q's Offset and Limit to runs.
+func (q Query) page(runs []*RunState) []*RunState {
+	if q.Offset > 0 {
+		if q.Offset >= len(runs) {
+			return nil
+		}
+		runs = runs[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(runs) {
+		runs = runs[:q.Limit]
+	}
+	return runs
+}
@@ -0,0 +1,131 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore runs the behavior every Store implementation must share.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	put := func(id string, status RunStatus, issue int, age time.Duration) *RunState {
+		rs := New(id, "plans/"+id+".md")
+		rs.Status = status
+		rs.SourceIssue = issue
+		rs.CreatedAt = base.Add(-age)
+		require.NoError(t, s.Save(rs))
+		return rs
+	}
+	put("run-old", RunCompleted, 7, 3*time.Hour)
+	put("run-mid", RunFailed, 0, 2*time.Hour)
+	newest := put("run-new", RunCompleted, 9, time.Hour)
+
+	t.Run("load round trip", func(t *testing.T) {
+		got, err := s.Load("run-new")
+		require.NoError(t, err)
+		assert.Equal(t, newest.ID, got.ID)
+		assert.Equal(t, 9, got.SourceIssue)
+		assert.True(t, newest.CreatedAt.Equal(got.CreatedAt))
+		assert.Len(t, got.Steps, len(StepNames))
+	})
+
+	t.Run("load missing", func(t *testing.T) {
+		_, err := s.Load("nope")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("list sorted desc", func(t *testing.T) {
+		runs, err := s.List(Query{})
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+		assert.Equal(t, "run-new", runs[0].ID)
+		assert.Equal(t, "run-mid", runs[1].ID)
+		assert.Equal(t, "run-old", runs[2].ID)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		runs, err := s.List(Query{Status: RunCompleted})
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Equal(t, "run-new", runs[0].ID)
+
+		runs, err = s.List(Query{SourceIssue: 7})
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, "run-old", runs[0].ID)
+
+		n, err := s.Count(Query{Status: RunFailed})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		runs, err := s.List(Query{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, "run-mid", runs[0].ID)
+
+		n, err := s.Count(Query{Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 3, n, "count ignores limit")
+	})
+
+	t.Run("updated after", func(t *testing.T) {
+		cutoff := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		rs, err := s.Load("run-mid")
+		require.NoError(t, err)
+		require.NoError(t, s.Save(rs))
+
+		runs, err := s.List(Query{UpdatedAfter: cutoff})
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, "run-mid", runs[0].ID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, s.Delete("run-old"))
+		require.NoError(t, s.Delete("run-old"), "deleting a missing run is not an error")
+		n, err := s.Count(Query{})
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+	})
+}
+
+func TestYAMLStore(t *testing.T) {
+	testStore(t, NewYAMLStore(filepath.Join(t.TempDir(), "runs")))
+}
+
+func TestForRepo_YAMLUsesRepoRunsDir(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	repo := t.TempDir()
+	s := ForRepo(repo)
+	require.IsType(t, &YAMLStore{}, s)
+	assert.Equal(t, filepath.Join(repo, ".forge", "runs"), s.(*YAMLStore).Dir)
+}
+
+func TestSetStore_RoutesPackageFunctions(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	s := NewYAMLStore(filepath.Join(t.TempDir(), "elsewhere"))
+	SetStore(s)
+	defer SetStore(nil)
+
+	require.NoError(t, New("run-1", "plan.md").Save())
+	_, err := os.Stat(filepath.Join(s.Dir, "run-1.yaml"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(runsDir, "run-1.yaml"))
+	assert.True(t, os.IsNotExist(err), "default runs dir must not be written")
+}
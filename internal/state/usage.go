@@ -0,0 +1,73 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Usage is the token and cost accounting of one or more agent invocations.
+// InputTokens excludes cached prompt tokens, which are counted separately.
+// CostUSD is zero for agents that don't report cost.
+type Usage struct {
+	Calls               int64   `yaml:"calls,omitempty"` // agent invocations
+	InputTokens         int64   `yaml:"input_tokens,omitempty"`
+	OutputTokens        int64   `yaml:"output_tokens,omitempty"`
+	CacheCreationTokens int64   `yaml:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64   `yaml:"cache_read_tokens,omitempty"`
+	CostUSD             float64 `yaml:"cost_usd,omitempty"`
+}
+
+// Add accumulates o into u.
+func (u *Usage) Add(o Usage) {
+	u.Calls += o.Calls
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheCreationTokens += o.CacheCreationTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CostUSD += o.CostUSD
+}
+
+// IsZero reports whether no usage was recorded. Also lets yaml omit empty usage.
+func (u Usage) IsZero() bool { return u == Usage{} }
+
+// Tokens returns the total of all token counts.
+func (u Usage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationTokens + u.CacheReadTokens
+}
+
+// String renders u compactly, e.g.
+// "2 agent calls, 12.3k tokens (1.2k in, 800 out, 10.3k cached), $0.42".
+func (u Usage) String() string {
+	calls := "agent calls"
+	if u.Calls == 1 {
+		calls = "agent call"
+	}
+	s := fmt.Sprintf("%d %s, %s tokens (%s in, %s out, %s cached)",
+		u.Calls, calls, FormatTokens(u.Tokens()), FormatTokens(u.InputTokens), FormatTokens(u.OutputTokens),
+		FormatTokens(u.CacheCreationTokens+u.CacheReadTokens))
+	if u.CostUSD > 0 {
+		s += fmt.Sprintf(", $%.2f", u.CostUSD)
+	}
+	return s
+}
+
+// FormatTokens renders a token count with a k/M suffix: 950, 12.3k, 1.5M.
+func FormatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000_000), ".0") + "M"
+	case n >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000), ".0") + "k"
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// TotalUsage sums the usage of every step of the run.
+func (s *RunState) TotalUsage() Usage {
+	var total Usage
+	for _, step := range s.Steps {
+		total.Add(step.Usage)
+	}
+	return total
+}
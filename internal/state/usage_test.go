@@ -0,0 +1,57 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUsage_AddAndTokens(t *testing.T) {
+	u := Usage{Calls: 1, InputTokens: 100, OutputTokens: 50, CostUSD: 0.25}
+	u.Add(Usage{Calls: 2, InputTokens: 10, CacheCreationTokens: 5, CacheReadTokens: 1000, CostUSD: 0.05})
+
+	assert.Equal(t, Usage{Calls: 3, InputTokens: 110, OutputTokens: 50, CacheCreationTokens: 5, CacheReadTokens: 1000, CostUSD: 0.30}, u)
+	assert.Equal(t, int64(1165), u.Tokens())
+	assert.False(t, u.IsZero())
+	assert.True(t, Usage{}.IsZero())
+}
+
+func TestUsage_String(t *testing.T) {
+	u := Usage{Calls: 2, InputTokens: 1200, OutputTokens: 800, CacheReadTokens: 10_300, CostUSD: 0.4213}
+	assert.Equal(t, "2 agent calls, 12.3k tokens (1.2k in, 800 out, 10.3k cached), $0.42", u.String())
+
+	// Agents that don't report cost show tokens only.
+	assert.Equal(t, "1 agent call, 500 tokens (500 in, 0 out, 0 cached)", Usage{Calls: 1, InputTokens: 500}.String())
+}
+
+func TestFormatTokens(t *testing.T) {
+	assert.Equal(t, "950", FormatTokens(950))
+	assert.Equal(t, "1k", FormatTokens(1000))
+	assert.Equal(t, "12.3k", FormatTokens(12_345))
+	assert.Equal(t, "1.5M", FormatTokens(1_500_000))
+}
+
+func TestRunState_TotalUsage(t *testing.T) {
+	rs := New("usage-1", "plans/x.md")
+	rs.Steps[4].Usage = Usage{InputTokens: 100, CostUSD: 1}
+	rs.Steps[8].Usage = Usage{OutputTokens: 20, CostUSD: 0.5}
+
+	assert.Equal(t, Usage{InputTokens: 100, OutputTokens: 20, CostUSD: 1.5}, rs.TotalUsage())
+}
+
+func TestStepState_UsageYAML(t *testing.T) {
+	// Steps without agent calls don't write an empty usage block.
+	data, err := yaml.Marshal(StepState{Name: "read plan", Status: StepCompleted})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "usage")
+
+	step := StepState{Name: "run agent", Status: StepCompleted, Usage: Usage{InputTokens: 42, CostUSD: 0.1}}
+	data, err = yaml.Marshal(step)
+	require.NoError(t, err)
+
+	var got StepState
+	require.NoError(t, yaml.Unmarshal(data, &got))
+	assert.Equal(t, step.Usage, got.Usage)
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLStore keeps each run in <Dir>/<id>.yaml.
+type YAMLStore struct {
+	Dir string
+}
+
+// NewYAMLStore creates a YAMLStore for the given runs directory.
+func NewYAMLStore(dir string) *YAMLStore { return &YAMLStore{Dir: dir} }
+
+// Load reads <Dir>/<id>.yaml.
+func (y *YAMLStore) Load(id string) (*RunState, error) {
+	return LoadFile(filepath.Join(y.Dir, id+".yaml"))
+}
+
+// Save writes the RunState atomically to <Dir>/<id>.yaml.
+func (y *YAMLStore) Save(s *RunState) error {
+	if err := os.MkdirAll(y.Dir, 0o755); err != nil {
+		return fmt.Errorf("creating runs dir: %w", err)
+	}
+
+	s.stamp()
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshaling run state: %w", err)
+	}
+
+	dest := filepath.Join(y.Dir, s.ID+".yaml")
+	tmp := dest + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing temp state file: %w", err)
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp) // best-effort cleanup
+		return fmt.Errorf("renaming state file: %w", err)
+	}
+
+	return nil
+}
+
+// List parses every state file in Dir and returns the matches of q.
+func (y *YAMLStore) List(q Query) ([]*RunState, error) {
+	runs, err := y.all(q)
+	if err != nil {
+		return nil, err
+	}
+	return q.page(runs), nil
+}
+
+// Count returns the number of state files matching q.
+func (y *YAMLStore) Count(q Query) (int, error) {
+	runs, err := y.all(q)
+	return len(runs), err
+}
+
+func (y *YAMLStore) all(q Query) ([]*RunState, error) {
+	entries, err := filepath.Glob(filepath.Join(y.Dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing run states: %w", err)
+	}
+
+	var runs []*RunState
+	for _, path := range entries {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // skip unreadable files
+		}
+		rs, err := decodeRunState(data)
+		if err != nil {
+			continue // skip corrupt files
+		}
+		if q.matches(rs) {
+			runs = append(runs, rs)
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+
+	return runs, nil
+}
+
+// Delete removes <Dir>/<id>.yaml.
+func (y *YAMLStore) Delete(id string) error {
+	if err := os.Remove(filepath.Join(y.Dir, id+".yaml")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting run state: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; YAMLStore holds no resources.
+func (y *YAMLStore) Close() error { return nil }
@@ -45,4 +45,43 @@ describe("StepProgress", () => {
     const { container } = render(StepProgress, { props: { steps } });
     expect(container.querySelector(".spinner")).not.toBeNull();
   });
+
+  it("makes agent steps clickable by name regardless of position", () => {
+    const steps: StepState[] = [
+      { name: "read plan", status: "completed" },
+      { name: "run agent", status: "completed" },
+    ];
+    render(StepProgress, { props: { steps } });
+    expect(screen.getByRole("button", { name: "run agent" })).toBeInTheDocument();
+    expect(screen.queryByRole("button", { name: "read plan" })).toBeNull();
+  });
+
+  it("shows duration for finished steps", () => {
+    const steps: StepState[] = [{ name: "run agent", status: "completed", duration_ms: 185_000 }];
+    render(StepProgress, { props: { steps } });
+    expect(screen.getByText("3m 05s")).toBeInTheDocument();
+  });
+
+  it("shows attempt count when a step was retried", () => {
+    const steps: StepState[] = [
+      {
+        name: "run agent",
+        status: "completed",
+        duration_ms: 1000,
+        attempts: [
+          { started_at: "2026-02-20T10:00:00Z", finished_at: "2026-02-20T10:01:00Z", error: "exit code 1" },
+          { started_at: "2026-02-20T11:00:00Z", finished_at: "2026-02-20T11:00:01Z" },
+        ],
+      },
+    ];
+    render(StepProgress, { props: { steps } });
+    const badge = screen.getByText("×2");
+    expect(badge.getAttribute("title")).toBe("#1: exit code 1\n#2: ok");
+  });
+
+  it("omits timing for steps that never ran", () => {
+    const steps = makeSteps(["pending"]);
+    const { container } = render(StepProgress, { props: { steps } });
+    expect(container.querySelector(".timing")).toBeNull();
+  });
 });
@@ -55,6 +55,26 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/runs/{id}/cancel": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Cancel an active run
+         * @description Kills the run's agent process group, signals the forge process executing it, and marks the run cancelled.
+         */
+        post: operations["cancelRun"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/events": {
         parameters: {
             query?: never;
@@ -77,13 +97,40 @@ export type webhooks = Record<string, never>;
 export interface components {
     schemas: {
         /** @enum {string} */
-        RunStatus: "active" | "completed" | "failed";
+        RunStatus: "active" | "completed" | "failed" | "cancelled";
         /** @enum {string} */
         StepStatus: "pending" | "running" | "completed" | "failed";
         StepState: {
             name: string;
             status: components["schemas"]["StepStatus"];
             error?: string;
+            /**
+             * Format: date-time
+             * @description Start of the latest attempt
+             */
+            started_at?: string;
+            /**
+             * Format: date-time
+             * @description End of the latest attempt
+             */
+            finished_at?: string;
+            /**
+             * Format: int64
+             * @description Duration of the latest attempt in milliseconds
+             */
+            duration_ms?: number;
+            attempts?: components["schemas"]["StepAttempt"][];
+        };
+        StepAttempt: {
+            /** Format: date-time */
+            started_at: string;
+            /**
+             * Format: date-time
+             * @description Absent when the attempt was interrupted
+             */
+            finished_at?: string;
+            error?: string;
+            log_path?: string;
         };
         Run: {
             id: string;
@@ -104,8 +151,18 @@ export interface components {
             cr_fix_summary?: string;
             plan_title?: string;
             source_issue?: number;
+            lock?: components["schemas"]["RunLock"];
             steps: components["schemas"]["StepState"][];
         };
+        /** @description Process currently executing the run (absent when unlocked or stale) */
+        RunLock: {
+            pid: number;
+            host: string;
+            /** Format: date-time */
+            acquired_at: string;
+            /** Format: date-time */
+            heartbeat: string;
+        };
         RunList: {
             runs: components["schemas"]["Run"][];
             total: number;
@@ -203,6 +260,46 @@ export interface operations {
             };
         };
     };
+    cancelRun: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                id: string;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Cancelled run */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Run"];
+                };
+            };
+            /** @description Run not found */
+            404: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["ErrorResponse"];
+                };
+            };
+            /** @description Run is not active */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["ErrorResponse"];
+                };
+            };
+        };
+    };
     streamEvents: {
         parameters: {
             query?: never;
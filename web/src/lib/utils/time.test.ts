@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, afterEach } from "vitest";
-import { relativeTime } from "./time.js";
+import { formatDuration, relativeTime } from "./time.js";
 
 describe("relativeTime", () => {
   afterEach(() => {
@@ -36,3 +36,21 @@ describe("relativeTime", () => {
     expect(relativeTime("2026-02-20T10:00:00Z")).toBe("2d ago");
   });
 });
+
+describe("formatDuration", () => {
+  it("formats sub-second durations in milliseconds", () => {
+    expect(formatDuration(850)).toBe("850ms");
+  });
+
+  it("formats seconds", () => {
+    expect(formatDuration(42_000)).toBe("42s");
+  });
+
+  it("formats minutes with padded seconds", () => {
+    expect(formatDuration(185_000)).toBe("3m 05s");
+  });
+
+  it("formats hours with padded minutes", () => {
+    expect(formatDuration(3_720_000)).toBe("1h 02m");
+  });
+});
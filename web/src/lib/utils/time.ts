@@ -11,3 +11,13 @@ export function relativeTime(iso: string): string {
   if (seconds < DAY) return `${Math.floor(seconds / HOUR)}h ago`;
   return `${Math.floor(seconds / DAY)}d ago`;
 }
+
+// formatDuration renders a step duration compactly: "850ms", "42s", "3m 05s", "1h 02m".
+export function formatDuration(ms: number): string {
+  if (ms < 1000) return `${Math.max(0, Math.round(ms))}ms`;
+  const seconds = Math.floor(ms / 1000);
+  if (seconds < MINUTE) return `${seconds}s`;
+  const pad = (n: number) => String(n).padStart(2, "0");
+  if (seconds < HOUR) return `${Math.floor(seconds / MINUTE)}m ${pad(seconds % MINUTE)}s`;
+  return `${Math.floor(seconds / HOUR)}h ${pad(Math.floor((seconds % HOUR) / MINUTE))}m`;
+}